@@ -12,7 +12,10 @@ import (
 
 	art "pdf-maker/internal/article"
 	"pdf-maker/internal/clean"
+	"pdf-maker/internal/envflag"
 	"pdf-maker/internal/fetch"
+	"pdf-maker/internal/media"
+	"pdf-maker/internal/pipeline"
 )
 
 // DefaultArticleURL is the initial target article if none provided via flag.
@@ -25,7 +28,19 @@ func main() {
 	timeout := flag.Duration("timeout", 30*time.Second, "Timeout for overall fetch operation")
 	maxPar := flag.Int("max-par", 4, "Maximum parallel fetches when using -urls")
 	removeImages := flag.Bool("remove-images", false, "Remove all image elements from the article HTML")
+	readerMode := flag.Bool("reader-mode", false, "Keep only headings, paragraphs, lists, blockquotes and images")
+	downloadImages := flag.Bool("download-images", false, "Download article images and point the saved HTML at the local copies")
+	imagesDir := flag.String("images-dir", "", "Directory to download images into with -download-images (default: <out>/images)")
+	csvPath := flag.String("csv", "", "Also write a CSV index of the fetched articles (title, author, date, word count, ...) to this path")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), "\n"+envflag.Usage(envflag.Prefix))
+	}
 	flag.Parse()
+	if err := envflag.Apply(flag.CommandLine, envflag.Prefix, nil); err != nil {
+		log.Fatal(err)
+	}
 
 	urls := []string{}
 	if *multiURLs != "" {
@@ -46,8 +61,20 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
+	var imgDownloader *media.Downloader
+	if *downloadImages {
+		if *imagesDir == "" {
+			*imagesDir = filepath.Join(*outDir, "images")
+		}
+		var err error
+		imgDownloader, err = media.NewDownloader(*imagesDir)
+		if err != nil {
+			log.Fatalf("Failed to create image downloader: %v", err)
+		}
+	}
+
 	if len(urls) == 1 { // original single-path behavior
-		article, _, err := fetch.FetchArticle(ctx, urls[0])
+		article, _, err := fetch.FetchArticleWithImages(ctx, urls[0], imgDownloader)
 		if err != nil {
 			log.Fatalf("fetch failed: %v", err)
 		}
@@ -56,34 +83,49 @@ func main() {
 		if *removeImages {
 			article = removeImagesFromArticle(article)
 		}
+		if *readerMode {
+			article = readerModeArticle(article)
+		}
 
-		path, err := saveArticleContent(article, urls[0], *outDir)
+		path, err := saveArticleContent(article, urls[0], *outDir, *imagesDir)
 		if err != nil {
 			log.Fatalf("save failed: %v", err)
 		}
 		printArticle(article, path)
+		writeCSVIndex(*csvPath, []*art.Article{article})
 		return
 	}
 
 	// Concurrent path
 	fmt.Printf("Fetching %d articles (max parallel=%d) ...\n", len(urls), *maxPar)
-	arts, errs := fetch.FetchArticlesConcurrent(ctx, urls, *maxPar)
+	pipe := pipeline.New(pipeline.Options{Fetch: fetch.FetchOptions{MaxParallel: *maxPar, ImageDownloader: imgDownloader}})
+	arts, errs := pipe.Fetch(ctx, urls)
 
 	// Save each article content
+	saved := make([]*art.Article, 0, len(arts))
 	for _, a := range arts {
 		// Apply image removal if flag is set
 		if *removeImages {
 			a = removeImagesFromArticle(a)
 		}
+		if *readerMode {
+			a = readerModeArticle(a)
+		}
 
-		path, err := saveArticleContent(a, a.Link, *outDir)
+		path, err := saveArticleContent(a, a.Link, *outDir, *imagesDir)
 		if err != nil {
 			fmt.Printf("ERROR saving %s: %v\n", a.Link, err)
 			continue
 		}
 		printArticle(a, path)
 		fmt.Println("------------------------------")
+		saved = append(saved, a)
 	}
+	writeCSVIndex(*csvPath, saved)
+
+	report := pipe.Report()
+	fmt.Printf("Fetched %d of %d URLs; images: %d downloaded, %d cached, %d failed\n",
+		report.Fetched, report.Queued, report.Images.Downloaded, report.Images.Cached, report.Images.Failed)
 
 	if len(errs) > 0 {
 		fmt.Printf("%d fetches failed:\n", len(errs))
@@ -93,6 +135,18 @@ func main() {
 	}
 }
 
+// writeCSVIndex saves the article metadata index when -csv is set.
+func writeCSVIndex(path string, articles []*art.Article) {
+	if path == "" {
+		return
+	}
+	if err := art.SaveCSV(path, articles); err != nil {
+		fmt.Printf("ERROR writing CSV index: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved CSV index to: %s\n", path)
+}
+
 // printArticle outputs metadata for a fetched article.
 func printArticle(a *art.Article, path string) {
 	fmt.Printf("Saved article to: %s\n", path)
@@ -110,6 +164,12 @@ func printArticle(a *art.Article, path string) {
 	if !a.PubDate.IsZero() {
 		fmt.Printf("Published: %s\n", a.PubDate.Format(time.RFC3339))
 	}
+	if !a.UpdatedDate.IsZero() {
+		fmt.Printf("Updated: %s\n", a.UpdatedDate.Format(time.RFC3339))
+	}
+	if a.Language != "" {
+		fmt.Printf("Language: %s\n", a.Language)
+	}
 	fmt.Printf("Link: %s\n", a.Link)
 }
 
@@ -129,13 +189,26 @@ func removeImagesFromArticle(article *art.Article) *art.Article {
 	return article
 }
 
+// readerModeArticle reduces an article's content to the reader-mode whitelist.
+func readerModeArticle(article *art.Article) *art.Article {
+	simplified, err := clean.ReaderMode(article.Content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: reader mode failed: %v\n", err)
+		return article
+	}
+	article.Content = simplified
+	return article
+}
+
 // saveArticleContent saves an article's content to disk and returns the file path.
-func saveArticleContent(article *art.Article, pageURL, outDir string) (string, error) {
+// When imagesDir is set, localized image paths are rewritten relative to outDir
+// so the saved file displays its images wherever it is opened from.
+func saveArticleContent(article *art.Article, pageURL, outDir, imagesDir string) (string, error) {
 	if outDir == "" {
 		outDir = "."
 	}
 
-	filename := deriveFilename(pageURL)
+	filename := fetch.DeriveFilename(pageURL)
 	if err := os.MkdirAll(outDir, 0o755); err != nil {
 		return "", fmt.Errorf("mkdir %s: %w", outDir, err)
 	}
@@ -145,31 +218,32 @@ func saveArticleContent(article *art.Article, pageURL, outDir string) (string, e
 		return "", fmt.Errorf("abs dir: %w", err)
 	}
 
+	content := article.Content
+	if imagesDir != "" {
+		content = relativeImagePaths(content, imagesDir, absDir)
+	}
+
 	outPath := filepath.Join(absDir, filename)
-	if err := os.WriteFile(outPath, []byte(article.Content), 0o644); err != nil {
+	if err := os.WriteFile(outPath, []byte(content), 0o644); err != nil {
 		return "", fmt.Errorf("write file: %w", err)
 	}
 
 	return outPath, nil
 }
 
-// deriveFilename creates a safe filename from a URL.
-func deriveFilename(pageURL string) string {
-	// Simple filename derivation - extract last path segment
-	parts := strings.Split(strings.TrimSuffix(pageURL, "/"), "/")
-	if len(parts) > 0 {
-		name := parts[len(parts)-1]
-		// Sanitize the filename
-		name = strings.ReplaceAll(name, " ", "_")
-		name = strings.Map(func(r rune) rune {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' || r == '.' {
-				return r
-			}
-			return '_'
-		}, name)
-		if name != "" {
-			return name + ".html"
-		}
+// relativeImagePaths rewrites src attributes pointing into imagesDir (as
+// written by the media downloader) to paths relative to absOutDir.
+func relativeImagePaths(content, imagesDir, absOutDir string) string {
+	absImagesDir, err := filepath.Abs(imagesDir)
+	if err != nil {
+		return content
+	}
+	rel, err := filepath.Rel(absOutDir, absImagesDir)
+	if err != nil {
+		return content
 	}
-	return "article.html"
+	prefix := filepath.ToSlash(filepath.Clean(imagesDir)) + "/"
+	relPrefix := filepath.ToSlash(rel) + "/"
+	content = strings.ReplaceAll(content, `src="`+prefix, `src="`+relPrefix)
+	return strings.ReplaceAll(content, `src='`+prefix, `src='`+relPrefix)
 }
@@ -2,52 +2,251 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 	"time"
 
 	art "pdf-maker/internal/article"
+	"pdf-maker/internal/clean"
+	"pdf-maker/internal/envflag"
 	"pdf-maker/internal/fetch"
 	"pdf-maker/internal/media"
+	"pdf-maker/internal/netguard"
 	"pdf-maker/internal/pdf"
+	"pdf-maker/internal/pipeline"
+	"pdf-maker/internal/useragent"
 )
 
 func main() {
 	urls := flag.String("urls", "", "Comma-separated list of article URLs to fetch and convert to PDF")
+	archive := flag.String("archive", "", "Publication archive, home page or feed URL to take the latest posts from (alternative to --urls; see --latest)")
+	latest := flag.Int("latest", 10, "With --archive, how many of the newest posts to fetch")
 	articlesJSON := flag.String("articles-json", "", "Path to JSON file containing article data (alternative to --urls)")
-	output := flag.String("output", "", "Output PDF path (default: newspapers/articles_TIMESTAMP.pdf)")
+	output := flag.String("output", "", "Output PDF path (default: newspapers/articles_TIMESTAMP.pdf); with --split, the output directory")
+	filenameTemplate := flag.String("filename-template", "", "Output filename when --output is empty; placeholders: {date}, {time}, {title-slug}, {count}. With --split, names each article's file: {title-slug} is the article's slug and {count} is 1")
 	title := flag.String("title", "Your Articles", "PDF header title")
-	layoutType := flag.String("layout-type", "newspaper", "PDF layout type: 'newspaper' or 'essay' (used with --urls, ignored with --articles-json)")
+	layoutType := flag.String("layout-type", "newspaper", "PDF layout type: 'newspaper', 'essay' or 'original' (used with --urls, ignored with --articles-json)")
 	keepHTML := flag.Bool("keep-html", false, "Keep intermediate HTML file for debugging")
+	dumpArticles := flag.String("dump-articles", "", "Directory to write each article's cleaned, image-processed content to as <slug>.html, for debugging")
+	saveRaw := flag.String("save-raw", "", "Directory to write each fetched page's raw body to as <slug>.html (.json for API responses), before extraction and cleaning")
 	removeImages := flag.Bool("remove-images", false, "Remove all images from the PDF (text-only)")
-	cleanupImages := flag.Bool("cleanup-images", true, "Delete downloaded images after PDF generation")
-	maxPar := flag.Int("max-par", 4, "Maximum parallel fetches")
+	dedupeImages := flag.Bool("dedupe-images", false, "Drop images identical to the image immediately before them, with no text in between")
+	imagesDir := flag.String("images-dir", "images", "Directory to download article images into")
+	indexedImages := flag.Bool("indexed-image-names", false, "Name downloaded images after their article and position, e.g. a03-img05-<hash>.png (useful with -cleanup-images=false)")
+	maxImageWidth := flag.Int("max-image-width", 0, "Downscale downloaded JPEG/PNG images wider than this many pixels (0 = keep original size)")
+	grayscaleImages := flag.Bool("grayscale-images", false, "Convert downloaded JPEG/PNG images to contrast-adjusted grayscale for black-and-white printing")
+	optimizeImages := flag.String("optimize-images", "", "Command run on each downloaded image, with {file} for the image (and optionally {out} for the output path), e.g. 'pngquant --force --ext .png {file}'; smaller output replaces the image")
+	jpegQuality := flag.Int("jpeg-quality", 0, "Re-encode downloaded JPEGs at this quality, 1-100 (0 = keep original encoding)")
+	cleanupImages := flag.Bool("cleanup-images", true, "Delete the images directory after PDF generation")
+	readerMode := flag.Bool("reader-mode", false, "Strip content to text, headings, lists, quotes and images (no tables, embeds or styling)")
+	dropCaps := flag.Bool("drop-caps", false, "Enlarge the first letter of each article (always on for the newspaper layout)")
+	balanceColumns := flag.Bool("balance-columns", false, "Run a second layout pass to even out the newspaper's last-page columns")
+	showAvatars := flag.Bool("show-avatars", false, "Show author avatars next to article bylines")
+	orientation := flag.String("orientation", "", "Page orientation: 'Portrait' or 'Landscape' (default: Landscape for newspaper, Portrait otherwise)")
+	theme := flag.String("theme", "light", "Color theme: 'light', 'dark' or 'sepia'")
+	pageSize := flag.String("page-size", "", "Page size for the original layout, e.g. 'A4' (default: Letter)")
+	wkhtmltopdfPath := flag.String("wkhtmltopdf-path", "", "wkhtmltopdf binary for the original layout (default: wkhtmltopdf on PATH)")
+	typstPath := flag.String("typst-path", "", "typst binary for the newspaper and essay layouts (default: typst on PATH)")
+	tagged := flag.Bool("tagged", false, "Produce an accessible, tagged PDF (PDF/UA-1) with alt text and nested headings; newspaper and essay layouts only, needs typst 0.14+")
+	includeComments := flag.Bool("comments", false, "Append each article's reader comments (when present in the page, not loaded by JavaScript) as an appendix")
+	dateRange := flag.Bool("date-range", false, "Date the issue header with the span of its articles' publication dates (e.g. \"Oct 1–7, 2025\") instead of today")
+	summarySpread := flag.Bool("summary-spread", false, "Open the issue with a \"This Week in Review\" page of titles, excerpts and reading times (newspaper and essay layouts)")
+	metaFormat := flag.String("meta-format", "", "Article byline template using {author}, {publication}, {date} and {updated}, e.g. '{author} · {publication} · {date}'; empty values drop their separator")
+	sourceLink := flag.Bool("source-link", false, "End each article with \"Originally published at\" and its source URL")
+	sourceQR := flag.Bool("source-qr", false, "End each article with a QR code linking to its source URL")
+	watermark := flag.String("watermark", "", "Draw this text diagonally behind every page, e.g. 'DRAFT'")
+	darkenImages := flag.Bool("darken-images", false, "Dim images to suit a dark theme (HTML-rendered layouts only)")
+	columns := flag.Int("columns", 0, "Newspaper column count, 1-4 (default: 3 landscape, 2 portrait)")
+	gutter := flag.String("gutter", "", "Newspaper column gutter as a CSS length, e.g. '24px' or '2%' (default 20px)")
+	defaultCover := flag.String("default-cover-image", "", "Local image used as the cover image of articles whose page declares none (og:image); shown with -toc-thumbnails")
+	tocThumbnails := flag.Bool("toc-thumbnails", false, "Download each article's cover image and show it as a thumbnail in the contents and -summary-spread page (newspaper and essay layouts)")
+	imageGalleries := flag.Bool("image-galleries", false, "Lay out runs of three or more consecutive images as a grid, two or three per row")
+	scaleTables := flag.Bool("scale-tables", false, "Scale wide tables down to fit the page width instead of clipping them")
+	headerHTML := flag.String("header-html", "", "Path to an HTML file inserted after the issue header (e.g. an editor's note)")
+	csvPath := flag.String("csv", "", "Also write a CSV index of the articles (title, author, date, word count, ...) to this path")
+	manifestPath := flag.String("manifest", "", "Also write a JSON manifest of the PDF(s) and each article's anchor, for file.pdf#<anchor> deep links, to this path")
+	sourceArchive := flag.String("source-archive", "", "Also write a zip of each article's raw fetched page, cleaned content and images, with a manifest.json, to this path")
+	footerHTML := flag.String("footer-html", "", "Path to an HTML file appended at the end of the issue (e.g. a colophon)")
+	split := flag.Bool("split", false, "Generate one PDF per article instead of a single combined issue")
+	splitParallel := flag.Int("split-parallel", 2, "With --split, number of PDFs rendered concurrently (max 8; each render is memory heavy)")
+	maxPar := flag.Int("max-par", 4, "Maximum parallel article fetches; each article downloads its own images (see -image-workers)")
+	imageWorkers := flag.Int("image-workers", 1, "Images downloaded concurrently per article; up to -max-par × -image-workers image requests can be in flight, bounded by -max-requests")
+	maxRequests := flag.Int("max-requests", 16, "Cap on page, image and stylesheet requests in flight at once across the whole run, whatever -max-par and -image-workers allow (0 = no cap)")
+	resume := flag.Bool("resume", false, "Resume an interrupted run: take articles already fetched from the checkpoint instead of fetching them again")
+	checkpointDir := flag.String("checkpoint", "", "Directory recording each successfully fetched URL, for -resume; removed once a run fetches everything (default: no checkpoint, or "+defaultCheckpointDir+" with -resume)")
+	lowMemory := flag.Bool("low-memory", false, "Keep fetched article content in temp files instead of memory until the PDF is assembled")
+	verbose := flag.Bool("verbose", false, "Log fetch diagnostics such as the redirect chain each page followed")
+	substackAPI := flag.Bool("substack-api", false, "Fetch Substack posts (<name>.substack.com/p/<slug>) through the publication's JSON API instead of scraping the page")
+	refetchThin := flag.Bool("refetch-thin", false, "Re-fetch an article once when its extracted text is suspiciously short")
+	thinWords := flag.Int("thin-words", 80, "With -refetch-thin, the word count below which content counts as incomplete")
+	retries := flag.Int("retries", 0, "Retries per article on network errors, 429 and 5xx (exponential backoff within the timeout budget); also retries images rate-limited with 429/503, honouring Retry-After")
+	maxAge := flag.Int("max-age", 0, "Skip articles published more than this many days ago (0 = no limit); undated articles are kept unless -skip-undated is set")
+	skipUndated := flag.Bool("skip-undated", false, "Skip articles whose publication date could not be determined")
+	circuitBreak := flag.Int("circuit-break", 0, "Skip a host's remaining URLs after this many consecutive network/429/5xx failures to it (0 = never); skipped URLs are listed for a later run")
 	timeout := flag.Duration("timeout", 90*time.Second, "Total operation timeout")
+	openPDF := flag.Bool("open", false, "Open the generated PDF in the system's default viewer (not with --split)")
+	selfTest := flag.Bool("selftest", false, "Check the rendering environment (typst, wkhtmltopdf, styles, images dir, a sample render) and exit")
+	footnoteSection := flag.Bool("footnote-section", false, "Gather each article's footnotes under a \"Notes\" heading at the article's end")
+	headingOffset := flag.Int("heading-offset", 0, "Demote article content headings by this many levels, 0-5 (e.g. 2: h1→h3, h2→h4), so they nest below the article title")
+	preserveMath := flag.Bool("preserve-math", false, "Keep MathJax/KaTeX equations readable by printing their TeX source in monospace")
+	pdfAuthor := flag.String("pdf-author", "", "Author recorded in the PDF's document properties, e.g. the issue's publications (newspaper and essay layouts)")
+	pdfSubject := flag.String("pdf-subject", "", "Subject recorded in the PDF's document properties (newspaper and essay layouts)")
+	var pdfKeywords stringList
+	flag.Var(&pdfKeywords, "pdf-keyword", "Keyword recorded in the PDF's document properties (repeatable; newspaper and essay layouts)")
+	var stripSelectors stringList
+	flag.Var(&stripSelectors, "strip-selector", "CSS selector for elements to remove from every article (repeatable)")
+	flag.Var(&stripSelectors, "remove-selector", "Alias for -strip-selector")
+	publicationConfig := flag.String("publication-config", "", "JSON file of per-publication defaults keyed by publication ID or host, e.g. {\"example.com\": {\"remove_images\": true, \"strip_selectors\": [\".promo\"], \"extractor_ruleset\": \"generic\"}}")
+	proxy := flag.String("proxy", "", "Send page, image and stylesheet requests through this proxy (http://, https:// or socks5:// URL; default: HTTP_PROXY/HTTPS_PROXY from the environment)")
+	allowPrivate := flag.Bool("allow-private-network", false, "Allow fetching pages and images from private, loopback and link-local addresses (refused by default)")
+	var allowHosts, blockHosts stringList
+	flag.Var(&allowHosts, "allow-host", "Only fetch pages and images from this host and its subdomains (repeatable)")
+	flag.Var(&blockHosts, "block-host", "Never fetch pages or images from this host or its subdomains (repeatable)")
+	var userAgents stringList
+	flag.Var(&userAgents, "user-agent", "User-Agent to rotate through for page and image requests (repeatable; default: one fixed agent). Only for sites you may read; keep -max-par polite")
+	userAgentSeed := flag.Int64("user-agent-seed", 0, "With -user-agent, seed for a reproducible rotation order (0 = random)")
+	userAgentPerHost := flag.Bool("user-agent-per-host", false, "With -user-agent, keep one agent per host instead of rotating every request")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), "\n"+envflag.Usage(envflag.Prefix))
+	}
 	flag.Parse()
+	// Defaults from N2P_* variables, for containers; N2P_LAYOUT is the
+	// short name of N2P_LAYOUT_TYPE
+	if err := envflag.Apply(flag.CommandLine, envflag.Prefix, map[string]string{"layout-type": "N2P_LAYOUT"}); err != nil {
+		log.Fatal(err)
+	}
 
-	// Must provide either --urls or --articles-json
-	if *urls == "" && *articlesJSON == "" {
-		log.Fatal("Either --urls or --articles-json is required")
+	if *selfTest {
+		if !runSelfTest(*imagesDir) {
+			os.Exit(1)
+		}
+		return
 	}
 
-	if *urls != "" && *articlesJSON != "" {
-		log.Fatal("Cannot use both --urls and --articles-json; choose one")
+	if err := pdf.ValidateOrientation(*orientation); err != nil {
+		log.Fatal(err)
+	}
+	if err := pdf.ValidateTheme(*theme); err != nil {
+		log.Fatal(err)
+	}
+	if err := pdf.ValidateMetaFormat(*metaFormat); err != nil {
+		log.Fatal(err)
+	}
+	if err := pdf.ValidateDefaultCoverImage(*defaultCover); err != nil {
+		log.Fatal(err)
+	}
+	if *gutter != "" {
+		if err := pdf.ValidateCSSLength(*gutter); err != nil {
+			log.Fatalf("Invalid -gutter: %v", err)
+		}
+	}
+	if *maxImageWidth < 0 {
+		log.Fatal("-max-image-width must not be negative")
+	}
+	if err := media.ValidateJPEGQuality(*jpegQuality); err != nil {
+		log.Fatal(err)
+	}
+	optimizeCommand := strings.Fields(*optimizeImages)
+	if err := media.ValidateOptimizeCommand(optimizeCommand); err != nil {
+		log.Fatal(err)
+	}
+	if err := netguard.ValidateProxy(*proxy); err != nil {
+		log.Fatal(err)
+	}
+	if err := clean.ValidateHeadingOffset(*headingOffset); err != nil {
+		log.Fatal(err)
+	}
+	for _, sel := range stripSelectors {
+		if err := clean.ValidateSelector(sel); err != nil {
+			log.Fatalf("Invalid -strip-selector %q: %v", sel, err)
+		}
+	}
+
+	var publications art.PublicationConfig
+	if *publicationConfig != "" {
+		var err error
+		publications, err = art.LoadPublicationConfig(*publicationConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for key, ps := range publications {
+			if err := fetch.ValidateExtractorRuleset(ps.ExtractorRuleset); err != nil {
+				log.Fatalf("Publication %q: %v", key, err)
+			}
+			for _, sel := range ps.StripSelectors {
+				if err := clean.ValidateSelector(sel); err != nil {
+					log.Fatalf("Publication %q: invalid strip selector %q: %v", key, sel, err)
+				}
+			}
+		}
+	}
+
+	// Must provide exactly one of --urls, --archive or --articles-json
+	inputs := 0
+	for _, in := range []string{*urls, *archive, *articlesJSON} {
+		if in != "" {
+			inputs++
+		}
+	}
+	if inputs == 0 {
+		log.Fatal("Either --urls, --archive or --articles-json is required")
+	}
+	if inputs > 1 {
+		log.Fatal("Cannot combine --urls, --archive and --articles-json; choose one")
+	}
+	if *archive != "" && *latest <= 0 {
+		log.Fatalf("Invalid --latest %d: must be positive", *latest)
+	}
+	if *imageWorkers < 1 {
+		log.Fatalf("Invalid --image-workers %d: must be at least 1", *imageWorkers)
+	}
+	if *maxRequests < 0 {
+		log.Fatalf("Invalid --max-requests %d: must be 0 (no cap) or positive", *maxRequests)
+	}
+	if *maxAge < 0 {
+		log.Fatalf("Invalid --max-age %d: must be 0 (no limit) or positive", *maxAge)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
+	agents := useragent.New(userAgents, useragent.Options{Seed: *userAgentSeed, PerHost: *userAgentPerHost})
+	network := &netguard.Policy{AllowPrivate: *allowPrivate, AllowHosts: allowHosts, BlockHosts: blockHosts, Proxy: *proxy, MaxRequests: *maxRequests}
+
 	// Create image downloader
-	imgDownloader, err := media.NewDownloader("images")
+	imgDownloader, err := media.NewDownloaderWithOptions(media.DownloadOptions{
+		UserAgents:       agents,
+		Network:          network,
+		ImagesDir:        *imagesDir,
+		MaxImageWidth:    *maxImageWidth,
+		JPEGQuality:      *jpegQuality,
+		Grayscale:        *grayscaleImages,
+		OptimizeCommand:  optimizeCommand,
+		MaxAttempts:      *retries + 1,
+		IndexedFilenames: *indexedImages,
+		Workers:          *imageWorkers,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create image downloader: %v", err)
 	}
 
+	// A checkpoint kept for -resume refers to the downloaded images, so they
+	// must survive until a later run completes
+	keepForResume := false
+
 	// Cleanup images after PDF generation if requested
 	if *cleanupImages {
 		defer func() {
+			if keepForResume {
+				fmt.Println("Keeping downloaded images for -resume")
+				return
+			}
 			fmt.Println("Cleaning up downloaded images...")
 			if err := imgDownloader.Cleanup(); err != nil {
 				fmt.Printf("Warning: cleanup failed: %v\n", err)
@@ -55,6 +254,63 @@ func main() {
 		}()
 	}
 
+	fetchOpts := fetch.FetchOptions{
+		MaxParallel:     *maxPar,
+		ImageDownloader: imgDownloader,
+		UserAgents:      agents,
+		Network:         network,
+		PreferAPI:       *substackAPI,
+		Verbose:         *verbose,
+		Retry:           fetch.RetryPolicy{MaxAttempts: *retries + 1},
+		Clean:           clean.CleanOptions{ExtraRemoveSelectors: stripSelectors, PreserveMath: *preserveMath, FootnoteSection: *footnoteSection},
+		ThinContent:     fetch.ThinContentPolicy{Enabled: *refetchThin, MinWords: *thinWords},
+		Publications:    publications,
+
+		CircuitBreakAfter: *circuitBreak,
+		CoverImages:       *tocThumbnails,
+	}
+
+	// Low-memory mode: fetched content waits on disk until assembly
+	if *lowMemory {
+		spillDir, err := os.MkdirTemp("", "makepdf-content-")
+		if err != nil {
+			log.Fatalf("Failed to create content spill directory: %v", err)
+		}
+		defer os.RemoveAll(spillDir)
+		fetchOpts.SpillDir = spillDir
+	}
+
+	if *saveRaw != "" {
+		if err := os.MkdirAll(*saveRaw, 0o755); err != nil {
+			log.Fatalf("Failed to create raw page directory: %v", err)
+		}
+		fetchOpts.RawDir = *saveRaw
+	}
+
+	// Checkpointing is opt-in: a shared default directory would let
+	// concurrent runs discard each other's checkpoints
+	var checkpoint *pipeline.Checkpoint
+	if *checkpointDir == "" && *resume {
+		*checkpointDir = defaultCheckpointDir
+	}
+	if *checkpointDir != "" {
+		checkpoint, err = pipeline.OpenCheckpoint(*checkpointDir, *resume)
+		if err != nil {
+			log.Fatalf("Failed to open checkpoint: %v", err)
+		}
+	}
+
+	var sources *pipeline.SourceArchive
+	if *sourceArchive != "" {
+		sources, err = pipeline.NewSourceArchive()
+		if err != nil {
+			log.Fatalf("Failed to prepare source archive: %v", err)
+		}
+		defer sources.Close()
+	}
+
+	pipe := pipeline.New(pipeline.Options{Fetch: fetchOpts, Split: *split, Checkpoint: checkpoint, Sources: sources, MaxAgeDays: *maxAge, SkipUndated: *skipUndated})
+
 	var articles []*art.Article
 	var errs []error
 	var layout string    // The actual layout type to use
@@ -63,61 +319,201 @@ func main() {
 	// Process based on input method
 	if *articlesJSON != "" {
 		// Load articles from JSON file - layout type and title come from JSON
-		articles, errs, layout, jsonTitle = processArticlesFromJSON(ctx, *articlesJSON, imgDownloader, *maxPar)
+		articles, errs, layout, jsonTitle = processArticlesFromJSON(ctx, *articlesJSON, imgDownloader, pipe, *maxPar, publications)
 	} else {
 		// Original URL-based processing - layout type comes from flag
 		urlList := parseURLs(*urls)
+		if *archive != "" {
+			fmt.Printf("Listing the latest %d posts from %s...\n", *latest, *archive)
+			listed, err := fetch.ListArchiveWithOptions(ctx, *archive, *latest, fetchOpts)
+			if err != nil {
+				log.Fatalf("Failed to list archive: %v", err)
+			}
+			urlList = listed
+		}
 		if len(urlList) == 0 {
 			log.Fatal("no valid URLs provided")
 		}
 
 		// Validate layout type flag
-		if *layoutType != "newspaper" && *layoutType != "essay" {
-			log.Fatalf("Invalid layout type '%s'. Must be 'newspaper' or 'essay'", *layoutType)
+		if *layoutType != "newspaper" && *layoutType != "essay" && *layoutType != "original" {
+			log.Fatalf("Invalid layout type '%s'. Must be 'newspaper', 'essay' or 'original'", *layoutType)
 		}
 
 		fmt.Printf("Fetching %d articles (max parallel=%d)...\n", len(urlList), *maxPar)
-		articles, errs = fetch.FetchArticlesConcurrentWithImages(ctx, urlList, *maxPar, imgDownloader)
+		articles, errs = pipe.Fetch(ctx, urlList)
 		layout = *layoutType // Use the flag value
 	}
 
-	if len(errs) > 0 {
-		fmt.Printf("⚠️  %d fetch errors:\n", len(errs))
-		for _, e := range errs {
+	// URLs skipped by the circuit breaker were never attempted; list them
+	// apart from real failures so they can be fed to a later run. URLs
+	// behind a bot challenge need a browser, so they are listed apart too.
+	var failed, skipped, blocked []error
+	for _, e := range errs {
+		switch {
+		case errors.Is(e, fetch.ErrCircuitOpen):
+			skipped = append(skipped, e)
+		case errors.Is(e, fetch.ErrBotChallenge):
+			blocked = append(blocked, e)
+		default:
+			failed = append(failed, e)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Printf("⚠️  %d fetch errors:\n", len(failed))
+		for _, e := range failed {
+			fmt.Printf("  - %v\n", e)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("⏭️  %d URLs skipped because their host kept failing (retry them later):\n", len(skipped))
+		for _, e := range skipped {
+			fmt.Printf("  - %v\n", e)
+		}
+	}
+	if len(blocked) > 0 {
+		fmt.Printf("🛡️  %d URLs blocked by a bot challenge (open them in a browser, or supply their content via --articles-json):\n", len(blocked))
+		for _, e := range blocked {
 			fmt.Printf("  - %v\n", e)
 		}
 	}
 
+	// Keep the checkpoint while URLs are missing, so a re-run with -resume
+	// only fetches those; a complete fetch no longer needs it
+	if len(errs) > 0 && checkpoint.Len() > 0 {
+		keepForResume = true
+		fmt.Printf("💾 Progress saved in %s; re-run with -resume -checkpoint %s to fetch only the %d missing URLs\n", checkpoint.Dir(), checkpoint.Dir(), len(errs))
+	} else if err := checkpoint.Remove(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	if len(articles) == 0 {
 		log.Fatal("no articles successfully fetched; cannot generate PDF")
 	}
 
+	// Drop stale (and, if asked, undated) articles before anything is built from them
+	articles = pipe.FilterByAge(articles, time.Now())
+	if r := pipe.Report(); r.TooOld > 0 {
+		fmt.Printf("⏭️  Skipped %d article(s) published more than %d days ago\n", r.TooOld, *maxAge)
+	}
+	if r := pipe.Report(); r.Undated > 0 {
+		fmt.Printf("⏭️  Skipped %d article(s) with no publication date\n", r.Undated)
+	}
+	if len(articles) == 0 {
+		log.Fatal("every fetched article was skipped by -max-age/-skip-undated; cannot generate PDF")
+	}
+
 	if len(errs) > 0 {
 		fmt.Printf("✅ Successfully processed %d articles (with %d errors)\n", len(articles), len(errs))
 	} else {
 		fmt.Printf("✅ Successfully processed %d articles\n", len(articles))
 	}
 
+	// Flag articles whose body may be page boilerplate rather than the post
+	for _, a := range articles {
+		if a.ExtractionConfidence < fetch.LowConfidenceThreshold {
+			fmt.Printf("⚠️  Low extraction confidence (%.2f) for '%s' (%s); review this article\n",
+				a.ExtractionConfidence, a.DisplayTitle(), a.Link)
+		}
+	}
+
+	// Archive the sources while the downloaded images are still on disk
+	if *sourceArchive != "" {
+		if err := sources.WriteZip(*sourceArchive, articles); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write source archive: %v\n", err)
+		} else {
+			fmt.Printf("🗄️  Source archive written: %s (%d raw pages)\n", *sourceArchive, sources.Len())
+		}
+	}
+
 	// Resolve the title: JSON-provided title takes precedence over the --title flag
 	resolvedTitle := jsonTitle
 	if resolvedTitle == "" {
 		resolvedTitle = *title
 	}
 
+	header, err := readOptionalFile(*headerHTML)
+	if err != nil {
+		log.Fatalf("Failed to read header HTML: %v", err)
+	}
+	footer, err := readOptionalFile(*footerHTML)
+	if err != nil {
+		log.Fatalf("Failed to read footer HTML: %v", err)
+	}
+
 	// Generate PDF
 	fmt.Println("Generating PDF...")
 	opts := pdf.GenerateOptions{
-		OutputPath:   *output,
-		Title:        resolvedTitle,
-		KeepHTML:     *keepHTML,
-		LayoutType:   layout,
-		RemoveImages: *removeImages,
+		OutputPath:       *output,
+		FilenameTemplate: *filenameTemplate,
+		Title:            resolvedTitle,
+		KeepHTML:         *keepHTML,
+		DumpArticlesDir:  *dumpArticles,
+		LayoutType:       layout,
+		RemoveImages:     *removeImages,
+		DedupeImages:     *dedupeImages,
+		ReaderMode:       *readerMode,
+		DropCaps:         *dropCaps,
+		BalanceColumns:   *balanceColumns,
+		ShowAvatars:      *showAvatars,
+		ScaleTables:      *scaleTables,
+		ImageGalleries:   *imageGalleries,
+		Orientation:      *orientation,
+		Theme:            *theme,
+		Gutter:           *gutter,
+		NewspaperColumns: *columns,
+		DarkenImages:     *darkenImages,
+		Watermark:        *watermark,
+		ShowSourceLink:   *sourceLink,
+		ShowSourceQR:     *sourceQR,
+		SummarySpread:    *summarySpread,
+		DateRange:        *dateRange,
+		MetaFormat:       *metaFormat,
+		IncludeComments:  *includeComments,
+		Tagged:           *tagged,
+		HeadingOffset:    *headingOffset,
+		SplitParallel:    *splitParallel,
+		ImagesDir:        imgDownloader.ImagesDir(),
+		HeaderHTML:       header,
+		FooterHTML:       footer,
+
+		DefaultCoverImage: *defaultCover,
+		TOCThumbnails:     *tocThumbnails,
+
+		PageSize:        *pageSize,
+		WkhtmltopdfPath: *wkhtmltopdfPath,
+		TypstPath:       *typstPath,
+
+		Author:   *pdfAuthor,
+		Subject:  *pdfSubject,
+		Keywords: pdfKeywords,
+	}
+	pipe.SetPDFOptions(opts)
+
+	// The original layout renders with each source page's own stylesheets,
+	// which the pipeline inlines before rendering
+	if layout == "original" {
+		fmt.Println("Inlining source stylesheets...")
+	}
+
+	renderErr := pipe.Render(ctx, articles)
+	report := pipe.Report()
+
+	if *split {
+		printSplitResults(articles, report.PDFs)
+		if renderErr != nil {
+			log.Fatal(renderErr)
+		}
+		writeCSVIndex(*csvPath, articles)
+		writeManifest(*manifestPath, pdf.NewManifest(articles, report.PDFs, opts, true))
+		printReport(report)
+		return
 	}
 
-	result := pdf.GeneratePDF(ctx, articles, opts)
-	if !result.Success {
-		log.Fatalf("PDF generation failed: %v", result.Error)
+	if renderErr != nil {
+		log.Fatalf("PDF generation failed: %v", renderErr)
 	}
+	result := report.PDFs[0]
 
 	fmt.Printf("✅ PDF generated: %s\n", result.PDFPath)
 	if result.HTMLPath != "" {
@@ -127,15 +523,146 @@ func main() {
 		}
 		fmt.Printf("📄 %s saved: %s\n", label, result.HTMLPath)
 	}
+	if *openPDF {
+		openInViewer(result.PDFPath)
+	}
+
+	writeCSVIndex(*csvPath, articles)
+	writeManifest(*manifestPath, pdf.NewManifest(articles, report.PDFs, opts, false))
 
 	fmt.Println("\n--- Articles Included ---")
 	for i, a := range articles {
-		fmt.Printf("%d. %s", i+1, a.Title)
+		fmt.Printf("%d. %s", i+1, a.DisplayTitle())
 		if a.Author != "" {
 			fmt.Printf(" (by %s)", a.Author)
 		}
 		fmt.Println()
 	}
+	printReport(report)
+}
+
+// printSplitResults reports the outcome of each per-article PDF.
+func printSplitResults(articles []*art.Article, results []pdf.GenerateResult) {
+	failed := 0
+	fmt.Println("\n--- PDFs Generated ---")
+	for i, r := range results {
+		if !r.Success {
+			failed++
+			fmt.Printf("%d. ❌ %s: %v\n", i+1, articles[i].DisplayTitle(), r.Error)
+			continue
+		}
+		fmt.Printf("%d. ✅ %s\n   %s\n", i+1, articles[i].DisplayTitle(), r.PDFPath)
+		if r.HTMLPath != "" {
+			fmt.Printf("   📄 Typst source saved: %s\n", r.HTMLPath)
+		}
+	}
+
+	if failed > 0 && failed < len(results) {
+		fmt.Printf("⚠️  %d of %d PDFs failed\n", failed, len(results))
+	}
+}
+
+// printReport prints the run summary aggregated by the pipeline.
+func printReport(r *pipeline.Report) {
+	fmt.Println("\n--- Run Summary ---")
+	fmt.Printf("Fetched: %d of %d URLs", r.Fetched, r.Queued)
+	if r.Resumed > 0 {
+		fmt.Printf(" (%d from checkpoint)", r.Resumed)
+	}
+	if len(r.Failures) > 0 {
+		fmt.Printf(" (%d failed)", len(r.Failures))
+	}
+	fmt.Println()
+	if r.TooOld > 0 || r.Undated > 0 {
+		fmt.Printf("Skipped: %d too old, %d undated\n", r.TooOld, r.Undated)
+	}
+	fmt.Printf("Images: %d downloaded, %d cached, %d failed", r.Images.Downloaded, r.Images.Cached, r.Images.Failed)
+	if r.Images.Resized > 0 {
+		fmt.Printf(", %d resized", r.Images.Resized)
+	}
+	if r.Images.Optimized > 0 {
+		fmt.Printf(", %d optimized (%.1f KB saved)", r.Images.Optimized, float64(r.Images.BytesSaved)/1024)
+	}
+	fmt.Println()
+	if r.PDFSize > 0 {
+		fmt.Printf("PDF size: %.1f MB\n", float64(r.PDFSize)/(1024*1024))
+	}
+	fmt.Printf("Elapsed: %s\n", r.Elapsed.Round(time.Millisecond))
+}
+
+// writeCSVIndex saves the article metadata index when -csv is set. A failure
+// is reported but does not fail the run, since the PDF is already written.
+func writeCSVIndex(path string, articles []*art.Article) {
+	if path == "" {
+		return
+	}
+	if err := art.SaveCSV(path, articles); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write CSV index: %v\n", err)
+		return
+	}
+	fmt.Printf("📊 CSV index saved: %s\n", path)
+}
+
+// writeManifest saves the deep-link manifest when -manifest is set. Like
+// the CSV index, a failure is reported without failing the run.
+func writeManifest(path string, m pdf.Manifest) {
+	if path == "" {
+		return
+	}
+	if err := pdf.WriteManifest(path, m); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write manifest: %v\n", err)
+		return
+	}
+	fmt.Printf("🔗 Manifest saved: %s\n", path)
+}
+
+// defaultCheckpointDir is the checkpoint -resume uses when no -checkpoint
+// directory is given.
+const defaultCheckpointDir = ".makepdf-checkpoint"
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// readOptionalFile returns the contents of path, or "" when path is empty.
+func readOptionalFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// removeArticleSelectors applies an article's own remove_selectors to its
+// content. Invalid selectors are reported and skipped; on error the content
+// is returned unchanged.
+func removeArticleSelectors(content string, selectors []string, title string) string {
+	valid := make([]string, 0, len(selectors))
+	for _, sel := range selectors {
+		if err := clean.ValidateSelector(sel); err != nil {
+			fmt.Printf("  ⚠️  ignoring invalid remove selector %q for '%s': %v\n", sel, title, err)
+			continue
+		}
+		valid = append(valid, sel)
+	}
+	cleaned, removed, err := clean.RemoveSelectors(content, valid)
+	if err != nil {
+		fmt.Printf("  ⚠️  remove selectors failed for '%s': %v\n", title, err)
+		return content
+	}
+	if removed > 0 {
+		fmt.Printf("  Removed %d element(s) matching remove_selectors from '%s'\n", removed, title)
+	}
+	return cleaned
 }
 
 // parseURLs extracts URLs from comma-separated string
@@ -150,8 +677,10 @@ func parseURLs(urls string) []string {
 	return urlList
 }
 
-// processArticlesFromJSON loads articles from JSON and fetches content if needed
-func processArticlesFromJSON(ctx context.Context, jsonPath string, imgDownloader *media.Downloader, maxPar int) ([]*art.Article, []error, string, string) {
+// processArticlesFromJSON loads articles from JSON and fetches content if needed.
+// Per-publication settings from publications add to each article's own
+// remove_images and remove_selectors.
+func processArticlesFromJSON(ctx context.Context, jsonPath string, imgDownloader *media.Downloader, pipe *pipeline.Pipeline, maxPar int, publications art.PublicationConfig) ([]*art.Article, []error, string, string) {
 	fmt.Printf("Loading articles from JSON: %s\n", jsonPath)
 
 	issueInput, err := art.LoadArticlesFromJSON(jsonPath)
@@ -174,14 +703,20 @@ func processArticlesFromJSON(ctx context.Context, jsonPath string, imgDownloader
 	// Track which articles need content fetching
 	articlesToFetch := []string{}
 	articleIndices := []int{}
+	fetchedSelectors := make(map[int][]string) // per-article remove_selectors, applied after fetching
 
+	pubIDs := []string{}
 	for i, input := range issueInput.Articles {
 		article := input.ToArticle()
+		pub, _ := publications.Lookup(input.PublicationID, input.ContentURL)
+		article.RemoveImages = article.RemoveImages || pub.RemoveImages
 
 		// If content is provided directly, use it (but still download any embedded images)
 		if input.Content != "" {
-			if !input.RemoveImages {
-				processed, imgErr := imgDownloader.ProcessHTML(article.Content)
+			selectors := append(append([]string(nil), input.RemoveSelectors...), pub.StripSelectors...)
+			article.Content = removeArticleSelectors(article.Content, selectors, article.Title)
+			if !article.RemoveImages {
+				processed, imgErr := imgDownloader.ProcessArticleHTML(ctx, article.Content, len(articles)+1, input.ContentURL)
 				if imgErr != nil {
 					fmt.Printf("  [%d/%d] ⚠️  image processing failed for '%s': %v\n", i+1, len(issueInput.Articles), article.Title, imgErr)
 				} else {
@@ -193,7 +728,11 @@ func processArticlesFromJSON(ctx context.Context, jsonPath string, imgDownloader
 		} else if input.ContentURL != "" {
 			// Mark for fetching
 			articlesToFetch = append(articlesToFetch, input.ContentURL)
+			pubIDs = append(pubIDs, input.PublicationID)
 			articleIndices = append(articleIndices, len(articles))
+			if len(input.RemoveSelectors) > 0 {
+				fetchedSelectors[len(articles)] = input.RemoveSelectors
+			}
 			articles = append(articles, article) // placeholder
 			fmt.Printf("  [%d/%d] Will fetch: %s\n", i+1, len(issueInput.Articles), input.ContentURL)
 		} else {
@@ -207,49 +746,46 @@ func processArticlesFromJSON(ctx context.Context, jsonPath string, imgDownloader
 	// Fetch articles that need fetching
 	if len(articlesToFetch) > 0 {
 		fmt.Printf("\nFetching %d articles (max parallel=%d)...\n", len(articlesToFetch), maxPar)
-		fetchedArticles, fetchErrs := fetch.FetchArticlesConcurrentWithImages(ctx, articlesToFetch, maxPar, imgDownloader)
-
-		// Map fetched articles back to their positions
-		fetchedIndex := 0
-		for i, idx := range articleIndices {
-			if fetchedIndex < len(fetchedArticles) && fetchedArticles[fetchedIndex] != nil {
-				// Merge fetched content with existing metadata
-				original := articles[idx]
-				fetched := fetchedArticles[fetchedIndex]
-
-				// Keep original metadata if it was provided, use fetched as fallback
-				if original.Title == "" {
-					original.Title = fetched.Title
-				}
-				if original.Author == "" {
-					original.Author = fetched.Author
-				}
-				if original.Publication == "" {
-					original.Publication = fetched.Publication
-				}
-				original.Content = fetched.Content
-				// RemoveImages is already preserved from original ArticleInput
-
-				articles[idx] = original
-				fetchedIndex++
-			} else {
-				// Fetch failed for this article
-				if i < len(fetchErrs) {
-					errs = append(errs, fetchErrs[i])
+		numbers := make([]int, len(articleIndices))
+		for k, idx := range articleIndices {
+			numbers[k] = idx + 1
+		}
+		pipe.SetArticleNumbers(numbers)
+		pipe.SetPublicationIDs(pubIDs)
+		fetchedArticles, fetchErrs := pipe.Fetch(ctx, articlesToFetch)
+
+		// Map fetched articles back to their positions by URL. Failed fetches and
+		// duplicates collapsed by the fetcher have no entry and are left without
+		// content, so they are dropped below.
+		fetchedByURL := make(map[string]*art.Article, len(fetchedArticles))
+		for _, f := range fetchedArticles {
+			fetchedByURL[f.Link] = f
+		}
+		for _, idx := range articleIndices {
+			original := articles[idx]
+			fetched, ok := fetchedByURL[original.Link]
+			if !ok {
+				continue
+			}
+			delete(fetchedByURL, original.Link) // a repeated URL is only included once
+
+			// Metadata given in the JSON wins; extraction only fills blanks
+			original.MergeFetched(fetched)
+			if sels := fetchedSelectors[idx]; len(sels) > 0 {
+				if content, err := original.LoadContent(); err == nil {
+					if err := original.StoreContent(removeArticleSelectors(content, sels, original.Title)); err != nil {
+						fmt.Printf("  ⚠️  failed to store content for '%s': %v\n", original.Title, err)
+					}
 				}
 			}
 		}
-
-		// Add any remaining fetch errors
-		if len(fetchErrs) > len(articleIndices) {
-			errs = append(errs, fetchErrs[len(articleIndices):]...)
-		}
+		errs = append(errs, fetchErrs...)
 	}
 
 	// Filter out articles with no content
 	validArticles := make([]*art.Article, 0, len(articles))
 	for _, a := range articles {
-		if a.Content != "" {
+		if a.HasContent() {
 			validArticles = append(validArticles, a)
 		}
 	}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// openInViewer opens path with the operating system's default application
+// (open on macOS, start on Windows, xdg-open elsewhere). It does not wait for
+// the viewer to exit. When no opener is available it prints a warning and
+// does nothing.
+func openInViewer(path string) {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name, args = "open", []string{path}
+	case "windows":
+		// start is a cmd builtin; the empty string is the window title
+		name, args = "cmd", []string{"/c", "start", "", path}
+	default:
+		name, args = "xdg-open", []string{path}
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: --open: %s not found; open %s manually\n", name, path)
+		return
+	}
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: --open: failed to launch viewer: %v\n", err)
+		return
+	}
+	// Reap the opener process in the background; its exit status is irrelevant
+	go cmd.Wait()
+}
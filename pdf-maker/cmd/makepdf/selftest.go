@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/pdf"
+)
+
+// selfTestCheck is one line of the -selftest report. Optional checks cover
+// tools only some layouts need; they are reported but never fail the run.
+type selfTestCheck struct {
+	name     string
+	optional bool
+	run      func() (string, error)
+}
+
+// runSelfTest verifies the runtime environment and prints a pass/fail
+// report. It returns false if any required check failed.
+func runSelfTest(imagesDir string) bool {
+	checks := []selfTestCheck{
+		{name: "typst binary", run: func() (string, error) { return toolVersion("typst", "--version") }},
+		{name: "wkhtmltopdf binary (original layout)", optional: true, run: func() (string, error) { return toolVersion("wkhtmltopdf", "--version") }},
+		{name: "xvfb-run (wkhtmltopdf images)", optional: true, run: func() (string, error) {
+			path, err := exec.LookPath("xvfb-run")
+			if err != nil {
+				return "", fmt.Errorf("not found in PATH")
+			}
+			return path, nil
+		}},
+		{name: "styles directory (HTML layouts)", optional: true, run: checkStylesDir},
+		{name: "images directory writable", run: func() (string, error) { return checkWritableDir(imagesDir) }},
+		{name: "render sample article", run: renderSampleArticle},
+	}
+
+	fmt.Println("makepdf self-test")
+	failed := 0
+	for _, c := range checks {
+		detail, err := c.run()
+		status := "PASS"
+		if err != nil {
+			detail = err.Error()
+			status = "FAIL"
+			if c.optional {
+				status = "WARN"
+			} else {
+				failed++
+			}
+		}
+		fmt.Printf("  [%s] %s: %s\n", status, c.name, detail)
+	}
+
+	if failed > 0 {
+		fmt.Printf("❌ %d check(s) failed\n", failed)
+		return false
+	}
+	fmt.Println("✅ All required checks passed")
+	return true
+}
+
+// toolVersion runs an external binary with a version flag and returns the
+// first line of its output.
+func toolVersion(name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("not found in PATH")
+	}
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line, nil
+}
+
+// checkStylesDir confirms the stylesheets used by the HTML layouts exist
+// relative to the working directory. Only pdf.AssembleHTMLWithOptions reads
+// them; the Typst layouts makepdf renders with do not, so a missing
+// directory is a warning.
+func checkStylesDir() (string, error) {
+	for _, name := range []string{"newspaper.css", "essay.css"} {
+		if _, err := os.Stat(filepath.Join("styles", name)); err != nil {
+			return "", fmt.Errorf("styles/%s: %w", name, err)
+		}
+	}
+	abs, _ := filepath.Abs("styles")
+	return abs, nil
+}
+
+// checkWritableDir creates dir if needed and writes and removes a probe file.
+func checkWritableDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	probe, err := os.CreateTemp(dir, ".selftest-*")
+	if err != nil {
+		return "", err
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return "", err
+	}
+	abs, _ := filepath.Abs(dir)
+	return abs, nil
+}
+
+// renderSampleArticle renders a tiny hardcoded article with the default
+// layout; GeneratePDF itself validates the output.
+func renderSampleArticle() (string, error) {
+	dir, err := os.MkdirTemp("", "makepdf-selftest-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	sample := &art.Article{
+		Title:       "Self-test",
+		Author:      "makepdf",
+		Publication: "Self-test",
+		PubDate:     time.Now(),
+		Link:        "https://example.com/self-test",
+		Content:     "<p>This article verifies that the PDF renderer is installed and working.</p>",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := pdf.GeneratePDF(ctx, []*art.Article{sample}, pdf.GenerateOptions{
+		OutputPath: filepath.Join(dir, "selftest.pdf"),
+		Title:      "Self-test",
+	})
+	if !result.Success {
+		return "", result.Error
+	}
+	info, err := os.Stat(result.PDFPath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d bytes", info.Size()), nil
+}
@@ -1,16 +1,240 @@
 package article
 
-import "time"
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
 
 // Article represents a single newsletter/article unit extracted from a source page.
 // Content holds ONLY the inner HTML of the main article body (div.available-content per Substack pages).
 type Article struct {
 	Title        string
 	Subtitle     string
-	Author       string
+	Excerpt      string   // one-line teaser: the page's meta description, else the opening words
+	Author       string   // display byline; all authors joined as "A, B, and C"
+	Authors      []string // individual author names, in byline order
+	AuthorAvatar string   // byline avatar image: local path once downloaded, else remote URL
 	Publication  string
 	PubDate      time.Time
 	Link         string
+	CanonicalURL string // <link rel="canonical"> / og:url of the fetched page, if any
 	Content      string // raw or cleaned HTML (body only)
+	ContentPath  string // file holding the content instead of Content, see SpillContent
 	RemoveImages bool   // Whether to remove images from this article's content
+	Language     string // BCP 47 language tag, e.g. "en", "ar", "he-IL" (empty if unknown)
+	Series       string // name of the multi-part series this article belongs to (empty if none)
+	SeriesPart   int    // position within Series, e.g. 3 for "Part 3 of 5" (0 if not a series)
+
+	// UpdatedDate is when the post was last revised, if the page says so;
+	// PubDate stays the original publish date. See WasUpdated.
+	UpdatedDate time.Time
+
+	// CSSClass holds extra class names, space-separated, added to the
+	// article's container in the HTML layouts (the article header in the
+	// newspaper layout), e.g. "feature" or "sidebar", so a custom stylesheet
+	// can target it. See CSSClasses.
+	CSSClass string
+
+	// CoverImage is the article's lead picture: the page's og:image or
+	// twitter:image (the post's cover image through the Substack API), or
+	// a local path given in the input or downloaded by the fetcher
+	// (FetchOptions.CoverImages). Empty when the page declares none, unless
+	// GenerateOptions.DefaultCoverImage stands in for it. Rendered as a
+	// contents thumbnail with GenerateOptions.TOCThumbnails.
+	CoverImage string
+
+	// PublicationID is the caller's identifier for the publication
+	// (ArticleInput.PublicationID), used to look up its PubSettings.
+	PublicationID string
+
+	// ExtractionConfidence (0–1) estimates how likely Content is the real
+	// article body rather than page boilerplate; 1 for caller-supplied content.
+	ExtractionConfidence float64
+
+	// ExcerptFromBody reports that Excerpt is the opening of the body
+	// rather than a description the page or API supplied.
+	ExcerptFromBody bool
+
+	// Source page styling, used only by the "original" layout.
+	StylesheetURLs []string // absolute <link rel="stylesheet"> URLs not yet inlined
+	PageCSS        string   // inlined CSS: fetched stylesheets followed by the page's <style> blocks
+
+	// Comments are the top-level reader comments present in the page's DOM,
+	// in page order. Empty when the page loads them with JavaScript.
+	Comments []Comment
+}
+
+// Comment is one top-level reader comment.
+type Comment struct {
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"` // plain text; paragraphs separated by blank lines
+}
+
+// ArticleFingerprint returns a stable hash of the article's visible body text.
+// Markup, case, punctuation and whitespace are ignored, so the same story
+// fetched via different URLs (canonical vs tracking links) or with minor
+// formatting differences yields the same fingerprint. Returns "" when the
+// article has no text.
+func ArticleFingerprint(a *Article) string {
+	text := a.body()
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(text)); err == nil {
+		text = doc.Text()
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(words, " "))))
+}
+
+// WasUpdated reports whether the article was revised on a later day than
+// it was published, i.e. whether "Updated <date>" is worth showing.
+func (a *Article) WasUpdated() bool {
+	if a.UpdatedDate.IsZero() {
+		return false
+	}
+	if a.PubDate.IsZero() {
+		return true
+	}
+	return a.UpdatedDate.Format("2006-01-02") > a.PubDate.Format("2006-01-02")
+}
+
+// CSSClasses returns the names in CSSClass as valid CSS identifiers, in
+// order and without duplicates: characters other than letters, digits, "-"
+// and "_" become "-", leading and trailing "-" are trimmed, and a name that
+// would start with a digit is prefixed with "_". Names left empty are dropped.
+func (a *Article) CSSClasses() []string {
+	var classes []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Fields(a.CSSClass) {
+		name = strings.Trim(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+				return r
+			}
+			return '-'
+		}, name), "-")
+		if name == "" || seen[name] {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(name); unicode.IsDigit(r) {
+			name = "_" + name
+		}
+		seen[name] = true
+		classes = append(classes, name)
+	}
+	return classes
+}
+
+// JoinAuthors formats author names for a byline: "A", "A and B", or
+// "A, B, and C". Blank names are skipped.
+func JoinAuthors(names []string) string {
+	var clean []string
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			clean = append(clean, n)
+		}
+	}
+	switch len(clean) {
+	case 0:
+		return ""
+	case 1:
+		return clean[0]
+	case 2:
+		return clean[0] + " and " + clean[1]
+	}
+	return strings.Join(clean[:len(clean)-1], ", ") + ", and " + clean[len(clean)-1]
+}
+
+// rtlLanguages lists primary language subtags written right-to-left.
+var rtlLanguages = map[string]bool{
+	"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true,
+	"iw": true, "ku": true, "ps": true, "sd": true, "syr": true, "ug": true,
+	"ur": true, "yi": true,
+}
+
+// IsRTL reports whether the article's Language is written right-to-left.
+func (a *Article) IsRTL() bool {
+	return rtlLanguages[primarySubtag(a.Language)]
+}
+
+// Direction returns the HTML dir value for the article: "rtl" or "ltr".
+func (a *Article) Direction() string {
+	if a.IsRTL() {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// LanguageCode returns the lower-cased primary subtag of Language ("pt-BR"
+// yields "pt"), suitable for hyphenation and font selection. Empty if unknown.
+func (a *Article) LanguageCode() string {
+	return primarySubtag(a.Language)
+}
+
+// primarySubtag returns the lower-cased primary subtag of a language tag
+// ("he-IL" and "he_IL" both yield "he").
+func primarySubtag(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// DisplayTitle returns the title to show for the article in headings and
+// tables of contents. When Title is blank (e.g. extraction failed) it falls
+// back to the subtitle, then the first <h1>/<h2> in Content, then a title
+// derived from the URL slug, and finally "Untitled".
+func (a *Article) DisplayTitle() string {
+	if t := strings.TrimSpace(a.Title); t != "" {
+		return t
+	}
+	if t := strings.TrimSpace(a.Subtitle); t != "" {
+		return t
+	}
+	if content := a.body(); content != "" {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(content)); err == nil {
+			if t := strings.Join(strings.Fields(doc.Find("h1, h2").First().Text()), " "); t != "" {
+				return t
+			}
+		}
+	}
+	if t := titleFromURL(a.Link); t != "" {
+		return t
+	}
+	return "Untitled"
+}
+
+// titleFromURL turns the last path segment of a URL ("/p/my-great-post")
+// into a readable title ("My great post").
+func titleFromURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	slug := path.Base(strings.TrimRight(u.Path, "/"))
+	if slug == "." || slug == "/" {
+		return ""
+	}
+	slug = strings.TrimSuffix(slug, path.Ext(slug))
+	words := strings.FieldsFunc(slug, func(r rune) bool { return r == '-' || r == '_' || r == '+' })
+	if len(words) == 0 {
+		return ""
+	}
+	t := strings.Join(words, " ")
+	r, size := utf8.DecodeRuneInString(t)
+	return string(unicode.ToUpper(r)) + t[size:]
 }
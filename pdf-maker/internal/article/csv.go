@@ -0,0 +1,92 @@
+package article
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// wordsPerMinute is the reading speed used for ReadingMinutes.
+const wordsPerMinute = 230
+
+// csvHeader lists the columns written by WriteCSV.
+var csvHeader = []string{"title", "author", "publication", "date", "url", "word_count", "image_count", "reading_minutes"}
+
+// WordCount returns the number of visible words in the article's content.
+func (a *Article) WordCount() int {
+	text := a.body()
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(text)); err == nil {
+		text = doc.Text()
+	}
+	return len(strings.Fields(text))
+}
+
+// ImageCount returns the number of <img> elements in the article's content.
+func (a *Article) ImageCount() int {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(a.body()))
+	if err != nil {
+		return 0
+	}
+	return doc.Find("img").Length()
+}
+
+// ReadingMinutes estimates reading time from the word count, rounded up.
+// Any non-empty article takes at least one minute.
+func (a *Article) ReadingMinutes() int {
+	return readingMinutes(a.WordCount())
+}
+
+func readingMinutes(words int) int {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// WriteCSV writes a spreadsheet-friendly index of articles to w: one header
+// row, then one row per article with its title, author, publication, date
+// (YYYY-MM-DD, empty if unknown), URL, word count, image count and reading
+// time in minutes. Fields are quoted as needed by encoding/csv.
+func WriteCSV(w io.Writer, articles []*Article) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	for _, a := range articles {
+		words := a.WordCount()
+		date := ""
+		if !a.PubDate.IsZero() {
+			date = a.PubDate.Format("2006-01-02")
+		}
+		row := []string{
+			a.DisplayTitle(),
+			a.Author,
+			a.Publication,
+			date,
+			a.Link,
+			strconv.Itoa(words),
+			strconv.Itoa(a.ImageCount()),
+			strconv.Itoa(readingMinutes(words)),
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// SaveCSV writes the WriteCSV index of articles to the file at path.
+func SaveCSV(path string, articles []*Article) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	if err := WriteCSV(f, articles); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -5,21 +5,45 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 // ArticleInput represents the JSON format that FastAPI will send to the Go CLI.
 // It can contain either a content_url (to be fetched) or raw HTML content.
 type ArticleInput struct {
-	Title         string `json:"title"`
-	Subtitle      string `json:"subtitle,omitempty"`
-	Author        string `json:"author,omitempty"`
-	Publication   string `json:"publication,omitempty"`
-	DatePublished string `json:"date_published,omitempty"` // ISO 8601 format
-	ContentURL    string `json:"content_url,omitempty"`    // URL to fetch content from
-	Content       string `json:"content,omitempty"`        // Or raw HTML content
-	PublicationID string `json:"publication_id,omitempty"`
-	RemoveImages  bool   `json:"remove_images,omitempty"` // Per-publication image removal setting
+	Title         string   `json:"title"`
+	Subtitle      string   `json:"subtitle,omitempty"`
+	Excerpt       string   `json:"excerpt,omitempty"` // TOC teaser; when empty, a content_url article takes the fetched page's
+	Author        string   `json:"author,omitempty"`
+	Authors       []string `json:"authors,omitempty"` // Co-authors; joined into Author when author is empty
+	Publication   string   `json:"publication,omitempty"`
+	DatePublished string   `json:"date_published,omitempty"` // ISO 8601 format
+	DateUpdated   string   `json:"date_updated,omitempty"`   // ISO 8601; shown as "Updated <date>" when later than date_published
+	ContentURL    string   `json:"content_url,omitempty"`    // URL to fetch content from
+	Content       string   `json:"content,omitempty"`        // Or raw HTML content
+	PublicationID string   `json:"publication_id,omitempty"`
+	RemoveImages  bool     `json:"remove_images,omitempty"` // Per-publication image removal setting
+	Language      string   `json:"language,omitempty"`      // BCP 47 tag; right-to-left languages render RTL
+	Series        string   `json:"series,omitempty"`        // Multi-part series name
+	SeriesPart    int      `json:"series_part,omitempty"`   // Part number within the series
+	// RemoveSelectors are CSS selectors for elements to strip from this
+	// article only, in addition to the built-in cleaning rules
+	RemoveSelectors []string `json:"remove_selectors,omitempty"`
+	// Comments are reader comments rendered as an appendix when
+	// GenerateOptions.IncludeComments is set
+	Comments []Comment `json:"comments,omitempty"`
+	// Order, when positive, is the article's 1-based position in the
+	// issue, overriding its position in the articles array (see
+	// ParseArticlesJSON)
+	Order int `json:"order,omitempty"`
+	// CSSClass holds extra class names, space-separated, for the article's
+	// container in the HTML layouts, e.g. "feature" or "sidebar"
+	CSSClass string `json:"css_class,omitempty"`
+	// CoverImage is the article's lead picture, a local path or URL;
+	// taken from the fetched page when empty
+	CoverImage string `json:"cover_image,omitempty"`
 }
 
 // IssueInput represents the full payload with issue metadata and articles.
@@ -43,6 +67,11 @@ func LoadArticlesFromJSON(path string) (*IssueInput, error) {
 }
 
 // ParseArticlesJSON parses an IssueInput from a reader (for stdin support).
+// Articles are returned in issue order: array order, except that an
+// article with a positive Order is placed as if it stood at that position
+// in the array (see sortByOrder). The issue renders in exactly this order
+// whatever the fetch timing; articles that fail are left out without
+// moving the rest.
 func ParseArticlesJSON(r io.Reader) (*IssueInput, error) {
 	var input IssueInput
 	decoder := json.NewDecoder(r)
@@ -54,41 +83,150 @@ func ParseArticlesJSON(r io.Reader) (*IssueInput, error) {
 	if len(input.Articles) == 0 {
 		return nil, fmt.Errorf("no articles provided in JSON")
 	}
+	for i, a := range input.Articles {
+		if a.Order < 0 {
+			return nil, fmt.Errorf("article %d (%q): invalid order %d: must be positive", i+1, a.Title, a.Order)
+		}
+	}
+	sortByOrder(input.Articles)
 
 	return &input, nil
 }
 
+// sortByOrder stably sorts articles by their Order, standing in each
+// article's 1-based array position when Order is unset. On a tie the
+// article that asked for the position comes first.
+func sortByOrder(articles []ArticleInput) {
+	type ranked struct {
+		key      int
+		explicit bool
+		a        ArticleInput
+	}
+	rs := make([]ranked, len(articles))
+	for i, a := range articles {
+		rs[i] = ranked{key: i + 1, a: a}
+		if a.Order > 0 {
+			rs[i].key, rs[i].explicit = a.Order, true
+		}
+	}
+	sort.SliceStable(rs, func(x, y int) bool {
+		if rs[x].key != rs[y].key {
+			return rs[x].key < rs[y].key
+		}
+		return rs[x].explicit && !rs[y].explicit
+	})
+	for i, r := range rs {
+		articles[i] = r.a
+	}
+}
+
+// MergeFetched fills a from the article fetched for its ContentURL.
+// Metadata supplied in the JSON (title, subtitle, author, publication,
+// date, language, excerpt, series, comments, cover image) takes
+// precedence; fetched values only fill fields left blank. The body, its page styling and the
+// fetch-only fields (canonical URL, avatar, extraction confidence) always
+// come from the fetch. RemoveImages stays as given in the JSON.
+func (a *Article) MergeFetched(f *Article) {
+	if strings.TrimSpace(a.Title) == "" {
+		a.Title = f.Title
+	}
+	if strings.TrimSpace(a.Subtitle) == "" {
+		a.Subtitle = f.Subtitle
+	}
+	if strings.TrimSpace(a.Author) == "" {
+		a.Author = f.Author
+		a.Authors = f.Authors
+	}
+	if strings.TrimSpace(a.Publication) == "" {
+		a.Publication = f.Publication
+	}
+	if a.PubDate.IsZero() {
+		a.PubDate = f.PubDate
+	}
+	if a.UpdatedDate.IsZero() {
+		a.UpdatedDate = f.UpdatedDate
+	}
+	if a.Language == "" {
+		a.Language = f.Language
+	}
+	if a.Excerpt == "" {
+		a.Excerpt, a.ExcerptFromBody = f.Excerpt, f.ExcerptFromBody
+	}
+	if a.Series == "" {
+		a.Series, a.SeriesPart = f.Series, f.SeriesPart
+	}
+	if len(a.Comments) == 0 {
+		a.Comments = f.Comments
+	}
+	if a.CoverImage == "" {
+		a.CoverImage = f.CoverImage
+	}
+	a.CanonicalURL = f.CanonicalURL
+	a.AuthorAvatar = f.AuthorAvatar
+	a.StylesheetURLs = f.StylesheetURLs
+	a.PageCSS = f.PageCSS
+	a.ExtractionConfidence = f.ExtractionConfidence
+	a.Content = f.Content
+	a.ContentPath = f.ContentPath
+}
+
 // ToArticle converts an ArticleInput to an Article struct.
 // If ContentURL is provided but Content is empty, the caller should fetch it.
 func (ai *ArticleInput) ToArticle() *Article {
 	a := &Article{
 		Title:        ai.Title,
 		Subtitle:     ai.Subtitle,
+		Excerpt:      ai.Excerpt,
 		Author:       ai.Author,
+		Authors:      ai.Authors,
 		Publication:  ai.Publication,
 		Link:         ai.ContentURL,
 		Content:      ai.Content,
 		RemoveImages: ai.RemoveImages,
-	}
+		Language:     ai.Language,
+		Series:       ai.Series,
+		SeriesPart:   ai.SeriesPart,
+		Comments:     ai.Comments,
 
-	// Parse date if provided
-	if ai.DatePublished != "" {
-		// Try multiple date formats
-		formats := []string{
-			time.RFC3339,
-			time.RFC3339Nano,
-			"2006-01-02T15:04:05Z07:00",
-			"2006-01-02T15:04:05",
-			"2006-01-02",
-		}
+		PublicationID: ai.PublicationID,
+		CSSClass:      ai.CSSClass,
+		CoverImage:    ai.CoverImage,
+	}
 
-		for _, format := range formats {
-			if t, err := time.Parse(format, ai.DatePublished); err == nil {
-				a.PubDate = t
-				break
-			}
-		}
+	if a.Content != "" {
+		a.ExtractionConfidence = 1
 	}
+	if a.Author == "" {
+		a.Author = JoinAuthors(ai.Authors)
+	} else if len(a.Authors) == 0 {
+		a.Authors = []string{a.Author}
+	}
+
+	// Parse dates if provided
+	a.PubDate = parseInputDate(ai.DatePublished)
+	a.UpdatedDate = parseInputDate(ai.DateUpdated)
 
 	return a
 }
+
+// parseInputDate parses an ISO 8601 date or timestamp from the JSON input,
+// returning the zero time when s is empty or unrecognised.
+func parseInputDate(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	// Try multiple date formats
+	formats := []string{
+		time.RFC3339,
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,75 @@
+package article
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeFetchedPrecedence(t *testing.T) {
+	given := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	fetchedDate := time.Date(2025, 2, 27, 0, 0, 0, 0, time.UTC)
+	fetched := &Article{
+		Title:       "Fetched title",
+		Subtitle:    "Fetched dek",
+		Excerpt:     "Fetched opening words",
+		Author:      "Fetched Author",
+		Authors:     []string{"Fetched Author"},
+		Publication: "Fetched Letter",
+		PubDate:     fetchedDate,
+		UpdatedDate: fetchedDate,
+		Language:    "de",
+		Series:      "Fetched Series",
+		SeriesPart:  4,
+		Comments:    []Comment{{Author: "Reader", Text: "Fetched comment"}},
+		CoverImage:  "https://cdn.example.com/fetched.jpg",
+
+		Content:              "<p>Fetched body</p>",
+		CanonicalURL:         "https://example.com/p/post",
+		AuthorAvatar:         "images/avatar.jpg",
+		PageCSS:              "body{}",
+		ExtractionConfidence: 0.8,
+		ExcerptFromBody:      true,
+	}
+
+	a := &Article{
+		Title:        "Given title",
+		Subtitle:     "Given dek",
+		Excerpt:      "Given teaser",
+		Author:       "Given Author",
+		Authors:      []string{"Given Author"},
+		Publication:  "Given Letter",
+		PubDate:      given,
+		UpdatedDate:  given,
+		Language:     "en",
+		Series:       "Given Series",
+		SeriesPart:   1,
+		Comments:     []Comment{{Text: "Given comment"}},
+		CoverImage:   "cover.png",
+		Content:      "<p>stale</p>",
+		RemoveImages: true,
+	}
+	want := *a
+	want.Content = fetched.Content
+	want.CanonicalURL = fetched.CanonicalURL
+	want.AuthorAvatar = fetched.AuthorAvatar
+	want.PageCSS = fetched.PageCSS
+	want.ExtractionConfidence = fetched.ExtractionConfidence
+	a.MergeFetched(fetched)
+	if !reflect.DeepEqual(*a, want) {
+		t.Errorf("JSON fields lost to the fetch:\n got %+v\nwant %+v", *a, want)
+	}
+
+	// Blank fields are filled from the fetch
+	a = &Article{Title: "  "}
+	a.MergeFetched(fetched)
+	if a.Title != fetched.Title || a.Author != fetched.Author || !reflect.DeepEqual(a.Authors, fetched.Authors) ||
+		a.Publication != fetched.Publication || !a.PubDate.Equal(fetchedDate) || a.Language != "de" ||
+		a.Excerpt != fetched.Excerpt || !a.ExcerptFromBody || a.Series != "Fetched Series" || a.SeriesPart != 4 ||
+		len(a.Comments) != 1 || a.CoverImage != fetched.CoverImage {
+		t.Errorf("blank fields not filled from the fetch: %+v", *a)
+	}
+	if a.RemoveImages {
+		t.Error("RemoveImages taken from the fetch")
+	}
+}
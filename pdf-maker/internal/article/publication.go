@@ -0,0 +1,71 @@
+package article
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// PubSettings are defaults applied to every article of one publication, so
+// a rule such as "always remove images from X" is stated once instead of on
+// each article.
+type PubSettings struct {
+	// RemoveImages renders the publication's articles without images (and
+	// skips downloading them), like ArticleInput.RemoveImages.
+	RemoveImages bool `json:"remove_images,omitempty"`
+	// StripSelectors are CSS selectors for the publication's recurring
+	// boilerplate, removed in addition to the built-in cleaning rules.
+	StripSelectors []string `json:"strip_selectors,omitempty"`
+	// ExtractorRuleset names the content extraction rules to use for the
+	// publication's pages (see fetch.ValidateExtractorRuleset); empty means
+	// the default rules.
+	ExtractorRuleset string `json:"extractor_ruleset,omitempty"`
+}
+
+// PublicationConfig maps publications to their settings. A key is either a
+// publication ID (ArticleInput.PublicationID) or a host, which also matches
+// its subdomains ("example.com" covers "www.example.com").
+type PublicationConfig map[string]PubSettings
+
+// LoadPublicationConfig reads a PublicationConfig from a JSON file of the
+// form {"<publication id or host>": {"remove_images": true, ...}}.
+func LoadPublicationConfig(path string) (PublicationConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read publication config: %w", err)
+	}
+	var c PublicationConfig
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse publication config: %w", err)
+	}
+	return c, nil
+}
+
+// Lookup returns the settings for an article: those keyed by its
+// publicationID when present, else those of the most specific host key
+// matching pageURL's host. ok is false when nothing matches.
+func (c PublicationConfig) Lookup(publicationID, pageURL string) (s PubSettings, ok bool) {
+	if len(c) == 0 {
+		return PubSettings{}, false
+	}
+	if publicationID != "" {
+		if s, ok := c[publicationID]; ok {
+			return s, true
+		}
+	}
+	u, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil || u.Hostname() == "" {
+		return PubSettings{}, false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	best := ""
+	for key := range c {
+		k := strings.ToLower(strings.TrimSpace(key))
+		if (host == k || strings.HasSuffix(host, "."+k)) && len(k) > len(best) {
+			best, s = k, c[key]
+		}
+	}
+	return s, best != ""
+}
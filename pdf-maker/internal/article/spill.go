@@ -0,0 +1,69 @@
+package article
+
+import (
+	"fmt"
+	"os"
+)
+
+// SpillContent moves the article's Content into a new file in dir and
+// records it in ContentPath, so that only metadata stays in memory until the
+// issue is assembled. It is a no-op for articles without in-memory content.
+// The caller owns dir and removes it (and the spilled files) when done.
+func SpillContent(a *Article, dir string) error {
+	if a.Content == "" {
+		return nil
+	}
+	f, err := os.CreateTemp(dir, "article-*.html")
+	if err != nil {
+		return fmt.Errorf("create content file: %w", err)
+	}
+	if _, err := f.WriteString(a.Content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("write content file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("close content file: %w", err)
+	}
+	a.ContentPath = f.Name()
+	a.Content = ""
+	return nil
+}
+
+// LoadContent returns the article's content HTML, reading it from
+// ContentPath when the content was spilled to disk.
+func (a *Article) LoadContent() (string, error) {
+	if a.Content != "" || a.ContentPath == "" {
+		return a.Content, nil
+	}
+	b, err := os.ReadFile(a.ContentPath)
+	if err != nil {
+		return "", fmt.Errorf("read content file: %w", err)
+	}
+	return string(b), nil
+}
+
+// StoreContent replaces the article's content HTML wherever it lives: the
+// ContentPath file when the content was spilled to disk, else Content.
+func (a *Article) StoreContent(html string) error {
+	if a.Content == "" && a.ContentPath != "" {
+		if err := os.WriteFile(a.ContentPath, []byte(html), 0o644); err != nil {
+			return fmt.Errorf("write content file: %w", err)
+		}
+		return nil
+	}
+	a.Content = html
+	return nil
+}
+
+// HasContent reports whether the article has content, in memory or on disk.
+func (a *Article) HasContent() bool {
+	return a.Content != "" || a.ContentPath != ""
+}
+
+// body is LoadContent for callers that treat unreadable content as empty.
+func (a *Article) body() string {
+	content, _ := a.LoadContent()
+	return content
+}
@@ -0,0 +1,156 @@
+package clean
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/atom"
+)
+
+// defaultAltText is given to images with no alt, caption or title, since a
+// tagged PDF must describe every figure.
+const defaultAltText = "Image"
+
+// AccessibleHTML prepares article content for a tagged PDF. Every <img>
+// gets alt text (its own, else its figure caption, else its title
+// attribute, else a generic label), and headings are renumbered so the
+// shallowest becomes <h2>, below the article title, and none skips a level
+// (h2 → h4 becomes h2 → h3). Returns the content and the number of images
+// and headings changed.
+func AccessibleHTML(htmlContent string) (string, int, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, fmt.Errorf("parse html: %w", err)
+	}
+
+	changed := 0
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		if strings.TrimSpace(img.AttrOr("alt", "")) != "" {
+			return
+		}
+		alt := ""
+		if fig := img.Closest("figure"); fig.Length() > 0 {
+			alt = strings.Join(strings.Fields(fig.Find("figcaption").First().Text()), " ")
+		}
+		if alt == "" {
+			alt = strings.TrimSpace(img.AttrOr("title", ""))
+		}
+		if alt == "" {
+			alt = defaultAltText
+		}
+		img.SetAttr("alt", alt)
+		changed++
+	})
+
+	// Shift headings so the shallowest is h2, then keep each at most one
+	// level below the previous one.
+	headings := doc.Find("h1, h2, h3, h4, h5, h6")
+	shallowest := 6
+	headings.Each(func(_ int, h *goquery.Selection) {
+		if level := headingLevel(h); level < shallowest {
+			shallowest = level
+		}
+	})
+	prev := 1
+	headings.Each(func(_ int, h *goquery.Selection) {
+		level := headingLevel(h)
+		want := level - shallowest + 2
+		if want > prev+1 {
+			want = prev + 1
+		}
+		if want > 6 {
+			want = 6
+		}
+		prev = want
+		if want != level {
+			setHeadingLevel(h, want)
+			changed++
+		}
+	})
+
+	if changed == 0 {
+		return htmlContent, 0, nil
+	}
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", 0, fmt.Errorf("extract html: %w", err)
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, changed, nil
+}
+
+// headingLevel returns N for an <hN> element.
+func headingLevel(h *goquery.Selection) int {
+	return int(goquery.NodeName(h)[1] - '0')
+}
+
+// setHeadingLevel renames the <hN> element h to <h{level}>.
+func setHeadingLevel(h *goquery.Selection, level int) {
+	name := fmt.Sprintf("h%d", level)
+	node := h.Nodes[0]
+	node.Data = name
+	node.DataAtom = atom.Lookup([]byte(name))
+}
+
+// maxHeadingOffset is the largest CleanOptions.HeadingOffset: enough to
+// push an <h1> down to <h6>.
+const maxHeadingOffset = 5
+
+// ValidateHeadingOffset reports whether n is a usable
+// CleanOptions.HeadingOffset, 0 to 5.
+func ValidateHeadingOffset(n int) error {
+	if n < 0 || n > maxHeadingOffset {
+		return fmt.Errorf("invalid heading offset %d: must be between 0 and %d", n, maxHeadingOffset)
+	}
+	return nil
+}
+
+// DemoteHeadings is CleanOptions.HeadingOffset for content that has
+// already been cleaned, such as articles supplied whole in an issue's
+// JSON. Returns the content and the number of headings changed.
+func DemoteHeadings(htmlContent string, offset int) (string, int, error) {
+	if offset <= 0 {
+		return htmlContent, 0, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, fmt.Errorf("parse html: %w", err)
+	}
+	changed := demoteHeadings(doc, offset)
+	if changed == 0 {
+		return htmlContent, 0, nil
+	}
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", 0, fmt.Errorf("extract html: %w", err)
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, changed, nil
+}
+
+// demoteHeadings moves every content heading offset levels down (with 2,
+// h1 → h3 and h2 → h4), stopping at h6, so the content's headings sit
+// below the article title and subtitle the layouts render around it.
+// Headings keep their relative order; levels squeezed together at h6 are
+// the only ones that merge. The heading of a FootnoteSection Notes section
+// already sits at its intended level and is left alone. Returns the number
+// of headings changed.
+func demoteHeadings(doc *goquery.Document, offset int) int {
+	if offset <= 0 {
+		return 0
+	}
+	changed := 0
+	doc.Find("h1, h2, h3, h4, h5, h6").Not("section." + notesSectionClass + " > h3").Each(func(_ int, h *goquery.Selection) {
+		level := headingLevel(h)
+		if want := min(level+offset, 6); want != level {
+			setHeadingLevel(h, want)
+			changed++
+		}
+	})
+	return changed
+}
@@ -0,0 +1,20 @@
+package clean
+
+import "testing"
+
+func TestDemoteHeadings(t *testing.T) {
+	in := `<h1>Top</h1><h5>Deep</h5><section class="article-notes"><h3>Notes</h3></section>`
+	out, n, err := DemoteHeadings(in, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<h3>Top</h3><h6>Deep</h6><section class="article-notes"><h3>Notes</h3></section>`; out != want {
+		t.Errorf("DemoteHeadings = %s, want %s", out, want)
+	}
+	if n != 2 {
+		t.Errorf("changed %d headings, want 2", n)
+	}
+	if out, n, _ := DemoteHeadings(in, 0); out != in || n != 0 {
+		t.Errorf("offset 0 changed the content: %s", out)
+	}
+}
@@ -0,0 +1,51 @@
+package clean
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ScopeAnchors prefixes every id in the content, and every in-page link
+// (href="#id") pointing at one of them, with prefix. Articles rendered into
+// one document otherwise share ids such as Substack's footnote-1 and
+// footnote-anchor-1, and a footnote link in the third article jumps to the
+// first article's note. Links to ids the content does not define are left
+// alone.
+// Returns the HTML and the number of ids rewritten.
+func ScopeAnchors(htmlContent, prefix string) (string, int, error) {
+	if prefix == "" || !strings.Contains(htmlContent, "id=") {
+		return htmlContent, 0, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, err
+	}
+
+	ids := make(map[string]bool)
+	doc.Find("body [id]").Each(func(_ int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if id == "" {
+			return
+		}
+		ids[id] = true
+		s.SetAttr("id", prefix+id)
+	})
+	if len(ids) == 0 {
+		return htmlContent, 0, nil
+	}
+	doc.Find("body [href^='#']").Each(func(_ int, s *goquery.Selection) {
+		if target := strings.TrimPrefix(s.AttrOr("href", ""), "#"); ids[target] {
+			s.SetAttr("href", "#"+prefix+target)
+		}
+	})
+
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", 0, err
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, len(ids), nil
+}
@@ -0,0 +1,78 @@
+package clean
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeAnchors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string // substrings of the output
+		wantN   int
+		notWant []string
+	}{
+		{
+			name:  "footnote pair",
+			in:    `<p>Claim<a id="footnote-anchor-1" href="#footnote-1">1</a></p><p><a id="footnote-1" href="#footnote-anchor-1">1.</a> Note</p>`,
+			want:  []string{`id="a2-footnote-anchor-1" href="#a2-footnote-1"`, `id="a2-footnote-1" href="#a2-footnote-anchor-1"`},
+			wantN: 2,
+		},
+		{
+			name:    "links to undefined ids are left alone",
+			in:      `<p id="intro"><a href="#elsewhere">x</a> <a href="#intro">top</a></p>`,
+			want:    []string{`href="#elsewhere"`, `href="#a2-intro"`},
+			wantN:   1,
+			notWant: []string{`#a2-elsewhere`},
+		},
+		{
+			name:  "no ids",
+			in:    `<p><a href="#x">x</a></p>`,
+			want:  []string{`href="#x"`},
+			wantN: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, n, err := ScopeAnchors(tt.in, "a2-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.wantN {
+				t.Errorf("rewrote %d ids, want %d", n, tt.wantN)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output lacks %q:\n%s", w, out)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("output contains %q:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLToTypstInPageLinks(t *testing.T) {
+	in := `<p>Claim<a id="a1-footnote-anchor-1" href="#a1-footnote-1">1</a> and <a href="#missing">dangling</a></p>` +
+		`<section class="article-notes"><h3>Notes</h3><p class="footnote-note"><a id="a1-footnote-1" href="#a1-footnote-anchor-1">1. </a>Note</p></section>`
+	out, err := HTMLToTypst(in, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{
+		`#metadata(none) <a1-footnote-anchor-1>#link(<a1-footnote-1>)[1];`,
+		`#metadata(none) <a1-footnote-1>#link(<a1-footnote-anchor-1>)[1.];`,
+		`#link("#missing")[dangling];`,
+	} {
+		if !strings.Contains(out, w) {
+			t.Errorf("Typst lacks %q:\n%s", w, out)
+		}
+	}
+	if strings.Contains(out, "<missing>") {
+		t.Errorf("a link to an undefined id must not become a label reference:\n%s", out)
+	}
+}
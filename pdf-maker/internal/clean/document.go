@@ -0,0 +1,40 @@
+package clean
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HasBodyTag reports whether htmlContent contains a real <body> start tag, as
+// opposed to being a body fragment. goquery always synthesizes a body node, so
+// this has to be answered from the markup itself; the tokenizer is used rather
+// than a substring search so that "<body" appearing inside text (a code sample
+// about HTML), an attribute value, or a script is not mistaken for a tag.
+func HasBodyTag(htmlContent string) bool {
+	return hasStartTag(htmlContent, atom.Body)
+}
+
+// HasHTMLTag reports whether htmlContent contains a real <html> start tag,
+// i.e. whether it is a full document rather than a fragment.
+func HasHTMLTag(htmlContent string) bool {
+	return hasStartTag(htmlContent, atom.Html)
+}
+
+// hasStartTag scans htmlContent with the HTML tokenizer for a start tag of the
+// given element.
+func hasStartTag(htmlContent string, tag atom.Atom) bool {
+	z := html.NewTokenizer(strings.NewReader(htmlContent))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			if atom.Lookup(name) == tag {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package clean
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasBodyTag(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     bool
+	}{
+		{"fragment", `<p>Just a paragraph.</p>`, false},
+		{"document", `<html><head></head><body><p>x</p></body></html>`, true},
+		{"body with attributes", `<BODY class="post"><p>x</p></BODY>`, true},
+		{"escaped in a code sample", `<pre><code>&lt;body class="x"&gt;</code></pre>`, false},
+		{"in an attribute value", `<a title="wrap it in <body>" href="#">x</a>`, false},
+		{"in a comment", `<!-- <body> --><p>x</p>`, false},
+		{"in a script", `<script>document.write("<body>")</script><p>x</p>`, false},
+		{"in a textarea", `<textarea><body></textarea>`, false},
+		{"tbody is not body", `<table><tbody><tr><td>x</td></tr></tbody></table>`, false},
+	}
+	for _, tt := range tests {
+		if got := HasBodyTag(tt.in); got != tt.want {
+			t.Errorf("%s: HasBodyTag = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A fragment mentioning <body> where a substring check would see a tag must
+// come back as a fragment, not wrapped in or cut to a synthesized body.
+func TestCleanHTMLFragmentMentioningBody(t *testing.T) {
+	in := `<p>Put the script at the end of <code title="<body>">the body element</code>.</p>` +
+		`<pre><code>&lt;body&gt;
+  &lt;script src="app.js"&gt;&lt;/script&gt;
+&lt;/body&gt;</code></pre>`
+	out, _, err := CleanHTMLWithOptions(in, false, CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = strings.TrimSpace(out)
+	if strings.HasPrefix(out, "<body") || strings.Contains(out, "</body>") || strings.Contains(out, "<head>") {
+		t.Errorf("fragment came back as a document:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "<p>Put the script") {
+		t.Errorf("fragment not trimmed like other fragments:\n%s", out)
+	}
+	assertInOrder(t, out, `title="&lt;body&gt;"`, "the body element", "&lt;body&gt;", "app.js", "&lt;/body&gt;")
+}
@@ -0,0 +1,135 @@
+package clean
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// galleryClass marks a container of consecutive images grouped by
+// GroupImageGalleries; the stylesheets and HTMLToTypst lay its children out
+// as a grid. A second class, gallery-2 or gallery-3, gives the column count.
+const galleryClass = "gallery"
+
+// minGalleryRun is the number of consecutive images that form a gallery.
+const minGalleryRun = 3
+
+// galleryParents are the elements whose children may be grouped: those
+// that can hold a <div>. Images inside a paragraph or link stay inline.
+var galleryParents = map[string]bool{
+	"body": true, "div": true, "section": true, "article": true, "main": true,
+	"aside": true, "header": true, "footer": true, "blockquote": true, "li": true,
+}
+
+// GroupImageGalleries wraps every run of three or more consecutive image
+// blocks in <div class="gallery gallery-N">, so that photo-heavy posts print
+// two or three images per row instead of one per column width. An image
+// block is an element holding exactly one image and no text other than a
+// <figcaption>: a bare <img>, a <figure> (whose caption moves with it), a
+// <picture>, or a paragraph, link or Substack image container wrapping one
+// of these. Only whitespace may separate the blocks of a run, and the run
+// must sit directly in a block container (see galleryParents).
+// Returns the HTML and the number of galleries created.
+func GroupImageGalleries(htmlContent string) (string, int, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, err
+	}
+
+	var runs []*goquery.Selection
+	doc.Find("body, body *").Each(func(_ int, parent *goquery.Selection) {
+		if !galleryParents[goquery.NodeName(parent)] || isImageBlock(parent) || parent.HasClass(galleryClass) {
+			return
+		}
+		var run []*goquery.Selection
+		flush := func() {
+			if len(run) >= minGalleryRun {
+				sel := run[0]
+				for _, s := range run[1:] {
+					sel = sel.AddSelection(s)
+				}
+				runs = append(runs, sel)
+			}
+			run = nil
+		}
+		parent.Contents().Each(func(_ int, s *goquery.Selection) {
+			switch {
+			case goquery.NodeName(s) == "#text" && strings.TrimSpace(s.Text()) == "":
+				// whitespace between blocks does not break a run
+			case goquery.NodeName(s) == "#comment":
+			case isImageBlock(s):
+				run = append(run, s)
+			default:
+				flush()
+			}
+		})
+		flush()
+	})
+	if len(runs) == 0 {
+		return htmlContent, 0, nil
+	}
+	for _, run := range runs {
+		run.WrapAllHtml(fmt.Sprintf(`<div class="%s gallery-%d"></div>`, galleryClass, galleryColumns(run.Length())))
+	}
+
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", 0, err
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, len(runs), nil
+}
+
+// galleryColumns returns the column count for a gallery of n images: two
+// for four images (a 2×2 block), else three.
+func galleryColumns(n int) int {
+	if n == 4 {
+		return 2
+	}
+	return 3
+}
+
+// isImageBlock reports whether s is an element holding exactly one image
+// and no text outside a <figcaption>.
+func isImageBlock(s *goquery.Selection) bool {
+	switch goquery.NodeName(s) {
+	case "img":
+		return true
+	case "figure", "picture", "p", "a", "div":
+	default:
+		return false
+	}
+	if s.Find("img").Length() != 1 || s.Find("table, iframe, video, audio, ul, ol, blockquote, pre, h1, h2, h3, h4, h5, h6").Length() > 0 {
+		return false
+	}
+	rest := s.Clone()
+	rest.Find("figcaption").Remove()
+	return strings.TrimSpace(rest.Text()) == ""
+}
+
+// emitGallery converts a gallery container to a Typst grid with one cell
+// per image block.
+func emitGallery(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
+	if removeImages {
+		return
+	}
+	cols := 3
+	if s.HasClass("gallery-2") {
+		cols = 2
+	}
+	var cells []string
+	s.Children().Each(func(_ int, c *goquery.Selection) {
+		var cell strings.Builder
+		emitNode(c, &cell, removeImages)
+		if body := strings.TrimSpace(cell.String()); body != "" {
+			cells = append(cells, "["+body+"]")
+		}
+	})
+	if len(cells) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("#grid(columns: %d, gutter: 6pt,\n  %s,\n)\n\n", cols, strings.Join(cells, ",\n  ")))
+}
@@ -0,0 +1,64 @@
+package clean
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupImageGalleriesFiveStacked(t *testing.T) {
+	in := `<p>Before the photos.</p>
+<figure><img src="1.jpg"><figcaption>One</figcaption></figure>
+<p><img src="2.jpg"></p>
+<img src="3.jpg">
+<div class="captioned-image-container"><figure><a href="4.jpg"><img src="4.jpg"></a></figure></div>
+<picture><img src="5.jpg"></picture>
+<p>After the photos.</p>`
+	out, n, err := GroupImageGalleries(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("galleries = %d, want 1:\n%s", n, out)
+	}
+	start := strings.Index(out, `<div class="gallery gallery-3">`)
+	if start < 0 {
+		t.Fatalf("no three-column gallery:\n%s", out)
+	}
+	gallery := out[start:strings.Index(out, "After the photos.")]
+	for _, want := range []string{"1.jpg", "<figcaption>One</figcaption>", "2.jpg", "3.jpg", "4.jpg", "5.jpg"} {
+		if !strings.Contains(gallery, want) {
+			t.Errorf("gallery lacks %q:\n%s", want, gallery)
+		}
+	}
+	if strings.Contains(gallery, "Before the photos.") {
+		t.Errorf("gallery swallowed the preceding paragraph:\n%s", out)
+	}
+	if strings.Index(out, "1.jpg") > strings.Index(out, "5.jpg") {
+		t.Errorf("images reordered:\n%s", out)
+	}
+}
+
+func TestGroupImageGalleriesShortRunsAndText(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     int
+	}{
+		{"two images", `<img src="1.jpg"><img src="2.jpg">`, 0},
+		{"text breaks the run", `<img src="1.jpg"><img src="2.jpg"><p>Words.</p><img src="3.jpg"><img src="4.jpg">`, 0},
+		{"four images", `<img src="1.jpg"><img src="2.jpg"><img src="3.jpg"><img src="4.jpg">`, 1},
+		{"inline in a paragraph", `<p><img src="1.jpg"><img src="2.jpg"><img src="3.jpg"></p>`, 0},
+	}
+	for _, tt := range tests {
+		out, n, err := GroupImageGalleries(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tt.want {
+			t.Errorf("%s: galleries = %d, want %d:\n%s", tt.name, n, tt.want, out)
+		}
+	}
+	out, _, _ := GroupImageGalleries(`<img src="1.jpg"><img src="2.jpg"><img src="3.jpg"><img src="4.jpg">`)
+	if !strings.Contains(out, "gallery-2") {
+		t.Errorf("four images should make a 2×2 gallery:\n%s", out)
+	}
+}
@@ -1,9 +1,14 @@
 package clean
 
 import (
+	"fmt"
+	"html"
 	"strings"
+	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	nethtml "golang.org/x/net/html"
 )
 
 // Stats tracks the number of elements removed/modified during cleaning.
@@ -15,11 +20,78 @@ type Stats struct {
 	ImageIcons          int
 	FootnotesFormatted  int
 	ImagesRemoved       int
+	ShareCards          int
+	CustomRemoved       int // elements removed by CleanOptions.ExtraRemoveSelectors
+	NoscriptImages      int // images promoted out of <noscript> fallbacks
+	MathPreserved       int // equations kept as TeX source (CleanOptions.PreserveMath)
+	LazyImages          int // placeholder srcs replaced from data-src/srcset
+	HeadingsDemoted     int // headings moved down by CleanOptions.HeadingOffset
+}
+
+// Add accumulates o's counts into s, for totals across several articles.
+func (s *Stats) Add(o Stats) {
+	s.SubscriptionWidgets += o.SubscriptionWidgets
+	s.Forms += o.Forms
+	s.Inputs += o.Inputs
+	s.SubscriptionElems += o.SubscriptionElems
+	s.ImageIcons += o.ImageIcons
+	s.FootnotesFormatted += o.FootnotesFormatted
+	s.ImagesRemoved += o.ImagesRemoved
+	s.ShareCards += o.ShareCards
+	s.CustomRemoved += o.CustomRemoved
+	s.NoscriptImages += o.NoscriptImages
+	s.MathPreserved += o.MathPreserved
+	s.LazyImages += o.LazyImages
+	s.HeadingsDemoted += o.HeadingsDemoted
+}
+
+// Classes given to reformatted footnotes and to the FootnoteSection wrapper.
+const (
+	footnoteClass     = "footnote-note"
+	notesSectionClass = "article-notes"
+)
+
+// CleanOptions configures optional CleanHTML behavior.
+type CleanOptions struct {
+	// ExtraRemoveSelectors are CSS selectors for source-specific boilerplate
+	// (e.g. a newsletter's recurring "support us" banner). Every matching
+	// element is removed and counted in Stats.CustomRemoved.
+	ExtraRemoveSelectors []string
+
+	// FootnoteSection gathers the article's footnotes under a "Notes"
+	// subheading at the end of its content instead of leaving each where
+	// the source page put it.
+	FootnoteSection bool
+
+	// PreserveMath keeps MathJax/KaTeX/Substack equations readable without
+	// JavaScript by replacing them with their TeX source in monospace.
+	PreserveMath bool
+
+	// HeadingOffset demotes every content heading by this many levels
+	// (2: h1 → h3, h2 → h4; capped at h6) so headings in the body do not
+	// outrank the article title (h2) and subtitle (h3). 0 keeps the source
+	// hierarchy. See ValidateHeadingOffset. makepdf sets
+	// pdf.GenerateOptions.HeadingOffset instead, which also reaches content
+	// supplied in an issue's JSON; setting both demotes fetched pages twice.
+	HeadingOffset int
+}
+
+// ValidateSelector reports whether sel is a CSS selector CleanHTML can use.
+func ValidateSelector(sel string) error {
+	_, err := cascadia.Compile(sel)
+	return err
 }
 
 // CleanHTML removes subscription widgets, forms, and formats footnotes for better PDF rendering.
+// Data tables are preserved, including any wrapper elements around them.
 // Returns cleaned HTML string and statistics about what was removed.
 func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
+	return CleanHTMLWithOptions(htmlContent, verbose, CleanOptions{})
+}
+
+// CleanHTMLWithOptions is CleanHTML with the extra removal rules in opts.
+// Invalid selectors are skipped (see ValidateSelector).
+func CleanHTMLWithOptions(htmlContent string, verbose bool, opts CleanOptions) (string, Stats, error) {
 	stats := Stats{}
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
@@ -27,6 +99,16 @@ func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
 		return "", stats, err
 	}
 
+	// Promote <noscript> fallback images over their JS lazy-load placeholders
+	stats.NoscriptImages = unwrapNoscriptImages(doc)
+	// Swap remaining placeholder srcs for the data-src/srcset image
+	stats.LazyImages = promoteLazySources(doc)
+
+	// Keep equations as TeX source before scripts and icon spans are stripped
+	if opts.PreserveMath {
+		stats.MathPreserved = preserveMath(doc)
+	}
+
 	// Remove subscription widgets
 	doc.Find("div.subscription-widget-wrap-editor").Each(func(i int, s *goquery.Selection) {
 		s.Remove()
@@ -115,6 +197,30 @@ func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
 		}
 	})
 
+	// Remove "Share this post" cards: a post screenshot or caption plus a Share
+	// button linking back to the post with action=share. Containers are only
+	// removed when they hold such a share call-to-action.
+	shareCardSelectors := []string{
+		"[data-component-name='CaptionedButtonToDOM']", // <div class="captioned-button-wrap"> caption + button
+		"[data-component-name='ButtonCreateButton']",   // <p class="button-wrapper"> standalone button
+		"[data-component-name='SharePostToDOM']",
+		".captioned-button-wrap",
+		".share-dialog",
+		".post-share",
+		"[class*='share-card']",
+	}
+	for _, selector := range shareCardSelectors {
+		doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+			if hasShareCTA(s) {
+				s.Remove()
+				stats.ShareCards++
+			}
+		})
+	}
+
+	// Remove caller-supplied, source-specific selectors
+	stats.CustomRemoved = removeMatching(doc, opts.ExtraRemoveSelectors, verbose)
+
 	// Remove injected scripts (like live-server, analytics, etc.)
 	doc.Find("script").Each(func(i int, s *goquery.Selection) {
 		scriptContent, _ := s.Html()
@@ -175,7 +281,11 @@ func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
 	}
 
 	// Remove buttons and elements containing media control symbols (play, pause, etc.)
+	// Wrappers around data tables are skipped: financial tables use arrow glyphs too.
 	doc.Find("button, div, span").Each(func(i int, s *goquery.Selection) {
+		if s.Find("table").Length() > 0 {
+			return
+		}
 		text := s.Text()
 		// Check for common media control symbols
 		if strings.Contains(text, "⏸") || // pause symbol
@@ -214,40 +324,39 @@ func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
 			contentP := footnoteContent.Find("p").First()
 
 			if contentP.Length() > 0 {
-				// Create new inline paragraph
-				newP := doc.Find("body").AppendHtml("<p></p>").Find("p").Last()
-				newP.SetAttr("style", "margin-bottom: 6px; text-indent: -1em; padding-left: 1em;")
-
-				// Create footnote number anchor
+				// Build the replacement paragraph as markup and swap it in
+				// for the footnote itself, so nothing is staged in <body>
 				href, _ := footnoteNum.Attr("href")
 				id, _ := footnoteNum.Attr("id")
 				target, exists := footnoteNum.Attr("target")
 				if !exists {
 					target = "_self"
 				}
-
-				newA := doc.Find("body").AppendHtml("<a></a>").Find("a").Last()
-				newA.SetAttr("href", href)
-				newA.SetAttr("id", id)
-				newA.SetAttr("target", target)
-				newA.SetAttr("style", "font-weight: bold; text-decoration: none;")
-				newA.SetText(numberText + ". ")
-
-				// Get inner HTML of content paragraph
 				contentHTML, _ := contentP.Html()
 
-				// Move the anchor and content into the new paragraph
-				newP.Empty()
-				newP.AppendSelection(newA)
-				newP.AppendHtml(contentHTML)
-
-				// Replace original footnote with new format
-				footnote.ReplaceWithSelection(newP)
+				footnote.ReplaceWithHtml(fmt.Sprintf(
+					`<p class="%s" style="margin-bottom: 6px; text-indent: -1em; padding-left: 1em;">`+
+						`<a href="%s" id="%s" target="%s" style="font-weight: bold; text-decoration: none;">%s</a>%s</p>`,
+					footnoteClass,
+					html.EscapeString(href), html.EscapeString(id), html.EscapeString(target),
+					html.EscapeString(numberText+". "), contentHTML))
 				stats.FootnotesFormatted++
 			}
 		}
 	})
 
+	// Fit the content's headings below the article title and subtitle;
+	// done before the Notes heading below is added at its intended level
+	stats.HeadingsDemoted = demoteHeadings(doc, opts.HeadingOffset)
+
+	// Gather formatted footnotes into a Notes section at the end of this
+	// article's content (the cleaned fragment is always a single article)
+	if opts.FootnoteSection && stats.FootnotesFormatted > 0 {
+		body := doc.Find("body")
+		body.AppendHtml(`<section class="` + notesSectionClass + `"><h3>Notes</h3></section>`)
+		body.Children().Last().AppendSelection(doc.Find("p." + footnoteClass))
+	}
+
 	// Get cleaned HTML
 	cleaned, err := doc.Find("body").Html()
 	if err != nil {
@@ -255,7 +364,7 @@ func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
 	}
 
 	// If original content was a fragment (no body tag), extract just the body content
-	if !strings.Contains(htmlContent, "<body") {
+	if !HasBodyTag(htmlContent) {
 		cleaned = strings.TrimSpace(cleaned)
 	}
 
@@ -266,6 +375,72 @@ func CleanHTML(htmlContent string, verbose bool) (string, Stats, error) {
 	return cleaned, stats, nil
 }
 
+// removeMatching removes every element matching one of selectors and returns
+// how many were removed. Invalid selectors are skipped.
+func removeMatching(doc *goquery.Document, selectors []string, verbose bool) int {
+	removed := 0
+	for _, selector := range selectors {
+		compiled, err := cascadia.Compile(selector)
+		if err != nil {
+			continue
+		}
+		matched := doc.FindMatcher(compiled)
+		if verbose && matched.Length() > 0 {
+			fmt.Printf("  - Removed %d element(s) matching %q\n", matched.Length(), selector)
+		}
+		removed += matched.Length()
+		matched.Remove()
+	}
+	return removed
+}
+
+// RemoveSelectors removes every element matching one of selectors from
+// already-cleaned HTML, for removal rules that apply to a single article
+// rather than the whole batch. Invalid selectors are skipped (see
+// ValidateSelector). Returns the modified HTML and the number removed.
+func RemoveSelectors(htmlContent string, selectors []string) (string, int, error) {
+	if len(selectors) == 0 {
+		return htmlContent, 0, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, err
+	}
+	removed := removeMatching(doc, selectors, false)
+	if removed == 0 {
+		return htmlContent, 0, nil
+	}
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", 0, err
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, removed, nil
+}
+
+// hasShareCTA reports whether s contains a Substack share call-to-action: a
+// link with action=share / utm_content=share, or a button or link reading
+// "Share" / "Share this post".
+func hasShareCTA(s *goquery.Selection) bool {
+	found := false
+	s.Find("a, button").AddSelection(s.Filter("a, button")).EachWithBreak(func(_ int, el *goquery.Selection) bool {
+		href := strings.ToLower(el.AttrOr("href", ""))
+		if strings.Contains(href, "action=share") || strings.Contains(href, "utm_content=share") {
+			found = true
+			return false
+		}
+		text := strings.ToLower(strings.TrimSpace(el.Text()))
+		if text == "share" || strings.HasPrefix(text, "share this post") {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
 // normalizeWhitespace cleans up excessive whitespace and newlines in HTML
 // while preserving intentional spacing and structure
 func normalizeWhitespace(html string) string {
@@ -342,7 +517,7 @@ func RemoveAllImages(htmlContent string) (string, int, error) {
 	}
 
 	// If original content was a fragment (no body tag), extract just the body content
-	if !strings.Contains(htmlContent, "<body") {
+	if !HasBodyTag(htmlContent) {
 		cleaned = strings.TrimSpace(cleaned)
 	}
 
@@ -397,3 +572,185 @@ func paragraphFallback(content string) []string {
 	}
 	return blocks
 }
+
+// DedupeConsecutiveImages removes an <img> whose src is identical to the src of
+// the image immediately before it, with no text in between. Some newsletters
+// repeat the same banner back to back; once localized these all resolve to the
+// same cached file and only waste vertical space in the PDF. An image
+// separated from its twin by text or by a different image is kept.
+// When the duplicate is the only image inside a <figure>, the whole figure is
+// removed so an orphaned caption is not left behind.
+// Returns cleaned HTML string and count of images removed.
+func DedupeConsecutiveImages(htmlContent string) (string, int, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Walk the document in order; text that is not just whitespace ends a
+	// run of images, so only back-to-back repeats are collected
+	var dupes []*nethtml.Node
+	prevSrc := ""
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		switch {
+		case n.Type == nethtml.TextNode && strings.TrimSpace(n.Data) != "":
+			prevSrc = ""
+		case n.Type == nethtml.ElementNode && n.Data == "img":
+			src := ""
+			for _, a := range n.Attr {
+				if a.Key == "src" {
+					src = strings.TrimSpace(a.Val)
+				}
+			}
+			if src == "" {
+				return
+			}
+			if src == prevSrc {
+				dupes = append(dupes, n)
+			}
+			prevSrc = src
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n)
+	}
+
+	removed := 0
+	doc.FindNodes(dupes...).Each(func(_ int, s *goquery.Selection) {
+		if fig := s.Closest("figure"); fig.Length() > 0 && fig.Find("img").Length() == 1 {
+			fig.Remove()
+		} else {
+			s.Remove()
+		}
+		removed++
+	})
+
+	cleaned, err := doc.Find("body").Html()
+	if err != nil {
+		return "", removed, err
+	}
+
+	// If original content was a fragment (no body tag), extract just the body content
+	if !HasBodyTag(htmlContent) {
+		cleaned = strings.TrimSpace(cleaned)
+	}
+
+	return cleaned, removed, nil
+}
+
+// fitTableClass marks tables that should be scaled down to the page or column
+// width rather than overflowing it.
+const fitTableClass = "fit-width"
+
+// FitTables marks every <table> with the fit-width class, which the
+// stylesheets and HTMLToTypst use to shrink wide tables to the available
+// width instead of clipping them.
+// Returns the marked HTML string and count of tables marked.
+func FitTables(htmlContent string) (string, int, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", 0, err
+	}
+
+	tables := doc.Find("table")
+	if tables.Length() == 0 {
+		return htmlContent, 0, nil
+	}
+	tables.AddClass(fitTableClass)
+
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", 0, err
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, tables.Length(), nil
+}
+
+// AddDropCap wraps the first letter of the content's opening paragraph in
+// <span class="dropcap"> so the stylesheet can enlarge it. Leading punctuation
+// such as an opening quote is kept inside the span with the letter.
+// Content that opens with anything other than a text paragraph (an image,
+// figure, list, heading, blockquote, ...) is returned unchanged, as is a
+// paragraph whose first visible node is an image.
+// Returns the HTML and whether a drop cap was added.
+func AddDropCap(htmlContent string) (string, bool, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", false, err
+	}
+
+	// Unwrap single outer wrapper divs (Substack's "body markup" div).
+	source := doc.Find("body")
+	for source.Children().Length() == 1 && goquery.NodeName(source.Children().First()) == "div" {
+		source = source.Children().First()
+	}
+
+	first := source.Children().First()
+	if first.Length() == 0 || goquery.NodeName(first) != "p" {
+		return htmlContent, false, nil
+	}
+
+	textNode := firstTextNode(first)
+	if textNode == nil {
+		return htmlContent, false, nil
+	}
+
+	text := textNode.Text()
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	runes := []rune(trimmed)
+	split := 0
+	for split < len(runes) && unicode.IsPunct(runes[split]) {
+		split++
+	}
+	if split >= len(runes) || !unicode.IsLetter(runes[split]) && !unicode.IsDigit(runes[split]) {
+		return htmlContent, false, nil
+	}
+	split++
+
+	textNode.ReplaceWithHtml(`<span class="dropcap">` + html.EscapeString(string(runes[:split])) +
+		"</span>" + html.EscapeString(string(runes[split:])))
+
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return "", false, err
+	}
+
+	// If original content was a fragment (no body tag), extract just the body content
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return out, true, nil
+}
+
+// firstTextNode returns the first non-blank text node inside sel, descending
+// through inline wrappers. It returns nil if an image or line break comes
+// before any text.
+func firstTextNode(sel *goquery.Selection) *goquery.Selection {
+	var found *goquery.Selection
+	stop := false
+	var walk func(s *goquery.Selection)
+	walk = func(s *goquery.Selection) {
+		s.Contents().EachWithBreak(func(_ int, c *goquery.Selection) bool {
+			switch goquery.NodeName(c) {
+			case "#text":
+				if strings.TrimSpace(c.Text()) != "" {
+					found = c
+					stop = true
+				}
+			case "img", "picture", "svg", "br":
+				stop = true
+			default:
+				walk(c)
+			}
+			return !stop
+		})
+	}
+	walk(sel)
+	return found
+}
@@ -0,0 +1,145 @@
+package clean
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// substackNote is a footnote in Substack's markup.
+func substackNote(n, text string) string {
+	return `<div class="footnote"><a class="footnote-number" id="footnote-` + n + `" href="#footnote-anchor-` + n + `">` + n + `</a>` +
+		`<div class="footnote-content"><p>` + text + `</p></div></div>`
+}
+
+func TestCleanHTMLFootnoteSection(t *testing.T) {
+	in := `<p>First claim<a class="footnote-anchor" id="footnote-anchor-1" href="#footnote-1">1</a>.</p>` +
+		substackNote("1", "Note one.") +
+		`<p>Second claim<a class="footnote-anchor" id="footnote-anchor-2" href="#footnote-2">2</a>.</p>` +
+		substackNote("2", "Note <em>two</em>.") +
+		`<p>Closing paragraph.</p>`
+
+	t.Run("in place", func(t *testing.T) {
+		out, stats, err := CleanHTMLWithOptions(in, false, CleanOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.FootnotesFormatted != 2 {
+			t.Errorf("FootnotesFormatted = %d, want 2", stats.FootnotesFormatted)
+		}
+		if strings.Contains(out, notesSectionClass) {
+			t.Error("Notes section added without FootnoteSection")
+		}
+		assertInOrder(t, out, "First claim", "1. </a>Note one.", "Second claim", "2. </a>Note <em>two</em>.", "Closing paragraph.")
+	})
+
+	t.Run("section", func(t *testing.T) {
+		out, _, err := CleanHTMLWithOptions(in, false, CleanOptions{FootnoteSection: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertInOrder(t, out,
+			"First claim", "Second claim", "Closing paragraph.",
+			`<section class="article-notes"><h3>Notes</h3>`,
+			`id="footnote-1"`, "Note one.", `id="footnote-2"`, "Note <em>two</em>.", "</section>")
+		if n := strings.Count(out, "footnote-note"); n != 2 {
+			t.Errorf("%d formatted notes, want 2 (moved, not copied):\n%s", n, out)
+		}
+		if !strings.Contains(out, `href="#footnote-anchor-1"`) {
+			t.Error("note lost its back link")
+		}
+	})
+
+	t.Run("section heading not demoted", func(t *testing.T) {
+		out, _, err := CleanHTMLWithOptions(`<h2>Part</h2>`+in, false, CleanOptions{FootnoteSection: true, HeadingOffset: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertInOrder(t, out, "<h4>Part</h4>", "<h3>Notes</h3>")
+	})
+
+	t.Run("no footnotes, no section", func(t *testing.T) {
+		out, _, err := CleanHTMLWithOptions(`<p>Plain.</p>`, false, CleanOptions{FootnoteSection: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "Notes") {
+			t.Errorf("empty Notes section:\n%s", out)
+		}
+	})
+}
+
+func TestCleanHTMLShareCards(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "substack-share.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, stats, err := CleanHTMLWithOptions(string(in), false, CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ShareCards != 3 {
+		t.Errorf("ShareCards = %d, want 3", stats.ShareCards)
+	}
+	for _, gone := range []string{"action=share", "feel free to share it", "post-preview.png", "Share this post"} {
+		if strings.Contains(out, gone) {
+			t.Errorf("share card left behind %q:\n%s", gone, out)
+		}
+	}
+	assertInOrder(t, out, "Opening paragraph", "Middle paragraph", "Read part one", "chart.png", "Readers who share posts, by month.", "Closing paragraph")
+}
+
+func TestDedupeConsecutiveImages(t *testing.T) {
+	tests := []struct {
+		name, in    string
+		wantImages  int
+		wantRemoved int
+	}{
+		{"adjacent repeat", `<img src="a.png"><img src="a.png">`, 1, 1},
+		{"repeat across whitespace and markup", `<p><img src=" a.png "></p>` + "\n  " + `<p><img src="a.png"></p>`, 1, 1},
+		{"three in a row", `<img src="a.png"><img src="a.png"><img src="a.png">`, 1, 2},
+		{"separated by text", `<img src="a.png"><p>Section two</p><img src="a.png">`, 2, 0},
+		{"separated by another image", `<img src="a.png"><img src="b.png"><img src="a.png">`, 3, 0},
+		{"different images", `<img src="a.png"><img src="b.png">`, 2, 0},
+		{"no src is not a repeat", `<img><img>`, 2, 0},
+	}
+	for _, tt := range tests {
+		out, removed, err := DedupeConsecutiveImages(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed != tt.wantRemoved {
+			t.Errorf("%s: removed = %d, want %d", tt.name, removed, tt.wantRemoved)
+		}
+		if n := strings.Count(out, "<img"); n != tt.wantImages {
+			t.Errorf("%s: %d images left, want %d:\n%s", tt.name, n, tt.wantImages, out)
+		}
+	}
+
+	// A repeated image alone in its figure takes the figure along
+	out, removed, err := DedupeConsecutiveImages(`<figure><img src="a.png"></figure><figure><img src="a.png"></figure>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || strings.Count(out, "<figure>") != 1 {
+		t.Errorf("figure repeat: removed %d, got %s", removed, out)
+	}
+	if out, _, _ := DedupeConsecutiveImages(`<p>No images here.</p>`); out != `<p>No images here.</p>` {
+		t.Errorf("content without images changed: %s", out)
+	}
+}
+
+// assertInOrder fails unless every string in want occurs in s, in order.
+func assertInOrder(t *testing.T, s string, want ...string) {
+	t.Helper()
+	at := 0
+	for _, w := range want {
+		i := strings.Index(s[at:], w)
+		if i < 0 {
+			t.Errorf("%q missing or out of order in:\n%s", w, s)
+			return
+		}
+		at += i + len(w)
+	}
+}
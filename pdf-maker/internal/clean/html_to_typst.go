@@ -2,6 +2,8 @@ package clean
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -13,8 +15,13 @@ import (
 // inline formatting.
 //
 // Images are rendered as #image("<absPath>", width: 100%) using the absolute
-// filesystem path already written by the media downloader. When removeImages
+// filesystem path already written by the media downloader, with the <img>
+// alt attribute as the image's alt text. When removeImages
 // is true all <img> elements are silently skipped.
+//
+// In-page links (href="#id", e.g. footnote references and their back
+// links) become #link(<id>) to a label placed where the element with that
+// id starts (see markTypstAnchors), so they jump within the PDF.
 func HTMLToTypst(htmlContent string, removeImages bool) (string, error) {
 	if strings.TrimSpace(htmlContent) == "" {
 		return "", nil
@@ -25,11 +32,60 @@ func HTMLToTypst(htmlContent string, removeImages bool) (string, error) {
 		return "", fmt.Errorf("parse html: %w", err)
 	}
 
+	root := doc.Find("#__root")
+	markTypstAnchors(root)
 	var sb strings.Builder
-	convertNode(doc.Find("#__root"), &sb, removeImages)
+	convertNode(root, &sb, removeImages)
 	return strings.TrimSpace(sb.String()), nil
 }
 
+// Attributes markTypstAnchors sets for emitNode: the label an element
+// starts with, and the label a link points at.
+const (
+	typstLabelAttr = "data-typst-label"
+	typstRefAttr   = "data-typst-ref"
+)
+
+// typstLabelRe matches ids usable as Typst labels as they are.
+var typstLabelRe = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// markTypstAnchors pairs in-page links with their targets under root. An
+// id some link points at gets a label, at its first occurrence only (Typst
+// rejects links to a label that occurs twice), and the links to it are
+// marked to use that label. Links to ids that are missing, or not valid
+// as a label, stay ordinary links, as a link to an undefined label would
+// fail the whole compile.
+func markTypstAnchors(root *goquery.Selection) {
+	linked := make(map[string]bool)
+	root.Find("a[href^='#']").Each(func(_ int, a *goquery.Selection) {
+		linked[strings.TrimPrefix(a.AttrOr("href", ""), "#")] = true
+	})
+	if len(linked) == 0 {
+		return
+	}
+	labelled := make(map[string]bool)
+	root.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if linked[id] && !labelled[id] && typstLabelRe.MatchString(id) {
+			labelled[id] = true
+			s.SetAttr(typstLabelAttr, id)
+		}
+	})
+	root.Find("a[href^='#']").Each(func(_ int, a *goquery.Selection) {
+		if id := strings.TrimPrefix(a.AttrOr("href", ""), "#"); labelled[id] {
+			a.SetAttr(typstRefAttr, id)
+		}
+	})
+}
+
+// typstInlineTags are the elements a label is placed inside a paragraph
+// for; before any other element it goes on a line of its own, so headings
+// and list items still start their line.
+var typstInlineTags = map[string]bool{
+	"a": true, "span": true, "sup": true, "sub": true, "em": true, "i": true,
+	"strong": true, "b": true, "code": true, "small": true, "mark": true,
+}
+
 // convertNode walks a goquery selection and emits Typst markup into sb.
 func convertNode(sel *goquery.Selection, sb *strings.Builder, removeImages bool) {
 	sel.Contents().Each(func(_ int, s *goquery.Selection) {
@@ -48,6 +104,13 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 	}
 
 	tag := goquery.NodeName(s)
+	if label := s.AttrOr(typstLabelAttr, ""); label != "" {
+		// An invisible, locatable element carries the label
+		sb.WriteString("#metadata(none) <" + label + ">")
+		if !typstInlineTags[tag] {
+			sb.WriteString("\n")
+		}
+	}
 	switch tag {
 	case "p":
 		var inner strings.Builder
@@ -165,7 +228,7 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 			return
 		}
 		alt, _ := s.Attr("alt")
-		sb.WriteString(fmt.Sprintf("#figure(\n  image(%q, width: 100%%),\n", src))
+		sb.WriteString(fmt.Sprintf("#figure(\n  %s,\n", typstImage(src, alt)))
 		if alt != "" {
 			sb.WriteString(fmt.Sprintf("  caption: [%s],\n", escapeTypst(alt)))
 		}
@@ -178,7 +241,7 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 		if img.Length() > 0 && !removeImages {
 			src, exists := img.Attr("src")
 			if exists && src != "" {
-				sb.WriteString(fmt.Sprintf("#figure(\n  image(%q, width: 100%%),\n", src))
+				sb.WriteString(fmt.Sprintf("#figure(\n  %s,\n", typstImage(src, img.AttrOr("alt", ""))))
 				if caption != "" {
 					sb.WriteString(fmt.Sprintf("  caption: [%s],\n", escapeTypst(caption)))
 				}
@@ -219,7 +282,13 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 		if body == "" {
 			body = href
 		}
-		if href != "" && href != body {
+		if ref := s.AttrOr(typstRefAttr, ""); ref != "" {
+			if body == href {
+				body = escapeTypst(href)
+			}
+			// The trailing ";" ends the expression, as for #link("url") below
+			sb.WriteString(fmt.Sprintf("#link(<%s>)[%s];", ref, body))
+		} else if href != "" && href != body {
 			// Append ";" to explicitly terminate the #link expression.
 			// In Typst markup mode, after any #expr, a following "(" is
 			// greedily consumed as a call suffix on the expression's result —
@@ -227,7 +296,7 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 			// escape hatch is a semicolon, which terminates the hash
 			// expression and is never included in rendered output.
 			// Example: "#link("u")[t];(more)" renders as link + "(more)".
-			sb.WriteString(fmt.Sprintf("#link(%q)[%s];", href, body))
+			sb.WriteString(fmt.Sprintf("#link(%s)[%s];", TypstString(href), body))
 		} else {
 			sb.WriteString(body)
 		}
@@ -239,6 +308,10 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 		sb.WriteString("\n#line(length: 100%, stroke: 0.5pt)\n\n")
 
 	case "code":
+		if s.HasClass(mathClass) {
+			sb.WriteString(fmt.Sprintf("#raw(%s)", TypstString(s.Text())))
+			return
+		}
 		var inner strings.Builder
 		convertNode(s, &inner, removeImages)
 		body := inner.String()
@@ -249,6 +322,10 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 		}
 
 	case "pre":
+		if s.HasClass(mathClass) {
+			sb.WriteString(fmt.Sprintf("#align(center, raw(%s, block: true))\n\n", TypstString(s.Text())))
+			return
+		}
 		var inner strings.Builder
 		// For pre, collect raw text
 		inner.WriteString(s.Text())
@@ -281,6 +358,10 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 		convertNode(s, sb, removeImages)
 
 	case "div":
+		if s.HasClass(galleryClass) {
+			emitGallery(s, sb, removeImages)
+			return
+		}
 		// Generic div: recurse into children, adding a paragraph break after
 		var inner strings.Builder
 		convertNode(s, &inner, removeImages)
@@ -302,37 +383,118 @@ func emitNode(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 	}
 }
 
-// emitTable converts a basic HTML table to Typst table syntax.
+// emitTable converts a basic HTML table to Typst table syntax. Rows inside
+// <thead> (or a leading row made only of <th> cells) become a table.header so
+// Typst repeats them when the table breaks across pages. Tables marked by
+// FitTables are scaled down to the available width instead of overflowing.
 func emitTable(s *goquery.Selection, sb *strings.Builder, removeImages bool) {
 	var rows [][]string
-	s.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+	headerRows := 0
+	s.Find("tr").Each(func(i int, tr *goquery.Selection) {
 		var row []string
-		tr.Find("th, td").Each(func(_ int, td *goquery.Selection) {
+		cells := tr.Find("th, td")
+		cells.Each(func(_ int, td *goquery.Selection) {
 			var cell strings.Builder
 			convertNode(td, &cell, removeImages)
 			row = append(row, strings.TrimSpace(cell.String()))
 		})
-		if len(row) > 0 {
-			rows = append(rows, row)
+		if len(row) == 0 {
+			return
 		}
+		isHeader := tr.ParentsFiltered("thead").Length() > 0 ||
+			(len(rows) == headerRows && cells.Length() == tr.Find("th").Length())
+		if isHeader && len(rows) == headerRows {
+			headerRows++
+		}
+		rows = append(rows, row)
 	})
 	if len(rows) == 0 {
 		return
 	}
 
-	// Determine column count from first row
-	cols := len(rows[0])
-	if cols == 0 {
+	// Column count is the widest row; short rows are padded with empty cells
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	if headerRows == len(rows) {
+		headerRows = 0
+	}
+
+	var tb strings.Builder
+	tb.WriteString(fmt.Sprintf("table(\n  columns: %d,\n", cols))
+	for i, row := range rows {
+		if i == 0 && headerRows > 0 {
+			tb.WriteString("  table.header(\n")
+		}
+		for j := 0; j < cols; j++ {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			if i < headerRows && cell != "" {
+				cell = "#strong[" + cell + "]"
+			}
+			tb.WriteString(fmt.Sprintf("  [%s],\n", cell))
+		}
+		if i == headerRows-1 {
+			tb.WriteString("  ),\n")
+		}
+	}
+	tb.WriteString(")")
+
+	if s.HasClass(fitTableClass) {
+		sb.WriteString("#layout(size => {\n")
+		sb.WriteString("  let t = " + tb.String() + "\n")
+		sb.WriteString("  let w = measure(t).width\n")
+		sb.WriteString("  if w > size.width { scale(x: size.width / w * 100%, y: size.width / w * 100%, reflow: true, t) } else { t }\n")
+		sb.WriteString("})\n\n")
 		return
 	}
+	sb.WriteString("#" + tb.String() + "\n\n")
+}
 
-	sb.WriteString(fmt.Sprintf("#table(\n  columns: %d,\n", cols))
-	for _, row := range rows {
-		for _, cell := range row {
-			sb.WriteString(fmt.Sprintf("  [%s],\n", cell))
+// typstImage returns the image() call for a figure, carrying alt as the
+// image's alternative text when present. stripBadImage in the pdf package
+// locates figures by the `image("<path>", width: 100%` prefix.
+func typstImage(src, alt string) string {
+	alt = strings.Join(strings.Fields(alt), " ")
+	if alt == "" {
+		return fmt.Sprintf("image(%s, width: 100%%)", TypstString(src))
+	}
+	return fmt.Sprintf("image(%s, width: 100%%, alt: %s)", TypstString(src), TypstString(alt))
+}
+
+// TypstString quotes s as a Typst string literal, for paths, URLs, alt
+// text, code and document properties in generated Typst code. Typst
+// strings take \\, \", \n, \r, \t and \u{hex} escapes; Go's %q writes \x,
+// \u and \U forms for control and invisible characters that Typst rejects.
+func TypstString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case !strconv.IsPrint(r):
+			sb.WriteString(fmt.Sprintf(`\u{%x}`, r))
+		default:
+			sb.WriteRune(r)
 		}
 	}
-	sb.WriteString(")\n\n")
+	sb.WriteByte('"')
+	return sb.String()
 }
 
 // escapeTypst escapes characters that have special meaning in Typst markup.
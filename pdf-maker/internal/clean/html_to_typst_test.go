@@ -0,0 +1,40 @@
+package clean
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypstString(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`plain`, `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path`, `"C:\\path"`},
+		{"a\nb\r\tc", `"a\nb\r\tc"`},
+		{"bell\x07 and del\x7f", `"bell\u{7} and del\u{7f}"`},
+		{"zero\u200bwidth", `"zero\u{200b}width"`},
+		{"café ✓ שלום", `"café ✓ שלום"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := TypstString(tt.in); got != tt.want {
+			t.Errorf("TypstString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLToTypstQuotesMathAndImages(t *testing.T) {
+	out, err := HTMLToTypst(`<p>Inline <code class="math">\frac{"a"}{b}</code></p><pre class="math">x := "\u00e9"`+"\x01"+`</pre><img src="/tmp/a &quot;b&quot;.png" alt="Say &quot;cheese&quot;">`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`#raw("\\frac{\"a\"}{b}")`,
+		`raw("x := \"\\u00e9\"\u{1}", block: true)`,
+		`image("/tmp/a \"b\".png", width: 100%, alt: "Say \"cheese\"")`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %s:\n%s", want, out)
+		}
+	}
+}
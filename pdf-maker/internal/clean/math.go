@@ -0,0 +1,114 @@
+package clean
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// mathClass marks the <code> (inline) and <pre> (display) elements that
+// preserveMath produces; HTMLToTypst renders them as raw text.
+const mathClass = "math"
+
+// texDelimiters matches TeX left in plain text by MathJax/KaTeX auto-render:
+// $$...$$, \[...\] (display) and \(...\) (inline). Single-dollar math is not
+// matched because it cannot be told apart from prices.
+var texDelimiters = regexp.MustCompile(`(?s)\$\$(.+?)\$\$|\\\[(.+?)\\\]|\\\((.+?)\\\)`)
+
+// preserveMath replaces math markup that needs JavaScript to render with its
+// TeX source in monospace: <code class="math"> inline, <pre class="math"> for
+// display equations that stand on their own (not inside a paragraph). It recognises Substack LaTeX blocks, KaTeX output (via
+// its TeX annotation), MathJax 2 script tags, and TeX delimiters in text.
+// Returns the number of expressions preserved.
+func preserveMath(doc *goquery.Document) int {
+	count := 0
+
+	// Substack: <div class="latex-rendered" data-attrs='{"persistentExpression": "..."}'>
+	doc.Find("div.latex-rendered[data-attrs]").Each(func(_ int, s *goquery.Selection) {
+		var attrs struct {
+			Expression string `json:"persistentExpression"`
+		}
+		if json.Unmarshal([]byte(s.AttrOr("data-attrs", "")), &attrs) != nil || strings.TrimSpace(attrs.Expression) == "" {
+			return
+		}
+		s.ReplaceWithHtml(mathHTML(attrs.Expression, true))
+		count++
+	})
+
+	// KaTeX: the TeX source survives in the MathML annotation
+	doc.Find(".katex-display, .katex").Each(func(_ int, s *goquery.Selection) {
+		if s.Closest("html").Length() == 0 { // detached: its .katex-display was replaced
+			return
+		}
+		tex := strings.TrimSpace(s.Find("annotation[encoding='application/x-tex']").First().Text())
+		if tex == "" {
+			return
+		}
+		s.ReplaceWithHtml(mathHTML(tex, s.HasClass("katex-display") && !inTextBlock(s)))
+		count++
+	})
+
+	// MathJax 2: <script type="math/tex[; mode=display]"> next to its rendered output
+	doc.Find(".MathJax_Preview, .MathJax, .MathJax_Display, .MathJax_SVG, .MathJax_SVG_Display").Remove()
+	doc.Find("script[type^='math/tex']").Each(func(_ int, s *goquery.Selection) {
+		tex := strings.TrimSpace(s.Text())
+		if tex == "" {
+			s.Remove()
+			return
+		}
+		s.ReplaceWithHtml(mathHTML(tex, strings.Contains(s.AttrOr("type", ""), "mode=display") && !inTextBlock(s)))
+		count++
+	})
+
+	// Delimited TeX in text nodes (outside code blocks)
+	doc.Find("body *").Not("code, pre, script, style").Contents().Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) != "#text" || s.ParentsFiltered("code, pre").Length() > 0 {
+			return
+		}
+		text := s.Text()
+		locs := texDelimiters.FindAllStringSubmatchIndex(text, -1)
+		if len(locs) == 0 {
+			return
+		}
+		var sb strings.Builder
+		last := 0
+		for _, m := range locs {
+			sb.WriteString(html.EscapeString(text[last:m[0]]))
+			var tex string
+			switch {
+			case m[2] >= 0:
+				tex = text[m[2]:m[3]]
+			case m[4] >= 0:
+				tex = text[m[4]:m[5]]
+			default:
+				tex = text[m[6]:m[7]]
+			}
+			// Always inline: a <pre> would split the surrounding paragraph
+			sb.WriteString(mathHTML(tex, false))
+			last = m[1]
+			count++
+		}
+		sb.WriteString(html.EscapeString(text[last:]))
+		s.ReplaceWithHtml(sb.String())
+	})
+
+	return count
+}
+
+// inTextBlock reports whether s sits inside a paragraph, list item or
+// heading, where a block-level <pre> would break the text apart.
+func inTextBlock(s *goquery.Selection) bool {
+	return s.ParentsFiltered("p, li, h1, h2, h3, h4, h5, h6").Length() > 0
+}
+
+// mathHTML wraps TeX source in the element preserveMath emits.
+func mathHTML(tex string, display bool) string {
+	tex = html.EscapeString(strings.TrimSpace(tex))
+	if display {
+		return `<pre class="` + mathClass + `">` + tex + `</pre>`
+	}
+	return `<code class="` + mathClass + `">` + tex + `</code>`
+}
@@ -0,0 +1,61 @@
+package clean
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// unwrapNoscriptImages replaces <noscript> fallbacks that carry images with
+// the images themselves. Lazy-loading sites often pair a JS-driven placeholder
+// <img> (empty or data: src, a "lazy" class) with the real image inside
+// <noscript>; since neither renderer runs JavaScript, the placeholder would
+// render blank. Placeholder images sharing the <noscript>'s parent are
+// removed. Returns the number of images promoted.
+//
+// The HTML parser runs with scripting enabled, so a <noscript>'s children
+// arrive as a single raw text node and are re-parsed here.
+func unwrapNoscriptImages(doc *goquery.Document) int {
+	promoted := 0
+	doc.Find("noscript").Each(func(_ int, ns *goquery.Selection) {
+		inner := ns.Text()
+		if !strings.Contains(strings.ToLower(inner), "<img") {
+			return
+		}
+		frag, err := goquery.NewDocumentFromReader(strings.NewReader(inner))
+		if err != nil {
+			return
+		}
+		var imgs []string
+		frag.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+			if src := strings.TrimSpace(img.AttrOr("src", "")); src == "" || strings.HasPrefix(src, "data:") {
+				return
+			}
+			if h, err := goquery.OuterHtml(img); err == nil {
+				imgs = append(imgs, h)
+			}
+		})
+		if len(imgs) == 0 {
+			return
+		}
+		ns.Parent().Children().Filter("img").Each(func(_ int, img *goquery.Selection) {
+			if isPlaceholderImage(img) {
+				img.Remove()
+			}
+		})
+		ns.ReplaceWithHtml(strings.Join(imgs, ""))
+		promoted += len(imgs)
+	})
+	return promoted
+}
+
+// isPlaceholderImage reports whether img is a lazy-load stand-in rather than
+// a real image: no src, an inline data: URI, a src IsPlaceholder flags, or a
+// "lazy" class.
+func isPlaceholderImage(img *goquery.Selection) bool {
+	src := strings.TrimSpace(img.AttrOr("src", ""))
+	if strings.HasPrefix(src, "data:") || IsPlaceholder(src) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(img.AttrOr("class", "")), "lazy")
+}
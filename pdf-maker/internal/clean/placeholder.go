@@ -0,0 +1,206 @@
+package clean
+
+import (
+	"encoding/base64"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PlaceholderRules tunes IsPlaceholder. The zero value flags only empty
+// sources; DefaultPlaceholderRules holds the values used by the pipeline.
+type PlaceholderRules struct {
+	// MaxDimension flags URLs whose width or height query parameter
+	// (w, width, h, height) is at most this many pixels, e.g. the "?w=50"
+	// blur-up previews some CDNs put in src.
+	MaxDimension int
+	// SpacerNames are file names (without extension, lower case) of known
+	// spacer and loading images, such as "spacer" or "blank".
+	SpacerNames []string
+	// MaxDataURIBytes flags inline data: URIs shorter than this; a 1x1
+	// transparent GIF is well under 100 bytes. Data GIFs and PNGs whose
+	// header declares a 1x1 image are flagged regardless of length.
+	MaxDataURIBytes int
+}
+
+// DefaultPlaceholderRules are the rules IsPlaceholder applies. Adjust them
+// before processing to tune the heuristic.
+var DefaultPlaceholderRules = PlaceholderRules{
+	MaxDimension: 60,
+	SpacerNames: []string{
+		"spacer", "blank", "pixel", "transparent", "1x1", "clear",
+		"placeholder", "lazy", "lazyload", "loading", "loader", "grey", "gray",
+	},
+	MaxDataURIBytes: 200,
+}
+
+// lazySourceAttrs are the attributes lazy-loading scripts read the real
+// image URL from, in order of preference.
+var lazySourceAttrs = []string{"data-src", "data-original", "data-lazy-src", "data-url"}
+
+// lazySrcsetAttrs are srcset equivalents used by lazy-loading scripts.
+var lazySrcsetAttrs = []string{"srcset", "data-srcset", "data-lazy-srcset"}
+
+// IsPlaceholder reports whether src looks like a lazy-load stand-in rather
+// than the real image, using DefaultPlaceholderRules.
+func IsPlaceholder(src string) bool {
+	return DefaultPlaceholderRules.IsPlaceholder(src)
+}
+
+// IsPlaceholder reports whether src is empty, a tiny inline data: image, a
+// known spacer file, or a URL asking for a tiny rendition.
+func (r PlaceholderRules) IsPlaceholder(src string) bool {
+	src = strings.TrimSpace(src)
+	if src == "" || src == "#" || strings.HasPrefix(src, "about:") {
+		return true
+	}
+	if strings.HasPrefix(src, "data:") {
+		return len(src) < r.MaxDataURIBytes || isOnePixelDataImage(src)
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+	name := strings.ToLower(path.Base(u.Path))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	for _, s := range r.SpacerNames {
+		if name == s {
+			return true
+		}
+	}
+	if r.MaxDimension > 0 {
+		q := u.Query()
+		for _, key := range []string{"w", "width", "h", "height"} {
+			if n, err := strconv.Atoi(q.Get(key)); err == nil && n > 0 && n <= r.MaxDimension {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isOnePixelDataImage reports whether a base64 data: URI holds a GIF or PNG
+// whose header declares a 1x1 image.
+func isOnePixelDataImage(src string) bool {
+	comma := strings.IndexByte(src, ',')
+	if comma < 0 || !strings.HasSuffix(src[:comma], ";base64") {
+		return false
+	}
+	payload := src[comma+1:]
+	if len(payload) > 64 {
+		payload = payload[:64] // the header is all we need
+	}
+	payload = payload[:len(payload)/4*4]
+	b, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return false
+	}
+	switch {
+	case len(b) >= 10 && strings.HasPrefix(string(b), "GIF8"):
+		return b[6] == 1 && b[7] == 0 && b[8] == 1 && b[9] == 0
+	case len(b) >= 24 && strings.HasPrefix(string(b), "\x89PNG"):
+		return string(b[16:24]) == "\x00\x00\x00\x01\x00\x00\x00\x01"
+	}
+	return false
+}
+
+// ImageSource returns the URL that should be downloaded for img. Its src is
+// used unless it IsPlaceholder, in which case the first lazy-load attribute
+// (data-src and friends) wins, then the largest candidate from the image's
+// srcset or its <picture> sources. Falls back to src when nothing better
+// is found.
+func ImageSource(img *goquery.Selection) string {
+	src := strings.TrimSpace(img.AttrOr("src", ""))
+	if !IsPlaceholder(src) {
+		return src
+	}
+	for _, attr := range lazySourceAttrs {
+		if v := strings.TrimSpace(img.AttrOr(attr, "")); v != "" && !IsPlaceholder(v) {
+			return v
+		}
+	}
+	sets := []*goquery.Selection{img}
+	if pic := img.Parent(); goquery.NodeName(pic) == "picture" {
+		sets = append(sets, pic.Find("source"))
+	}
+	for _, s := range sets {
+		for _, attr := range lazySrcsetAttrs {
+			best := ""
+			s.Each(func(_ int, el *goquery.Selection) {
+				if best == "" {
+					best = largestSrcsetCandidate(el.AttrOr(attr, ""))
+				}
+			})
+			if best != "" {
+				return best
+			}
+		}
+	}
+	return src
+}
+
+// promoteLazySources rewrites the src of every <img> whose src is a
+// placeholder to the URL ImageSource picks, so renderers without
+// JavaScript (and the image downloader) see the real image. Returns the
+// number of images changed.
+func promoteLazySources(doc *goquery.Document) int {
+	promoted := 0
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		src := strings.TrimSpace(img.AttrOr("src", ""))
+		if !IsPlaceholder(src) {
+			return
+		}
+		if real := ImageSource(img); real != "" && real != src {
+			img.SetAttr("src", real)
+			promoted++
+		}
+	})
+	return promoted
+}
+
+// largestSrcsetCandidate returns the URL of the widest candidate in a
+// srcset ("a.jpg 400w, b.jpg 800w" or "a.jpg 1x, b.jpg 2x"), or "". URLs
+// may themselves contain commas (CDN transform paths such as
+// "w_424,c_limit"), so candidates are split per the HTML spec: a URL runs
+// to the next whitespace, and a comma only separates candidates after it.
+func largestSrcsetCandidate(srcset string) string {
+	best, bestSize := "", -1.0
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, " \t\n\r\f,")
+		if s == "" {
+			break
+		}
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end < 0 {
+			end = len(s)
+		}
+		candidate := s[:end]
+		s = s[end:]
+		descriptor := ""
+		if !strings.HasSuffix(candidate, ",") {
+			if c := strings.IndexByte(s, ','); c >= 0 {
+				descriptor, s = s[:c], s[c+1:]
+			} else {
+				descriptor, s = s, ""
+			}
+		}
+		candidate = strings.TrimRight(candidate, ",")
+		if candidate == "" || IsPlaceholder(candidate) {
+			continue
+		}
+		size := 1.0 // no descriptor means 1x
+		if d := strings.TrimSpace(descriptor); len(d) > 1 {
+			if v, err := strconv.ParseFloat(d[:len(d)-1], 64); err == nil {
+				size = v
+			}
+		}
+		if size > bestSize {
+			best, bestSize = candidate, size
+		}
+	}
+	return best
+}
@@ -0,0 +1,86 @@
+package clean
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// readerKeepTags are the elements ReaderMode keeps: headings, paragraphs,
+// lists, blockquotes, images, and the inline elements needed for readable text.
+var readerKeepTags = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"p": true, "br": true, "blockquote": true,
+	"ul": true, "ol": true, "li": true,
+	"img": true, "a": true,
+	"em": true, "i": true, "strong": true, "b": true,
+	"sup": true, "sub": true, "code": true, "pre": true,
+}
+
+// readerDropTags are removed together with everything inside them.
+var readerDropTags = []string{
+	"script", "style", "noscript", "template",
+	"iframe", "embed", "object", "video", "audio", "svg", "canvas",
+	"table", "form", "button", "input", "select", "textarea",
+	"figcaption",
+}
+
+// readerKeepAttrs are the only attributes ReaderMode preserves.
+var readerKeepAttrs = map[string]bool{"src": true, "alt": true, "href": true}
+
+// ReaderMode aggressively simplifies article HTML for minimalist readers (e.g.
+// e-ink). Only the readerKeepTags whitelist survives: tables, embeds and media
+// players are dropped with their contents, any other wrapper (div, span,
+// figure, picture, ...) is unwrapped so its text and images are kept, and all
+// attributes except src/alt/href are stripped. It is independent of CleanHTML
+// and can be composed with it in either order.
+func ReaderMode(htmlContent string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", err
+	}
+	body := doc.Find("body")
+
+	body.Find(strings.Join(readerDropTags, ", ")).Remove()
+
+	// Unwrap non-whitelisted elements deepest-first so nested wrappers collapse.
+	all := body.Find("*")
+	for i := all.Length() - 1; i >= 0; i-- {
+		s := all.Eq(i)
+		if !readerKeepTags[goquery.NodeName(s)] {
+			if s.Children().Length() == 0 && strings.TrimSpace(s.Text()) == "" {
+				s.Remove()
+			} else {
+				s.Contents().Unwrap()
+			}
+		}
+	}
+
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		for _, n := range s.Nodes {
+			attrs := n.Attr[:0]
+			for _, a := range n.Attr {
+				if readerKeepAttrs[a.Key] {
+					attrs = append(attrs, a)
+				}
+			}
+			n.Attr = attrs
+		}
+	})
+
+	// Images without a source are useless once attributes are stripped.
+	body.Find("img").Each(func(_ int, s *goquery.Selection) {
+		if strings.TrimSpace(s.AttrOr("src", "")) == "" {
+			s.Remove()
+		}
+	})
+
+	out, err := body.Html()
+	if err != nil {
+		return "", err
+	}
+	if !HasBodyTag(htmlContent) {
+		out = strings.TrimSpace(out)
+	}
+	return normalizeWhitespace(out), nil
+}
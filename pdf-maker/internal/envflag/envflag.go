@@ -0,0 +1,62 @@
+// Package envflag lets the commands take their flag defaults from the
+// environment, which is easier to configure than a command line in a
+// container. Every flag -some-name is read from PREFIX_SOME_NAME; a flag
+// given on the command line overrides the environment.
+package envflag
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Prefix is the prefix of the environment variables the commands read.
+const Prefix = "N2P"
+
+// Name returns the environment variable for the flag name under prefix:
+// "max-par" becomes "N2P_MAX_PAR".
+func Name(prefix, flagName string) string {
+	return prefix + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+}
+
+// Apply sets every flag of fs that was not given on the command line from
+// its environment variable (see Name), or from an alias listed for it in
+// aliases (flag name → variable), when set. Call it after fs.Parse. A
+// repeatable flag takes a single value from the environment.
+func Apply(fs *flag.FlagSet, prefix string, aliases map[string]string) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var errs []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] {
+			return
+		}
+		name := Name(prefix, f.Name)
+		v, ok := os.LookupEnv(name)
+		if alias := aliases[f.Name]; !ok && alias != "" {
+			name = alias
+			v, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			return
+		}
+		if err := fs.Set(f.Name, v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s=%q: %v", name, v, err))
+		}
+	})
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("invalid environment: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Usage returns a note for a command's help text saying how its flags map
+// to environment variables.
+func Usage(prefix string) string {
+	return fmt.Sprintf("Every flag can also be set through the environment as %s_<FLAG>, e.g. -max-par as %s; flags on the command line take precedence.\n",
+		prefix, Name(prefix, "max-par"))
+}
@@ -0,0 +1,314 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Archive listing limits. Substack's archive API serves at most archivePageSize
+// posts per request; maxArchivePages stops runaway pagination on sites whose
+// "next" links loop.
+const (
+	archivePageSize = 12
+	maxArchivePages = 20
+	maxArchiveBytes = 10 * 1024 * 1024
+)
+
+// substackArchivePost is the part of a Substack /api/v1/archive entry we use.
+type substackArchivePost struct {
+	CanonicalURL string `json:"canonical_url"`
+	Slug         string `json:"slug"`
+}
+
+// archiveEntry is a post link found on an archive page or feed, with its
+// publication date when known.
+type archiveEntry struct {
+	URL  string
+	Date time.Time
+}
+
+// ListArchive returns the URLs of the newest n posts of a publication, given
+// its archive page, home page or feed URL, newest first. The result can be
+// passed straight to FetchArticlesConcurrent.
+//
+// Substack publications are listed through their archive API, paging until
+// n posts are found. Otherwise the URL is read as an RSS/Atom feed or, for
+// HTML, scanned for post links (Substack's /p/ paths, else <article> links),
+// following rel="next" pagination; an HTML page that advertises a feed via
+// <link rel="alternate"> is listed from that feed instead. Non-Substack
+// archives are best-effort.
+func ListArchive(ctx context.Context, archiveURL string, n int) ([]string, error) {
+	return ListArchiveWithOptions(ctx, archiveURL, n, FetchOptions{})
+}
+
+// ListArchiveWithOptions is ListArchive using opts.Client and opts.Network;
+// other fields are ignored.
+func ListArchiveWithOptions(ctx context.Context, archiveURL string, n int, opts FetchOptions) ([]string, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid post count %d: must be positive", n)
+	}
+	base, err := url.Parse(strings.TrimSpace(archiveURL))
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid archive url %q", archiveURL)
+	}
+	client := opts.Client
+	if client == nil {
+		client = opts.Network.Client(15 * time.Second)
+	}
+	get := func(pageURL string) ([]byte, string, error) {
+		if err := opts.Network.CheckURL(pageURL); err != nil {
+			return nil, "", err
+		}
+		return getArchivePage(ctx, client, pageURL)
+	}
+
+	// A failure part-way through still leaves usable newest posts
+	if urls, _ := listSubstackArchive(get, base, n); len(urls) > 0 {
+		return urls, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []archiveEntry
+	seen := make(map[string]bool)
+	next := base.String()
+	for page := 0; next != "" && page < maxArchivePages && len(entries) < n; page++ {
+		body, contentType, err := get(next)
+		if err != nil {
+			if page == 0 {
+				return nil, err
+			}
+			break // keep what earlier pages gave
+		}
+		pageURL, _ := url.Parse(next)
+		var found []archiveEntry
+		if isFeed(contentType, body) {
+			found, next = parseFeed(body, pageURL), ""
+		} else {
+			doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, fmt.Errorf("parse archive page: %w", err)
+			}
+			if page == 0 {
+				if feed := feedLink(doc, pageURL); feed != "" && feed != next {
+					if fb, fct, err := get(feed); err == nil && isFeed(fct, fb) {
+						if fe := parseFeed(fb, pageURL); len(fe) > 0 {
+							found, next = fe, ""
+						}
+					}
+				}
+			}
+			if found == nil {
+				found = archiveLinks(doc, pageURL)
+				next = resolveRef(pageURL, doc.Find("link[rel='next'][href], a[rel~='next'][href]").First().AttrOr("href", ""))
+			}
+		}
+		for _, e := range found {
+			if !seen[e.URL] {
+				seen[e.URL] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no post links found at %s", archiveURL)
+	}
+
+	// Dated entries newest first; undated ones keep page order after them
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date.IsZero() || entries[j].Date.IsZero() {
+			return !entries[i].Date.IsZero() && entries[j].Date.IsZero()
+		}
+		return entries[i].Date.After(entries[j].Date)
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	urls := make([]string, len(entries))
+	for i, e := range entries {
+		urls[i] = e.URL
+	}
+	return urls, nil
+}
+
+// listSubstackArchive pages through <origin>/api/v1/archive, which every
+// Substack publication (including custom domains) serves. Returns an error
+// when the endpoint is missing or not Substack's JSON.
+func listSubstackArchive(get func(string) ([]byte, string, error), base *url.URL, n int) ([]string, error) {
+	origin := base.Scheme + "://" + base.Host
+	var urls []string
+	for offset := 0; len(urls) < n && offset < archivePageSize*maxArchivePages; offset += archivePageSize {
+		api := fmt.Sprintf("%s/api/v1/archive?sort=new&offset=%d&limit=%d", origin, offset, archivePageSize)
+		body, contentType, err := get(api)
+		if err != nil {
+			return urls, err
+		}
+		if !strings.Contains(contentType, "json") {
+			return urls, errors.New("archive api did not return json")
+		}
+		var posts []substackArchivePost
+		if err := json.Unmarshal(body, &posts); err != nil {
+			return urls, fmt.Errorf("decode archive api: %w", err)
+		}
+		for _, p := range posts {
+			u := p.CanonicalURL
+			if u == "" && p.Slug != "" {
+				u = origin + "/p/" + p.Slug
+			}
+			if u != "" && len(urls) < n {
+				urls = append(urls, u)
+			}
+		}
+		if len(posts) < archivePageSize {
+			break // last page
+		}
+	}
+	return urls, nil
+}
+
+// getArchivePage downloads one archive page, feed or API response.
+func getArchivePage(ctx context.Context, client *http.Client, pageURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("http get: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", statusError(resp)
+	}
+	limited := &io.LimitedReader{R: resp.Body, N: maxArchiveBytes + 1}
+	raw, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, "", fmt.Errorf("read body: %w", err)
+	}
+	if limited.N <= 0 {
+		return nil, "", errors.New("archive page exceeds size limit (10MB)")
+	}
+	return raw, strings.ToLower(resp.Header.Get("Content-Type")), nil
+}
+
+// isFeed reports whether a response is an RSS or Atom feed rather than HTML.
+func isFeed(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "rss") || strings.Contains(contentType, "atom") {
+		return true
+	}
+	head := bytes.ToLower(bytes.TrimSpace(body[:min(len(body), 512)]))
+	return bytes.HasPrefix(head, []byte("<?xml")) && (bytes.Contains(head, []byte("<rss")) || bytes.Contains(head, []byte("<feed"))) ||
+		bytes.HasPrefix(head, []byte("<rss")) || bytes.HasPrefix(head, []byte("<feed"))
+}
+
+// feedDoc covers both RSS 2.0 (<channel><item>) and Atom (<entry>).
+type feedDoc struct {
+	Items []struct {
+		Link    string `xml:"link"`
+		PubDate string `xml:"pubDate"`
+	} `xml:"channel>item"`
+	Entries []struct {
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// parseFeed returns the post links of an RSS or Atom feed with their dates.
+func parseFeed(body []byte, base *url.URL) []archiveEntry {
+	var f feedDoc
+	if err := xml.Unmarshal(body, &f); err != nil {
+		return nil
+	}
+	var entries []archiveEntry
+	for _, it := range f.Items {
+		if u := resolveRef(base, strings.TrimSpace(it.Link)); u != "" {
+			entries = append(entries, archiveEntry{URL: u, Date: parseFeedDate(it.PubDate)})
+		}
+	}
+	for _, e := range f.Entries {
+		href := ""
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				href = l.Href
+				break
+			}
+		}
+		date := e.Published
+		if date == "" {
+			date = e.Updated
+		}
+		if u := resolveRef(base, strings.TrimSpace(href)); u != "" {
+			entries = append(entries, archiveEntry{URL: u, Date: parseFeedDate(date)})
+		}
+	}
+	return entries
+}
+
+// parseFeedDate reads RSS (RFC 1123) and Atom (RFC 3339) dates.
+func parseFeedDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	t, _ := parseDate(s)
+	return t
+}
+
+// feedLink returns the absolute URL of the RSS/Atom feed an HTML page
+// advertises, or "".
+func feedLink(doc *goquery.Document, base *url.URL) string {
+	href := doc.Find("link[rel='alternate'][type='application/rss+xml'][href], link[rel='alternate'][type='application/atom+xml'][href]").First().AttrOr("href", "")
+	return resolveRef(base, strings.TrimSpace(href))
+}
+
+// archiveLinks collects same-site post links from an archive page: Substack
+// /p/<slug> links when present, else the first link of each <article>.
+// Dates come from a <time datetime> beside the link when there is one.
+func archiveLinks(doc *goquery.Document, base *url.URL) []archiveEntry {
+	var entries []archiveEntry
+	add := func(a *goquery.Selection, scope *goquery.Selection) {
+		u := resolveRef(base, strings.TrimSpace(a.AttrOr("href", "")))
+		pu, err := url.Parse(u)
+		if err != nil || u == "" || !strings.EqualFold(pu.Hostname(), base.Hostname()) || strings.Contains(pu.Path, "/comments") {
+			return
+		}
+		pu.Fragment, pu.RawQuery = "", ""
+		var date time.Time
+		if dt := scope.Find("time[datetime]").First().AttrOr("datetime", ""); dt != "" {
+			date, _ = parseDate(dt)
+		}
+		entries = append(entries, archiveEntry{URL: pu.String(), Date: date})
+	}
+	doc.Find("a[href*='/p/']").Each(func(_ int, a *goquery.Selection) {
+		add(a, a.Parent())
+	})
+	if len(entries) == 0 {
+		doc.Find("article").Each(func(_ int, el *goquery.Selection) {
+			if a := el.Find("a[href]").First(); a.Length() > 0 {
+				add(a, el)
+			}
+		})
+	}
+	return entries
+}
@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
 	art "pdf-maker/internal/article"
@@ -21,6 +22,9 @@ import (
 	"pdf-maker/internal/media"
 )
 
+// defaultUserAgent identifies the fetcher when no rotation is configured.
+const defaultUserAgent = "newsletter2newspaper-fetcher/0.1 (+https://example.com)"
+
 // FetchAndSaveArticle downloads the HTML for the given article URL and saves it to disk.
 // It returns the absolute path to the saved file.
 // Behavior:
@@ -37,7 +41,16 @@ func FetchArticle(ctx context.Context, pageURL string) (*art.Article, []byte, er
 
 // FetchArticleWithImages retrieves the page, parses fields, and optionally downloads images.
 func FetchArticleWithImages(ctx context.Context, pageURL string, imageDownloader *media.Downloader) (*art.Article, []byte, error) {
+    return FetchArticleWithOptions(ctx, pageURL, FetchOptions{ImageDownloader: imageDownloader})
+}
+
+// FetchArticleWithOptions retrieves the page using the per-article settings in opts
+// (image downloader, cleaning rules); batch-only fields such as MaxParallel are ignored.
+func FetchArticleWithOptions(ctx context.Context, pageURL string, opts FetchOptions) (*art.Article, []byte, error) {
     if pageURL == "" { return nil, nil, errors.New("empty url") }
+    opts, pub := opts.forPublication(pageURL)
+    reqURL := pageURL // Link stays the URL the caller asked for
+    if opts.PreferAPI { if api, ok := substackAPIURL(pageURL); ok { reqURL = api } }
 
     if _, ok := ctx.Deadline(); !ok {
         var cancel context.CancelFunc
@@ -45,42 +58,70 @@ func FetchArticleWithImages(ctx context.Context, pageURL string, imageDownloader
         defer cancel()
     }
 
-    client := &http.Client{Timeout: 15 * time.Second}
-    req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
-    if err != nil { return nil, nil, fmt.Errorf("build request: %w", err) }
-    req.Header.Set("User-Agent", "newsletter2newspaper-fetcher/0.1 (+https://example.com)")
-    req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-
-    resp, err := client.Do(req)
-    if err != nil { return nil, nil, fmt.Errorf("http get: %w", err) }
+    client := opts.Client
+    if client == nil { client = opts.Network.Client(15 * time.Second) }
+    resp, err := getPage(ctx, client, reqURL, opts)
+    if err != nil { return nil, nil, err }
+    // A post the API does not know (404), or an API path answered with a web page: scrape the page instead
+    if reqURL != pageURL && (resp.StatusCode == http.StatusNotFound || (resp.StatusCode == http.StatusOK && !isJSONContentType(resp.Header.Get("Content-Type")))) {
+        resp.Body.Close()
+        fmt.Fprintf(os.Stderr, "Warning: no Substack API post at %s (status %d); fetching the page\n", reqURL, resp.StatusCode)
+        if resp, err = getPage(ctx, client, pageURL, opts); err != nil { return nil, nil, err }
+    }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusOK { return nil, nil, fmt.Errorf("unexpected status %d", resp.StatusCode) }
+    if resp.StatusCode != http.StatusOK { return nil, nil, statusError(resp) }
 
     const maxSize = 20 * 1024 * 1024
     limited := &io.LimitedReader{R: resp.Body, N: maxSize + 1}
     raw, err := io.ReadAll(limited)
     if err != nil { return nil, nil, fmt.Errorf("read body: %w", err) }
     if limited.N <= 0 { return nil, nil, errors.New("article exceeds size limit (20MB)") }
+    // Some challenges are served with 200; never keep one as the article
+    if err := detectChallenge(resp, raw[:min(len(raw), challengeSniffSize)]); err != nil { return nil, nil, err }
+
+    // JSON bodies come from Substack's post API rather than a web page
+    if isJSONContentType(resp.Header.Get("Content-Type")) {
+        a, err := articleFromSubstackJSON(raw, pageURL)
+        if err != nil { return nil, nil, err }
+        finishArticle(ctx, a, strategyPrimary, "", a.Excerpt, opts, pageURL, pageURL)
+        applyPubSettings(a, pub, opts)
+        return a, raw, nil
+    }
 
     // Parse the document
     doc, err := goquery.NewDocumentFromReader(bytes.NewReader(raw))
     if err != nil { return nil, nil, fmt.Errorf("parse html: %w", err) }
 
     a := &art.Article{ Link: pageURL }
+    ld, _ := extractLinkedData(doc) // schema.org metadata, used where the Substack selectors find nothing
 
     // Title & Subtitle
     a.Title = strings.TrimSpace(doc.Find("h1.post-title.published").First().Text())
+    if a.Title == "" { a.Title = ld.Headline }
     a.Subtitle = strings.TrimSpace(doc.Find("h3.subtitle").First().Text())
+    if a.Subtitle == "" { // fall back to the page's meta description as the dek
+        if d := extractMetaDescription(doc); d != "" && !strings.EqualFold(d, a.Title) { a.Subtitle = truncateWords(d, maxDekLen) }
+    }
+    a.Series, a.SeriesPart = extractSeries(doc, a.Title, a.Subtitle)
+    a.Comments = extractComments(doc)
     // Author & Publication via helpers (with fallbacks)
-    a.Author = extractAuthor(doc)
-    a.Publication = extractPublication(doc, pageURL)
-    // PubDate extraction strategies (priority order): meta tag, time tag, byline text pattern
+    a.Authors = extractAuthors(doc, ld.Authors)
+    a.Author = art.JoinAuthors(a.Authors)
+    a.AuthorAvatar = extractAuthorAvatar(doc, pageURL)
+    a.CoverImage = extractCoverImage(doc, pageURL, ld.Image)
+    a.Publication = extractPublication(doc, pageURL, ld.Publisher)
+    a.CanonicalURL = extractCanonicalURL(doc, pageURL)
+    a.StylesheetURLs, a.PageCSS = extractStyles(doc, pageURL)
+    // PubDate extraction strategies (priority order): meta tag, JSON-LD, time tag (datetime, then text), byline text pattern
     if ts := doc.Find("meta[property='article:published_time']").AttrOr("content", ""); ts != "" {
         if t, e := time.Parse(time.RFC3339, ts); e == nil { a.PubDate = t }
     }
+    if a.PubDate.IsZero() { a.PubDate = ld.DatePublished }
     if a.PubDate.IsZero() {
         if tEl := doc.Find("time").First(); tEl.Length() > 0 {
-            if dt, ok := tEl.Attr("datetime"); ok { if t, e := time.Parse(time.RFC3339, dt); e == nil { a.PubDate = t } }
+            if dt, ok := tEl.Attr("datetime"); ok { if t, ok := parseDate(dt); ok { a.PubDate = t } }
+            // Fall back to the element's text ("October 9, 2025") when datetime is absent or unparseable
+            if a.PubDate.IsZero() { if t, ok := parseDate(tEl.Text()); ok { a.PubDate = t } }
         }
     }
     if a.PubDate.IsZero() { // pattern search inside byline wrapper for formats like "Oct 09, 2025"
@@ -88,42 +129,138 @@ func FetchArticleWithImages(ctx context.Context, pageURL string, imageDownloader
             if t, e := time.Parse("Jan 02, 2006", dateStr); e == nil { a.PubDate = t }
         }
     }
+    a.UpdatedDate = extractUpdatedDate(doc) // PubDate stays the original publish date
+    if a.UpdatedDate.IsZero() { a.UpdatedDate = ld.DateModified }
+    ruleset := resolveRuleset(doc, opts.Extractor)
+    if ruleset == RulesetGhost { applyGhostMetadata(doc, a) }
+    if a.Title == "" { a.Title = strings.TrimSpace(doc.Find("meta[property='og:title']").AttrOr("content", "")) } // other platforms
+    if a.Title == "" { a.Title = strings.Join(strings.Fields(doc.Find("h1").First().Text()), " ") }
 
-    // Content extraction
-    if sel := doc.Find("div.available-content").First(); sel.Length() > 0 {
-        if inner, e := sel.Html(); e == nil { a.Content = inner }
-    }
-    if a.Content == "" { // fallback
-        if sel := doc.Find("div#entry").First(); sel.Length() > 0 { if inner, e := sel.Html(); e == nil { a.Content = inner } }
-    }
-    if a.Content == "" { a.Content = string(raw) } // ultimate fallback
+    // Content extraction (see the Ruleset constants)
+    content, strategy, err := extractContent(doc, raw, ruleset)
+    if err != nil { return nil, nil, err }
+    a.Content = content
+
+    finishArticle(ctx, a, strategy, pageLanguage(doc), extractMetaDescription(doc), opts, pageURL, documentBaseURL(doc, resp.Request.URL))
+    applyPubSettings(a, pub, opts)
+    return a, raw, nil
+}
+
+// getPage sends the GET request for reqURL through client, subject to the
+// network policy and the page redirect policy.
+func getPage(ctx context.Context, client *http.Client, reqURL string, opts FetchOptions) (*http.Response, error) {
+    if err := opts.Network.CheckURL(reqURL); err != nil { return nil, err }
+    redirects := &redirectTracker{}
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+    if err != nil { return nil, fmt.Errorf("build request: %w", err) }
+    req.Header.Set("User-Agent", opts.UserAgents.Pick(req.URL.Hostname(), defaultUserAgent))
+    req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 
+    resp, err := redirects.client(client).Do(req)
+    var redirectErr *RedirectError
+    if errors.As(err, &redirectErr) { return nil, redirectErr } // clearer than the *url.Error around it
+    if err != nil { return nil, fmt.Errorf("http get: %w", err) }
+    if opts.Verbose && len(redirects.chain) > 0 { fmt.Fprintf(os.Stderr, "Followed redirects: %s\n", redirects) }
+    return resp, nil
+}
+
+// applyPubSettings records the publication settings that outlive the fetch
+// on a: its publication ID and, when its images are removed, the render-time
+// RemoveImages flag (the avatar and cover image go too; the avatar was never
+// downloaded).
+func applyPubSettings(a *art.Article, pub art.PubSettings, opts FetchOptions) {
+    a.PublicationID = opts.PublicationID
+    if pub.RemoveImages { a.RemoveImages = true; a.AuthorAvatar = ""; a.CoverImage = "" }
+}
+
+// finishArticle runs the steps shared by every extraction path on a's raw
+// content: encoding repair (repairUTF8), cleaning, language detection (when
+// the page declared none), confidence scoring, the excerpt (description,
+// else the opening of the body) and image downloading, with relative image
+// URLs resolved against baseURL.
+func finishArticle(ctx context.Context, a *art.Article, strategy extractionStrategy, lang, description string, opts FetchOptions, pageURL, baseURL string) {
+    imageDownloader := opts.ImageDownloader
+    // Bytes from a mis-detected encoding would break JSON export and rendering
+    fields := []*string{&a.Content, &a.Title, &a.Subtitle, &a.Author, &a.Publication, &a.Series, &description}
+    for i := range a.Authors { fields = append(fields, &a.Authors[i]) }
+    for i := range a.Comments { fields = append(fields, &a.Comments[i].Author, &a.Comments[i].Text) }
+    if repairArticleText(fields...) {
+        fmt.Fprintf(os.Stderr, "Warning: repaired invalid UTF-8 in %s (read as Windows-1252)\n", pageURL)
+    }
     // Clean HTML content (remove subscription widgets, forms, format footnotes)
-    cleaned, _, err := clean.CleanHTML(a.Content, false)
+    cleaned, stats, err := clean.CleanHTMLWithOptions(a.Content, false, opts.Clean)
     if err == nil {
         a.Content = cleaned
+        if opts.OnCleaned != nil { opts.OnCleaned(pageURL, stats) }
+        if stats.CustomRemoved > 0 {
+            fmt.Fprintf(os.Stderr, "Removed %d element(s) matching custom strip selectors from %s\n", stats.CustomRemoved, pageURL)
+        }
     }
     // If cleaning fails, we keep the uncleaned content rather than failing the whole fetch
 
+    // Language: page metadata first, then a lightweight detector on the body text
+    bodyText := ""
+    if cdoc, e := goquery.NewDocumentFromReader(strings.NewReader(a.Content)); e == nil { bodyText = cdoc.Text() }
+    a.Language = lang
+    if a.Language == "" { a.Language = detectLanguage(bodyText) }
+    a.ExtractionConfidence = extractionConfidence(strategy, a.Content, bodyText)
+    a.Excerpt = description
+    a.ExcerptFromBody = a.Excerpt == ""
+    if a.ExcerptFromBody { a.Excerpt = bodyText }
+    a.Excerpt = truncateAtSentence(a.Excerpt, excerptWords)
+
     // Download images and rewrite URLs if downloader is provided
     if imageDownloader != nil {
-        processedContent, err := imageDownloader.ProcessHTML(a.Content)
+        processedContent, err := imageDownloader.ProcessArticleHTML(ctx, a.Content, opts.ArticleIndex, baseURL)
         if err == nil {
             a.Content = processedContent
         } else {
             fmt.Fprintf(os.Stderr, "Warning: failed to process images for %s: %v\n", pageURL, err)
         }
+        if a.AuthorAvatar != "" {
+            if local, err := imageDownloader.DownloadImage(ctx, a.AuthorAvatar); err == nil {
+                a.AuthorAvatar = local
+            } else {
+                // A remote URL cannot be rendered by Typst; drop the avatar instead
+                a.AuthorAvatar = ""
+            }
+        }
+        if opts.CoverImages && a.CoverImage != "" {
+            if local, err := imageDownloader.DownloadImage(ctx, a.CoverImage); err == nil {
+                a.CoverImage = local
+            } else {
+                // Dropped like the avatar, so a default cover can stand in
+                fmt.Fprintf(os.Stderr, "Warning: failed to download cover image for %s: %v\n", pageURL, err)
+                a.CoverImage = ""
+            }
+        }
     }
+}
 
-    return a, raw, nil
+// documentBaseURL returns the URL the page's relative references resolve
+// against: its <base href> when present, else the address it was finally
+// served from (after redirects, which may change host or path).
+func documentBaseURL(doc *goquery.Document, served *url.URL) string {
+    href := strings.TrimSpace(doc.Find("base[href]").First().AttrOr("href", ""))
+    if href == "" { return served.String() }
+    ref, err := url.Parse(href)
+    if err != nil { return served.String() }
+    return served.ResolveReference(ref).String()
 }
 
+// StatusError reports a non-200 HTTP response for an article page.
+type StatusError struct {
+    Code int
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("unexpected status %d", e.Code) }
+
 // FetchAndSaveArticle keeps backward compatibility: fetches article, saves content HTML, returns path.
 func FetchAndSaveArticle(ctx context.Context, pageURL, outDir string) (string, error) {
     artc, _, err := FetchArticle(ctx, pageURL)
     if err != nil { return "", err }
     if outDir == "" { outDir = "." }
-    filename := deriveFilename(pageURL)
+    filename := DeriveFilename(pageURL)
     if err := os.MkdirAll(outDir, 0o755); err != nil { return "", fmt.Errorf("mkdir %s: %w", outDir, err) }
     absDir, err := filepath.Abs(outDir); if err != nil { return "", fmt.Errorf("abs dir: %w", err) }
     outPath := filepath.Join(absDir, filename)
@@ -140,7 +277,7 @@ func findDateInByline(doc *goquery.Document) string {
     var found string
     doc.Find("div.byline-wrapper").First().Find("div,span").EachWithBreak(func(_ int, s *goquery.Selection) bool {
         text := strings.TrimSpace(s.Text())
-        if text == "" { return true }
+        if text == "" || updatedPattern.MatchString(text) { return true } // a revision date, not the publish date
         if datePattern.MatchString(text) {
             found = datePattern.FindString(text)
             return false
@@ -169,25 +306,159 @@ func normalizePublication(s string) string {
     return s
 }
 
-// extractAuthor attempts multiple selectors / metadata sources to retrieve the author name.
-func extractAuthor(doc *goquery.Document) string {
-    // Primary: byline wrapper anchor
-    if v := strings.TrimSpace(doc.Find("div.byline-wrapper a.pencraft").First().Text()); v != "" {
-        return normalizeName(v)
+// extractAuthors attempts multiple selectors / metadata sources to retrieve the author names.
+// Every byline anchor is collected so co-authored posts list all authors; names are
+// normalized and de-duplicated while preserving byline order. ldAuthors are the
+// page's JSON-LD authors, preferred to the meta author tag.
+func extractAuthors(doc *goquery.Document, ldAuthors []string) []string {
+    // Primary: byline wrapper anchors
+    if names := collectNames(doc.Find("div.byline-wrapper a.pencraft")); len(names) > 0 {
+        return names
     }
-    // Fallback: any anchor with profile hover class
-    if v := strings.TrimSpace(doc.Find(".profile-hover-card-target a").First().Text()); v != "" {
-        return normalizeName(v)
+    // Fallback: anchors with profile hover class
+    if names := collectNames(doc.Find(".profile-hover-card-target a")); len(names) > 0 {
+        return names
     }
+    // Structured data
+    if len(ldAuthors) > 0 { return ldAuthors }
     // Meta author
     if v := strings.TrimSpace(doc.Find("meta[name='author']").AttrOr("content", "")); v != "" {
-        return normalizeName(v)
+        return []string{normalizeName(v)}
+    }
+    return nil
+}
+
+// extractAuthorAvatar returns the absolute URL of the first author avatar in the byline.
+func extractAuthorAvatar(doc *goquery.Document, pageURL string) string {
+    src := ""
+    for _, sel := range []string{"div.byline-wrapper img[src]", ".profile-hover-card-target img[src]"} {
+        if v := strings.TrimSpace(doc.Find(sel).First().AttrOr("src", "")); v != "" { src = v; break }
+    }
+    if src == "" || strings.HasPrefix(src, "data:") { return "" }
+    base, err := url.Parse(pageURL)
+    if err != nil { return src }
+    ref, err := url.Parse(src)
+    if err != nil { return "" }
+    return base.ResolveReference(ref).String()
+}
+
+// extractCoverImage returns the absolute URL of the page's lead picture: og:image, else twitter:image,
+// else ldImage (the JSON-LD image).
+func extractCoverImage(doc *goquery.Document, pageURL, ldImage string) string {
+    src := ""
+    for _, sel := range []string{"meta[property='og:image']", "meta[name='twitter:image']", "meta[property='twitter:image']"} {
+        if v := strings.TrimSpace(doc.Find(sel).First().AttrOr("content", "")); v != "" { src = v; break }
+    }
+    if src == "" { src = ldImage }
+    if src == "" || strings.HasPrefix(src, "data:") { return "" }
+    base, err := url.Parse(pageURL)
+    if err != nil { return src }
+    ref, err := url.Parse(src)
+    if err != nil { return "" }
+    return base.ResolveReference(ref).String()
+}
+
+// collectNames returns the normalized, de-duplicated text of each selected element,
+// skipping blanks and date strings that share the byline markup.
+func collectNames(sel *goquery.Selection) []string {
+    var names []string
+    seen := make(map[string]bool)
+    sel.Each(func(_ int, s *goquery.Selection) {
+        v := strings.TrimSpace(s.Text())
+        if v == "" || datePattern.MatchString(v) { return }
+        name := normalizeName(v)
+        key := strings.ToLower(name)
+        if seen[key] { return }
+        seen[key] = true
+        names = append(names, name)
+    })
+    return names
+}
+
+// extractCanonicalURL returns the page's canonical URL from <link rel="canonical">
+// or og:url, resolved against pageURL. Empty if the page declares neither.
+func extractCanonicalURL(doc *goquery.Document, pageURL string) string {
+    v := strings.TrimSpace(doc.Find("link[rel='canonical']").AttrOr("href", ""))
+    if v == "" { v = strings.TrimSpace(doc.Find("meta[property='og:url']").AttrOr("content", "")) }
+    if v == "" { return "" }
+    base, err := url.Parse(pageURL)
+    if err != nil { return v }
+    ref, err := url.Parse(v)
+    if err != nil { return v }
+    return base.ResolveReference(ref).String()
+}
+
+// maxDekLen caps a dek derived from a meta description, in bytes.
+const maxDekLen = 200
+
+// extractMetaDescription returns the page summary from og:description,
+// meta description or twitter:description, with whitespace collapsed.
+func extractMetaDescription(doc *goquery.Document) string {
+    for _, sel := range []string{"meta[property='og:description']", "meta[name='description']", "meta[name='twitter:description']"} {
+        if v := strings.Join(strings.Fields(doc.Find(sel).First().AttrOr("content", "")), " "); v != "" { return v }
     }
     return ""
 }
 
+// excerptWords is the target length of an excerpt, in words.
+const excerptWords = 30
+
+// sentenceSlack is how many words past the limit truncateAtSentence will
+// read to finish the current sentence.
+const sentenceSlack = 15
+
+// abbreviations end in a period without ending a sentence.
+var abbreviations = map[string]bool{
+    "mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true, "st.": true, "vs.": true,
+    "e.g.": true, "i.e.": true, "etc.": true, "inc.": true, "jr.": true, "sr.": true, "no.": true,
+}
+
+// endsSentence reports whether word closes a sentence: it ends in . ! ? or …
+// (possibly inside closing quotes or brackets) and is not an abbreviation
+// or a single-letter initial.
+func endsSentence(word string) bool {
+    w := strings.TrimRight(word, "\"'”’)]")
+    if w == "" || !strings.ContainsAny(w[len(w)-1:], ".!?") && !strings.HasSuffix(w, "…") { return false }
+    if strings.HasSuffix(w, ".") {
+        lw := strings.ToLower(w)
+        if abbreviations[lw] { return false }
+        if r, size := utf8.DecodeRuneInString(w); size+1 == len(w) && r >= 'A' && r <= 'Z' { return false } // "J."
+    }
+    return true
+}
+
+// truncateAtSentence shortens text to about maxWords words without cutting
+// a sentence in half: it reads on to the end of the current sentence when
+// that is at most sentenceSlack words away, else backs up to the last
+// sentence ending within the limit, and only cuts mid-sentence when the
+// first sentence alone is too long. An ellipsis marks anything dropped.
+func truncateAtSentence(text string, maxWords int) string {
+    words := strings.Fields(text)
+    if len(words) <= maxWords { return strings.Join(words, " ") }
+    for i := maxWords - 1; i < len(words) && i < maxWords+sentenceSlack; i++ {
+        if endsSentence(words[i]) {
+            if i == len(words)-1 { return strings.Join(words, " ") }
+            return strings.Join(words[:i+1], " ") + " …"
+        }
+    }
+    for i := maxWords - 2; i >= maxWords/2; i-- {
+        if endsSentence(words[i]) { return strings.Join(words[:i+1], " ") + " …" }
+    }
+    return strings.TrimRight(strings.Join(words[:maxWords], " "), ",;:.-") + "…"
+}
+
+// truncateWords shortens s to at most max bytes, cutting at a word boundary
+// and appending an ellipsis when anything was dropped.
+func truncateWords(s string, max int) string {
+    if len(s) <= max { return s }
+    cut := strings.LastIndex(s[:max], " ")
+    if cut <= 0 { cut = max; for cut > 0 && !utf8.RuneStart(s[cut]) { cut-- } }
+    return strings.TrimRight(s[:cut], " ,;:.-") + "…"
+}
+
 // extractPublication pulls publication name from several potential locations.
-func extractPublication(doc *goquery.Document, pageURL string) string {
+// ldPublisher is the page's JSON-LD publisher name, preferred to the meta tags.
+func extractPublication(doc *goquery.Document, pageURL, ldPublisher string) string {
     // Text inside explicit newsletter title link
     if v := strings.TrimSpace(doc.Find("h1.title-oOnUGd a").First().Text()); v != "" {
         return normalizePublication(v)
@@ -200,6 +471,8 @@ func extractPublication(doc *goquery.Document, pageURL string) string {
     if v, ok := doc.Find("h1.title-oOnUGd img[alt]").First().Attr("alt"); ok && strings.TrimSpace(v) != "" {
         return normalizePublication(v)
     }
+    // Structured data
+    if ldPublisher != "" { return normalizePublication(ldPublisher) }
     // OpenGraph site name
     if v := strings.TrimSpace(doc.Find("meta[property='og:site_name']").AttrOr("content", "")); v != "" {
         return normalizePublication(v)
@@ -219,20 +492,30 @@ func extractPublication(doc *goquery.Document, pageURL string) string {
     return ""
 }
 
-func deriveFilename(rawURL string) string {
+// DeriveFilename returns a deterministic, filesystem-safe .html filename for
+// an article URL: a readable slug from the last path segment followed by a
+// short hash of the full URL, e.g. "my-post-3f2a9c1e.html". The hash keeps
+// articles whose URLs share a last segment (two "index" pages) from
+// overwriting each other in the same directory, while the same URL always
+// maps to the same file.
+func DeriveFilename(rawURL string) string {
+	const maxSlug = 80
 	// Extract path after last '/'
 	parts := strings.Split(trailingSlash.ReplaceAllString(rawURL, ""), "/")
 	last := parts[len(parts)-1]
 	last = strings.Split(last, "?")[0]
 	last = strings.Split(last, "#")[0]
+	if strings.HasSuffix(strings.ToLower(last), ".html") {
+		last = last[:len(last)-len(".html")]
+	}
 	last = unsafeChars.ReplaceAllString(last, "-")
 	last = strings.Trim(last, "-._")
-	if last == "" {
-		// Fallback to hash
-		return fmt.Sprintf("article-%x.html", sha1.Sum([]byte(rawURL)))
+	if len(last) > maxSlug {
+		last = strings.Trim(last[:maxSlug], "-._")
 	}
-	if !strings.HasSuffix(strings.ToLower(last), ".html") {
-		last += ".html"
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(rawURL)))[:8]
+	if last == "" {
+		return fmt.Sprintf("article-%s.html", hash)
 	}
-	return last
+	return fmt.Sprintf("%s-%s.html", last, hash)
 }
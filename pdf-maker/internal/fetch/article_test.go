@@ -0,0 +1,114 @@
+package fetch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchSubstackFixture(t *testing.T) {
+	a := fetchFixture(t, "substack.html")
+	if a.Title != "Rates, Explained, Part 2: The Long End" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	if a.Subtitle != "Why long-term rates don't always follow the central bank" {
+		t.Errorf("Subtitle = %q", a.Subtitle)
+	}
+	if want := []string{"Jane Example", "Sam Sample"}; !reflect.DeepEqual(a.Authors, want) {
+		t.Errorf("Authors = %q, want %q", a.Authors, want)
+	}
+	if a.Author != "Jane Example and Sam Sample" {
+		t.Errorf("Author = %q", a.Author)
+	}
+	if a.Publication != "Example Economics" {
+		t.Errorf("Publication = %q", a.Publication)
+	}
+	if want := time.Date(2025, 10, 9, 12, 0, 0, 0, time.UTC); !a.PubDate.Equal(want) {
+		t.Errorf("PubDate = %v, want %v", a.PubDate, want)
+	}
+	if a.CanonicalURL != "https://example.substack.com/p/rates-explained-part-2" {
+		t.Errorf("CanonicalURL = %q", a.CanonicalURL)
+	}
+	if a.Series != "Rates, Explained" || a.SeriesPart != 2 {
+		t.Errorf("Series = %q part %d, want Rates, Explained part 2", a.Series, a.SeriesPart)
+	}
+	if a.Language != "en" {
+		t.Errorf("Language = %q", a.Language)
+	}
+	if a.Excerpt != "Why long-term rates don't always follow the central bank." || a.ExcerptFromBody {
+		t.Errorf("Excerpt = %q (from body: %t), want the meta description", a.Excerpt, a.ExcerptFromBody)
+	}
+	assertContains(t, "content", a.Content,
+		"short-term yields usually follow",
+		"The term premium</h2>",
+		"Markets set long rates",
+		"curve.png",
+		"Estimates of the term premium vary widely")
+	assertLacks(t, "content", a.Content, "subscription-widget", "Type your email", "© 2025 Example Economics", "post-title")
+}
+
+func TestFetchMediumFixture(t *testing.T) {
+	a := fetchFixture(t, "medium.html")
+	if a.Title != "Shipping Small Changes Often" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	if a.Subtitle != "A year of deploying twenty times a day, and what it changed about code review." {
+		t.Errorf("Subtitle = %q, want the meta description as dek", a.Subtitle)
+	}
+	if a.Author != "Alex Sample" {
+		t.Errorf("Author = %q", a.Author)
+	}
+	if a.Publication != "Example Engineering" {
+		t.Errorf("Publication = %q", a.Publication)
+	}
+	if want := time.Date(2025, 3, 14, 8, 30, 0, 0, time.UTC); !a.PubDate.Equal(want) {
+		t.Errorf("PubDate = %v, want %v", a.PubDate, want)
+	}
+	assertContains(t, "content", a.Content,
+		"deployed once a week",
+		"small diffs get read",
+		"deploys.png",
+		"Deploys per day over twelve months.",
+		"A single required reviewer",
+		"deploy --canary 5%")
+	assertLacks(t, "content", a.Content, "Sign up for our newsletter", "Follow</button>")
+}
+
+func TestSubtitleFallsBackToDescription(t *testing.T) {
+	long := strings.Repeat("A very long summary of the post that goes on. ", 8)
+	tests := []struct {
+		name, head, subtitle, want string
+	}{
+		{"og:description", `<meta property="og:description" content="  The  dek from  og. ">`, "", "The dek from og."},
+		{"meta description", `<meta name="description" content="The dek from meta.">`, "", "The dek from meta."},
+		{"og:description wins", `<meta name="description" content="Meta."><meta property="og:description" content="OG.">`, "", "OG."},
+		{"subtitle wins", `<meta property="og:description" content="OG.">`, "The real subtitle", "The real subtitle"},
+		{"description repeating the title", `<meta property="og:description" content="the post title">`, "", ""},
+		{"no description", ``, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := ""
+			if tt.subtitle != "" {
+				sub = `<h3 class="subtitle">` + tt.subtitle + `</h3>`
+			}
+			a := fetchHTML(t, `<html><head>`+tt.head+`</head><body><h1 class="post-title published">The Post Title</h1>`+sub+
+				`<div class="available-content"><p>Body text.</p></div></body></html>`)
+			if a.Subtitle != tt.want {
+				t.Errorf("Subtitle = %q, want %q", a.Subtitle, tt.want)
+			}
+		})
+	}
+
+	a := fetchHTML(t, `<html><head><meta property="og:description" content="`+long+`"></head><body><h1 class="post-title published">T</h1>`+
+		`<div class="available-content"><p>Body.</p></div></body></html>`)
+	if len(a.Subtitle) > maxDekLen+len("…") || !strings.HasSuffix(a.Subtitle, "…") {
+		t.Errorf("long description not truncated to a dek: %q", a.Subtitle)
+	}
+
+	// The Ghost fixture has no h3.subtitle, only og:description
+	if a := fetchFixture(t, "ghost.html"); a.Subtitle != "Three weeks by train from Lisbon to Tallinn." {
+		t.Errorf("ghost.html Subtitle = %q", a.Subtitle)
+	}
+}
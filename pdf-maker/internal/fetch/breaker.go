@@ -0,0 +1,86 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrCircuitOpen marks a URL that was not attempted because its host had
+// already failed too many times in a row during the batch. Such URLs are
+// worth retrying in a later run.
+var ErrCircuitOpen = errors.New("host circuit open")
+
+// CircuitOpenError reports a URL skipped by the per-host circuit breaker.
+type CircuitOpenError struct {
+	Host     string
+	Failures int // consecutive failures that tripped the breaker
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("skipped: %s failed %d times in a row", e.Host, e.Failures)
+}
+
+// Is lets errors.Is(err, ErrCircuitOpen) match.
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+// hostBreaker counts consecutive transient failures per host and trips once
+// a host reaches limit. A success resets the host's count. Safe for
+// concurrent use; a nil *hostBreaker never trips.
+type hostBreaker struct {
+	limit int
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// newHostBreaker returns a breaker tripping after limit consecutive
+// failures, or nil when limit <= 0 (disabled).
+func newHostBreaker(limit int) *hostBreaker {
+	if limit <= 0 {
+		return nil
+	}
+	return &hostBreaker{limit: limit, failures: make(map[string]int)}
+}
+
+// check returns a *CircuitOpenError when pageURL's host has tripped.
+func (b *hostBreaker) check(pageURL string) error {
+	if b == nil {
+		return nil
+	}
+	host := breakerHost(pageURL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n := b.failures[host]; n >= b.limit {
+		return &CircuitOpenError{Host: host, Failures: n}
+	}
+	return nil
+}
+
+// record updates pageURL's host after a fetch. Only transient failures
+// (isRetryable) count: a 404 says nothing about whether the host is up.
+func (b *hostBreaker) record(pageURL string, err error) {
+	if b == nil {
+		return
+	}
+	host := breakerHost(pageURL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err == nil:
+		delete(b.failures, host)
+	case isRetryable(err):
+		b.failures[host]++
+	}
+}
+
+// breakerHost is the lower-cased host of pageURL (the URL itself if it
+// does not parse).
+func breakerHost(pageURL string) string {
+	if u, err := url.Parse(pageURL); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return pageURL
+}
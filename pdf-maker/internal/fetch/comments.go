@@ -0,0 +1,78 @@
+package fetch
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+)
+
+// maxComments caps how many top-level comments an article keeps.
+const maxComments = 50
+
+var (
+	// commentSelector matches a comment container in Substack's markup
+	// (server-rendered comment pages and the post page's comment preview).
+	commentSelector = "div.comment, div.comment-list-item, article.comment"
+	// commentAuthorSelectors locate the commenter's name inside a comment.
+	commentAuthorSelectors = []string{".commenter-name", ".comment-meta a", "a.comment-author", ".comment-author"}
+	// commentBodySelectors locate the comment text inside a comment.
+	commentBodySelectors = []string{".comment-body", ".comment-content", ".comment-text"}
+)
+
+// extractComments returns the top-level comments present in the page DOM.
+// Replies (comments nested inside another comment) are skipped. Substack
+// usually loads comments with JavaScript, in which case nothing is found and
+// nil is returned.
+func extractComments(doc *goquery.Document) []art.Comment {
+	var comments []art.Comment
+	doc.Find(commentSelector).EachWithBreak(func(_ int, c *goquery.Selection) bool {
+		if c.ParentsFiltered(commentSelector).Length() > 0 {
+			return true // a reply
+		}
+		body := firstMatch(c, commentBodySelectors)
+		if body == nil {
+			return true
+		}
+		// Exclude nested replies that live inside the body wrapper
+		body = body.Clone()
+		body.Find(commentSelector).Remove()
+		text := commentText(body)
+		if text == "" {
+			return true
+		}
+		author := ""
+		if a := firstMatch(c, commentAuthorSelectors); a != nil {
+			author = strings.Join(strings.Fields(a.Text()), " ")
+		}
+		comments = append(comments, art.Comment{Author: author, Text: text})
+		return len(comments) < maxComments
+	})
+	return comments
+}
+
+// firstMatch returns the first element under s matching any of selectors,
+// tried in order, or nil.
+func firstMatch(s *goquery.Selection, selectors []string) *goquery.Selection {
+	for _, sel := range selectors {
+		if m := s.Find(sel).First(); m.Length() > 0 {
+			return m
+		}
+	}
+	return nil
+}
+
+// commentText flattens a comment body to plain text, one paragraph per <p>
+// (or the whole body when it has none), separated by blank lines.
+func commentText(body *goquery.Selection) string {
+	var paras []string
+	body.Find("p").Each(func(_ int, p *goquery.Selection) {
+		if t := strings.Join(strings.Fields(p.Text()), " "); t != "" {
+			paras = append(paras, t)
+		}
+	})
+	if len(paras) == 0 {
+		return strings.Join(strings.Fields(body.Text()), " ")
+	}
+	return strings.Join(paras, "\n\n")
+}
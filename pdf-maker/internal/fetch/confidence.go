@@ -0,0 +1,51 @@
+package fetch
+
+import (
+	"math"
+	"strings"
+)
+
+// extractionStrategy identifies which content selector produced Article.Content.
+type extractionStrategy int
+
+const (
+	strategyPrimary  extractionStrategy = iota // div.available-content (Substack post body)
+	strategyFallback                           // div#entry
+	strategyRawPage                            // whole page HTML
+	strategyDensity                            // densest text container (extractDensest)
+)
+
+// strategyConfidence is the starting confidence for each strategy, before the
+// text-density adjustment.
+var strategyConfidence = map[extractionStrategy]float64{
+	strategyPrimary:  0.95,
+	strategyFallback: 0.6,
+	strategyRawPage:  0.05,
+	strategyDensity:  0.6,
+}
+
+// LowConfidenceThreshold is the ExtractionConfidence below which an article
+// should be reviewed by hand.
+const LowConfidenceThreshold = 0.5
+
+// extractionConfidence scores how likely content is the real article body.
+// The strategy sets the baseline; it is then scaled by text density (visible
+// text per byte of markup, low for navigation and widget boilerplate) and by
+// length, since a few dozen words is rarely a whole article.
+func extractionConfidence(strategy extractionStrategy, contentHTML, text string) float64 {
+	const (
+		goodDensity = 0.25 // typical prose articles are well above this
+		minWords    = 150
+	)
+	base := strategyConfidence[strategy]
+	if len(contentHTML) == 0 {
+		return 0
+	}
+
+	density := float64(len(strings.TrimSpace(text))) / float64(len(contentHTML))
+	densityScore := math.Min(1, density/goodDensity)
+	lengthScore := math.Min(1, float64(len(strings.Fields(text)))/minWords)
+
+	score := base * (0.6 + 0.4*densityScore) * (0.5 + 0.5*lengthScore)
+	return math.Round(score*100) / 100
+}
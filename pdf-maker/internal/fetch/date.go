@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// dateLayouts are the formats parseDate accepts, machine-readable first.
+// Numeric day/month orders such as 01/02/2006 are deliberately absent: they
+// are ambiguous between US and European pages.
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"Monday, January 2, 2006",
+	"Mon, January 2, 2006",
+	"Monday, Jan 2, 2006",
+	"Mon, Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"Monday 2 January 2006",
+	"January 2006",
+}
+
+var (
+	ordinalSuffix = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)\b`)
+	abbrevPeriod  = regexp.MustCompile(`\b([A-Z][a-z]{2,3})\.`)
+)
+
+// parseDate parses a publication date written either machine-readably
+// (RFC 3339, ISO 8601 dates) or as human text such as "October 9, 2025",
+// "Oct. 9th, 2025" or "9 October 2025". Surrounding whitespace, ordinal
+// suffixes and abbreviation periods are ignored; "Sept" is read as "Sep".
+func parseDate(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return time.Time{}, false
+	}
+	s = ordinalSuffix.ReplaceAllString(s, "$1")
+	s = abbrevPeriod.ReplaceAllString(s, "$1")
+	s = strings.Replace(s, "Sept ", "Sep ", 1)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// updatedPattern finds a revision note such as "Updated Oct 10, 2025",
+// "Last updated: October 10th, 2025" or "Updated on 10 October 2025".
+var updatedPattern = regexp.MustCompile(`(?i)(?:last\s+)?updated(?:\s+on)?:?\s+([A-Z][a-z]{2,8}\.? \d{1,2}(?:st|nd|rd|th)?,? \d{4}|\d{1,2} [A-Z][a-z]{2,8}\.? \d{4}|\d{4}-\d{2}-\d{2})`)
+
+// updatedScopes are where a post's revision note is looked for: its byline
+// and header, not the body, whose prose may mention unrelated updates.
+const updatedScopes = "div.byline-wrapper, .post-meta, .post-header, .byline, article header"
+
+// extractUpdatedDate returns when the post was last revised: the
+// article:modified_time meta tag, else an "Updated <date>" note in the
+// byline or header. Zero when the page gives neither.
+func extractUpdatedDate(doc *goquery.Document) time.Time {
+	if ts := doc.Find("meta[property='article:modified_time']").AttrOr("content", ""); ts != "" {
+		if t, ok := parseDate(ts); ok {
+			return t
+		}
+	}
+	var updated time.Time
+	doc.Find(updatedScopes).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		m := updatedPattern.FindStringSubmatch(strings.Join(strings.Fields(s.Text()), " "))
+		if m == nil {
+			return true
+		}
+		if t, ok := parseDate(m[1]); ok {
+			updated = t
+			return false
+		}
+		return true
+	})
+	return updated
+}
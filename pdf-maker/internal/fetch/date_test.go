@@ -0,0 +1,37 @@
+package fetch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2025-10-09", time.Date(2025, 10, 9, 0, 0, 0, 0, time.UTC), true},
+		{"2025-10-09T12:30:00Z", time.Date(2025, 10, 9, 12, 30, 0, 0, time.UTC), true},
+		{"October 9, 2025", time.Date(2025, 10, 9, 0, 0, 0, 0, time.UTC), true},
+		{"  Oct.  9th,\n 2025 ", time.Date(2025, 10, 9, 0, 0, 0, 0, time.UTC), true},
+		{"9 October 2025", time.Date(2025, 10, 9, 0, 0, 0, 0, time.UTC), true},
+		{"Thursday, Sept. 18th, 2025", time.Date(2025, 9, 18, 0, 0, 0, 0, time.UTC), true},
+		{"01/02/2025", time.Time{}, false}, // ambiguous day/month order
+		{"last Thursday", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDate(tt.in)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, %t; want %v, %t", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFetchTimeTextFixture(t *testing.T) {
+	a := fetchFixture(t, "time-text.html")
+	if want := time.Date(2025, 9, 18, 0, 0, 0, 0, time.UTC); !a.PubDate.Equal(want) {
+		t.Errorf("PubDate = %v, want %v from the <time> text", a.PubDate, want)
+	}
+}
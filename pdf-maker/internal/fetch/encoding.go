@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Pages are parsed as UTF-8. One served in Latin-1 or Windows-1252 without
+// saying so leaves its accented letters and curly quotes as bytes that are
+// not valid UTF-8; they break the JSON export and print as replacement
+// characters. Such bytes are re-read as Windows-1252, which is what they
+// almost always are, so "café" survives instead of becoming "caf�".
+
+// cp1252High maps the bytes 0x80-0x9F to the characters Windows-1252 gives
+// them; 0 marks the five bytes it leaves undefined. Latin-1 and
+// Windows-1252 agree on 0xA0-0xFF, which map to U+00A0-U+00FF.
+var cp1252High = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// repairUTF8 returns s with every byte that is not part of a valid UTF-8
+// sequence replaced by its Windows-1252 character (U+FFFD for the few
+// bytes that have none), and whether anything was replaced.
+func repairUTF8(s string) (string, bool) {
+	if utf8.ValidString(s) {
+		return s, false
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + len(s)/8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != utf8.RuneError || size > 1 {
+			sb.WriteString(s[i : i+size])
+			i += size
+			continue
+		}
+		b := s[i]
+		switch {
+		case b >= 0xA0:
+			sb.WriteRune(rune(b))
+		case b >= 0x80 && cp1252High[b-0x80] != 0:
+			sb.WriteRune(cp1252High[b-0x80])
+		default:
+			sb.WriteRune(utf8.RuneError)
+		}
+		i++
+	}
+	return sb.String(), true
+}
+
+// repairArticleText applies repairUTF8 to the text fields a takes from the
+// page and reports whether any needed it.
+func repairArticleText(fields ...*string) bool {
+	repaired := false
+	for _, f := range fields {
+		if fixed, ok := repairUTF8(*f); ok {
+			*f = fixed
+			repaired = true
+		}
+	}
+	return repaired
+}
@@ -0,0 +1,47 @@
+package fetch
+
+import (
+	"context"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestRepairUTF8(t *testing.T) {
+	if got, ok := repairUTF8("caf\xe9 \x93quoted\x94"); !ok || got != "café “quoted”" {
+		t.Errorf("repairUTF8 = %q, %v", got, ok)
+	}
+	if got, ok := repairUTF8("café"); ok || got != "café" {
+		t.Errorf("valid UTF-8 changed: %q, %v", got, ok)
+	}
+}
+
+// Every text field taken from the page is repaired, including the byline
+// list, the series name and reader comments.
+func TestFinishArticleRepairsAllText(t *testing.T) {
+	a := &art.Article{
+		Title:    "Caf\xe9",
+		Content:  "<p>Caf\xe9 au lait.</p>",
+		Author:   "Ren\xe9e & Zo\xeb",
+		Authors:  []string{"Ren\xe9e", "Zo\xeb"},
+		Series:   "Caf\xe9 Notes",
+		Comments: []art.Comment{{Author: "Jos\xe9", Text: "Tr\xe8s bien"}},
+	}
+	finishArticle(context.Background(), a, strategyPrimary, "en", "", FetchOptions{}, "https://example.com/p/x", "https://example.com/p/x")
+	for name, got := range map[string]string{
+		"Title":              a.Title,
+		"Author":             a.Author,
+		"Authors[0]":         a.Authors[0],
+		"Authors[1]":         a.Authors[1],
+		"Series":             a.Series,
+		"Comments[0].Author": a.Comments[0].Author,
+		"Comments[0].Text":   a.Comments[0].Text,
+	} {
+		if _, bad := repairUTF8(got); bad {
+			t.Errorf("%s = %q, still invalid UTF-8", name, got)
+		}
+	}
+	if a.Authors[0] != "Renée" || a.Series != "Café Notes" || a.Comments[0].Text != "Très bien" {
+		t.Errorf("repaired to %q, %q, %q", a.Authors[0], a.Series, a.Comments[0].Text)
+	}
+}
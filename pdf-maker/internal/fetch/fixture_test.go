@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/netguard"
+)
+
+// fetchFixture fetches testdata/<name> from an httptest server as described
+// in testdata/README.md.
+func fetchFixture(t *testing.T, name string) *art.Article {
+	t.Helper()
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+	a, _, err := FetchArticleWithOptions(context.Background(), srv.URL+"/"+name, FetchOptions{
+		Client:  srv.Client(),
+		Network: &netguard.Policy{AllowPrivate: true},
+	})
+	if err != nil {
+		t.Fatalf("fetch %s: %v", name, err)
+	}
+	return a
+}
+
+// fetchHTML fetches page, served as text/html from an httptest server.
+func fetchHTML(t *testing.T, page string) *art.Article {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+	a, _, err := FetchArticleWithOptions(context.Background(), srv.URL+"/p/post", FetchOptions{
+		Client:  srv.Client(),
+		Network: &netguard.Policy{AllowPrivate: true},
+	})
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	return a
+}
+
+// loadFixture parses testdata/<name>.
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// assertContains fails for each of want that s lacks.
+func assertContains(t *testing.T, what, s string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(s, w) {
+			t.Errorf("%s lacks %q", what, w)
+		}
+	}
+}
+
+// assertLacks fails for each of unwanted that s contains.
+func assertLacks(t *testing.T, what, s string, unwanted ...string) {
+	t.Helper()
+	for _, u := range unwanted {
+		if strings.Contains(s, u) {
+			t.Errorf("%s contains %q", what, u)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+)
+
+// ghostContentSelectors are tried in order by RulesetGhost: the post body
+// of current themes (Casper, Source, Headline), then older ones.
+var ghostContentSelectors = []string{
+	"section.gh-content", ".gh-content", ".post-full-content .post-content",
+	".post-content", ".post-full-content", "article .content",
+}
+
+// ghostTitleSelectors locate the post title in Ghost themes.
+var ghostTitleSelectors = []string{
+	"h1.article-title", "h1.gh-article-title", "h1.post-full-title", "h1.post-title", "article h1",
+}
+
+// ghostAuthorSelectors locate author links in Ghost bylines; Ghost author
+// pages live under /author/<slug>/.
+var ghostAuthorSelectors = []string{
+	".article-byline a[href*='/author/']", ".author-name a", ".gh-article-author a[href*='/author/']",
+	"a[rel='author']", "a[href*='/author/']",
+}
+
+// isGhostPage reports whether the page was published with Ghost, which
+// stamps every page with <meta name="generator" content="Ghost 5.80">.
+func isGhostPage(doc *goquery.Document) bool {
+	gen := strings.TrimSpace(doc.Find("meta[name='generator']").AttrOr("content", ""))
+	return strings.HasPrefix(strings.ToLower(gen), "ghost")
+}
+
+// resolveRuleset returns the extraction ruleset for doc: the one asked for,
+// except that RulesetAuto (or none) becomes RulesetGhost on Ghost pages.
+func resolveRuleset(doc *goquery.Document, ruleset string) string {
+	if (ruleset == "" || ruleset == RulesetAuto) && isGhostPage(doc) {
+		return RulesetGhost
+	}
+	return ruleset
+}
+
+// applyGhostMetadata fills the fields the Substack selectors and the
+// page's JSON-LD left blank on a Ghost page: the title from the theme's
+// heading or og:title, and the authors from the byline's author links.
+func applyGhostMetadata(doc *goquery.Document, a *art.Article) {
+	if a.Title == "" {
+		for _, sel := range ghostTitleSelectors {
+			if v := strings.Join(strings.Fields(doc.Find(sel).First().Text()), " "); v != "" {
+				a.Title = v
+				break
+			}
+		}
+	}
+	if a.Title == "" {
+		a.Title = strings.TrimSpace(doc.Find("meta[property='og:title']").AttrOr("content", ""))
+	}
+	for _, sel := range ghostAuthorSelectors {
+		if len(a.Authors) > 0 {
+			break
+		}
+		a.Authors = collectNames(doc.Find(sel))
+		a.Author = art.JoinAuthors(a.Authors)
+	}
+}
@@ -0,0 +1,78 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestResolveRuleset(t *testing.T) {
+	ghost := loadFixture(t, "ghost.html")
+	medium := loadFixture(t, "medium.html")
+	tests := []struct {
+		name, fixture, asked, want string
+	}{
+		{"auto on Ghost", "ghost", RulesetAuto, RulesetGhost},
+		{"none on Ghost", "ghost", "", RulesetGhost},
+		{"explicit on Ghost", "ghost", RulesetGeneric, RulesetGeneric},
+		{"auto elsewhere", "medium", RulesetAuto, RulesetAuto},
+	}
+	for _, tt := range tests {
+		doc := ghost
+		if tt.fixture == "medium" {
+			doc = medium
+		}
+		if got := resolveRuleset(doc, tt.asked); got != tt.want {
+			t.Errorf("%s: resolveRuleset(%q) = %q, want %q", tt.name, tt.asked, got, tt.want)
+		}
+	}
+}
+
+func TestApplyGhostMetadata(t *testing.T) {
+	doc := loadFixture(t, "ghost.html")
+
+	a := &art.Article{}
+	applyGhostMetadata(doc, a)
+	if a.Title != "Notes on Slow Travel" {
+		t.Errorf("Title = %q, want the theme heading", a.Title)
+	}
+	if a.Author != "Robin Example" || len(a.Authors) != 1 {
+		t.Errorf("Author = %q (%v), want the byline's author link", a.Author, a.Authors)
+	}
+
+	// Fields the Substack selectors or JSON-LD already found are kept
+	a = &art.Article{Title: "From JSON-LD", Authors: []string{"LD Author"}, Author: "LD Author"}
+	applyGhostMetadata(doc, a)
+	if a.Title != "From JSON-LD" || a.Author != "LD Author" {
+		t.Errorf("overwrote earlier fields: Title = %q, Author = %q", a.Title, a.Author)
+	}
+}
+
+func TestFetchGhostFixture(t *testing.T) {
+	a := fetchFixture(t, "ghost.html")
+	if a.Title != "Notes on Slow Travel" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	if a.Author != "Robin Example" {
+		t.Errorf("Author = %q", a.Author)
+	}
+	if a.Publication != "The Example Dispatch" {
+		t.Errorf("Publication = %q", a.Publication)
+	}
+	if a.Language != "de" {
+		t.Errorf("Language = %q, want de", a.Language)
+	}
+	if want := time.Date(2025, 6, 1, 5, 0, 0, 0, time.UTC); !a.PubDate.Equal(want) {
+		t.Errorf("PubDate = %v, want %v", a.PubDate, want)
+	}
+	if want := time.Date(2025, 6, 3, 9, 30, 0, 0, time.UTC); !a.UpdatedDate.Equal(want) {
+		t.Errorf("UpdatedDate = %v, want %v", a.UpdatedDate, want)
+	}
+	if !strings.HasSuffix(a.CoverImage, "/content/images/2025/06/cover.jpg") || !strings.HasPrefix(a.CoverImage, "http") {
+		t.Errorf("CoverImage = %q, want the JSON-LD image resolved against the page", a.CoverImage)
+	}
+	assertContains(t, "content", a.Content, "overnight train from Lisbon", "<table>", "Berlin – Warsaw", "/content/images/2025/06/platform.jpg")
+	assertLacks(t, "content", a.Content, "Subscribe to The Example Dispatch", "Notes on Slow Travel</h1>")
+}
@@ -0,0 +1,169 @@
+package fetch
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// linkedData is the article metadata a page declares as schema.org JSON-LD
+// (<script type="application/ld+json">). Sites generate it from their CMS
+// rather than their theme, so it survives redesigns that break selectors.
+type linkedData struct {
+	Headline      string
+	Authors       []string // in byline order, without duplicates
+	Publisher     string
+	DatePublished time.Time
+	DateModified  time.Time
+	Image         string // lead image URL as declared, possibly relative
+}
+
+// isArticleType reports whether a schema.org @type describes an article:
+// Article and its subtypes (NewsArticle, TechArticle, ...) and the posting
+// types (BlogPosting, SocialMediaPosting, ...).
+func isArticleType(t string) bool {
+	t = strings.TrimPrefix(strings.TrimPrefix(t, "https://schema.org/"), "http://schema.org/")
+	return strings.HasSuffix(t, "Article") || strings.HasSuffix(t, "Posting")
+}
+
+// extractLinkedData returns the metadata of the first article node in the
+// page's JSON-LD scripts. A script may hold one node, an array of nodes or
+// an {"@graph": [...]} wrapper, and nodes may refer to one another by @id
+// (WordPress/Yoast lists the author as a separate Person). ok is false when
+// the page declares no article.
+func extractLinkedData(doc *goquery.Document) (ld linkedData, ok bool) {
+	var nodes []map[string]any
+	doc.Find("script[type='application/ld+json']").Each(func(_ int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &v); err == nil {
+			nodes = collectLinkedNodes(v, nodes)
+		}
+	})
+	byID := make(map[string]map[string]any)
+	for _, n := range nodes {
+		if id, _ := n["@id"].(string); id != "" {
+			byID[id] = n
+		}
+	}
+	for _, n := range nodes {
+		if !hasArticleType(n["@type"]) {
+			continue
+		}
+		ld.Headline = linkedString(n["headline"])
+		if ld.Headline == "" {
+			ld.Headline = linkedString(n["name"])
+		}
+		seen := make(map[string]bool)
+		for _, name := range linkedNames(n["author"], byID) {
+			if key := strings.ToLower(name); !seen[key] {
+				seen[key] = true
+				ld.Authors = append(ld.Authors, name)
+			}
+		}
+		if p := linkedNames(n["publisher"], byID); len(p) > 0 {
+			ld.Publisher = p[0]
+		}
+		ld.DatePublished, _ = parseDate(linkedString(n["datePublished"]))
+		ld.DateModified, _ = parseDate(linkedString(n["dateModified"]))
+		ld.Image = linkedImage(n["image"], byID)
+		return ld, true
+	}
+	return ld, false
+}
+
+// collectLinkedNodes appends the JSON-LD nodes in v to nodes, unwrapping
+// arrays and @graph.
+func collectLinkedNodes(v any, nodes []map[string]any) []map[string]any {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			nodes = collectLinkedNodes(e, nodes)
+		}
+	case map[string]any:
+		nodes = append(nodes, v)
+		if g, ok := v["@graph"]; ok {
+			nodes = collectLinkedNodes(g, nodes)
+		}
+	}
+	return nodes
+}
+
+// hasArticleType reports whether a node's @type, a string or a list, names
+// an article type.
+func hasArticleType(t any) bool {
+	switch t := t.(type) {
+	case string:
+		return isArticleType(t)
+	case []any:
+		for _, e := range t {
+			if s, ok := e.(string); ok && isArticleType(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linkedString returns v when it is a string, with whitespace collapsed.
+func linkedString(v any) string {
+	s, _ := v.(string)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// linkedNames returns the names of the Person or Organization values in v:
+// plain strings, objects with a name, objects that only reference another
+// node by @id, or a list of these.
+func linkedNames(v any, byID map[string]map[string]any) []string {
+	var names []string
+	switch v := v.(type) {
+	case string:
+		if s := linkedString(v); s != "" {
+			names = append(names, s)
+		}
+	case []any:
+		for _, e := range v {
+			names = append(names, linkedNames(e, byID)...)
+		}
+	case map[string]any:
+		name := linkedString(v["name"])
+		if name == "" {
+			if id, _ := v["@id"].(string); id != "" {
+				name = linkedString(byID[id]["name"])
+			}
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// linkedImage returns the URL of the first image in v: a plain URL string,
+// an ImageObject with a url (or contentUrl), one that only references
+// another node by @id, or a list of these.
+func linkedImage(v any, byID map[string]map[string]any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		for _, e := range v {
+			if u := linkedImage(e, byID); u != "" {
+				return u
+			}
+		}
+	case map[string]any:
+		for _, key := range []string{"url", "contentUrl"} {
+			if u, _ := v[key].(string); strings.TrimSpace(u) != "" {
+				return strings.TrimSpace(u)
+			}
+		}
+		if id, _ := v["@id"].(string); id != "" {
+			if n, ok := byID[id]; ok {
+				return linkedImage(n, nil) // nil: follow one reference, never a cycle
+			}
+		}
+	}
+	return ""
+}
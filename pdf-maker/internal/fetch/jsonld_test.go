@@ -0,0 +1,50 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestLinkedDataImage(t *testing.T) {
+	tests := []struct {
+		name, image, graph, want string
+	}{
+		{"url string", `"https://example.org/a.jpg"`, ``, "https://example.org/a.jpg"},
+		{"ImageObject", `{"@type": "ImageObject", "url": "https://example.org/b.jpg"}`, ``, "https://example.org/b.jpg"},
+		{"contentUrl", `{"@type": "ImageObject", "contentUrl": "https://example.org/c.jpg"}`, ``, "https://example.org/c.jpg"},
+		{"list", `["", {"url": "https://example.org/d.jpg"}, "https://example.org/e.jpg"]`, ``, "https://example.org/d.jpg"},
+		{"@id reference", `{"@id": "#primaryimage"}`, `, {"@type": "ImageObject", "@id": "#primaryimage", "url": "https://example.org/f.jpg"}`, "https://example.org/f.jpg"},
+		{"dangling reference", `{"@id": "#missing"}`, ``, ""},
+	}
+	for _, tt := range tests {
+		page := `<html><head><script type="application/ld+json">{"@graph": [{"@type": "BlogPosting", "headline": "H", "image": ` +
+			tt.image + `}` + tt.graph + `]}</script></head><body></body></html>`
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ld, ok := extractLinkedData(doc)
+		if !ok {
+			t.Fatalf("%s: no article node found", tt.name)
+		}
+		if ld.Image != tt.want {
+			t.Errorf("%s: Image = %q, want %q", tt.name, ld.Image, tt.want)
+		}
+	}
+}
+
+func TestCoverImagePrefersOpenGraph(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head><meta property="og:image" content="/og.jpg"></head></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := extractCoverImage(doc, "https://example.org/p/x", "/ld.jpg"); got != "https://example.org/og.jpg" {
+		t.Errorf("with og:image: %q", got)
+	}
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader(`<html><head></head></html>`))
+	if got := extractCoverImage(doc, "https://example.org/p/x", "/ld.jpg"); got != "https://example.org/ld.jpg" {
+		t.Errorf("JSON-LD fallback: %q", got)
+	}
+}
@@ -0,0 +1,121 @@
+package fetch
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// pageLanguage returns the language a page declares, as a BCP 47 tag:
+// <html lang>, else og:locale, else "". Callers fall back to
+// detectLanguage on the body text.
+func pageLanguage(doc *goquery.Document) string {
+	if v := normalizeLangTag(doc.Find("html").First().AttrOr("lang", "")); v != "" {
+		return v
+	}
+	return normalizeLangTag(doc.Find("meta[property='og:locale']").AttrOr("content", ""))
+}
+
+// normalizeLangTag turns "en_US" / "EN-us" into "en-US" and rejects values
+// that are not plausibly language tags.
+func normalizeLangTag(tag string) string {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	parts := strings.Split(tag, "-")
+	primary := strings.ToLower(parts[0])
+	if len(primary) < 2 || len(primary) > 3 || strings.IndexFunc(primary, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0 {
+		return ""
+	}
+	if len(parts) > 1 && len(parts[1]) == 2 {
+		return primary + "-" + strings.ToUpper(parts[1])
+	}
+	return primary
+}
+
+// scriptLanguages maps non-Latin scripts to the language most likely to be
+// written in them. Checked in order, so Japanese kana wins over shared Han.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// stopwords holds very common function words used to tell Latin-script
+// languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "with", "for", "was", "this"},
+	"es": {"el", "los", "las", "que", "de", "y", "en", "por", "una", "para"},
+	"fr": {"le", "les", "des", "et", "est", "une", "que", "dans", "pour", "pas"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "den", "zu"},
+	"pt": {"o", "os", "que", "de", "e", "não", "uma", "para", "com", "em"},
+	"it": {"il", "che", "di", "e", "la", "non", "per", "una", "sono", "gli"},
+	"nl": {"de", "het", "een", "en", "van", "dat", "niet", "is", "op", "zijn"},
+}
+
+// detectLanguage is a lightweight detector: it classifies by dominant script,
+// and for Latin text picks the language whose stopwords occur most often.
+// Returns "" when there is too little text to decide.
+func detectLanguage(text string) string {
+	const minLetters = 40
+
+	letters := 0
+	scriptCounts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.table, r) {
+				scriptCounts[sl.lang]++
+				break
+			}
+		}
+	}
+	if letters < minLetters {
+		return ""
+	}
+
+	best, bestCount := "", 0
+	for lang, n := range scriptCounts {
+		if n > bestCount {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount*2 > letters {
+		return best
+	}
+
+	counts := make(map[string]int)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+		for lang, words := range stopwords {
+			for _, sw := range words {
+				if w == sw {
+					counts[lang]++
+					break
+				}
+			}
+		}
+	}
+	best, bestCount = "", 0
+	for _, lang := range []string{"en", "es", "fr", "de", "pt", "it", "nl"} {
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	return best
+}
@@ -0,0 +1,17 @@
+package fetch
+
+import "testing"
+
+func TestFetchRTLFixture(t *testing.T) {
+	a := fetchFixture(t, "rtl.html")
+	if a.Language != "he" {
+		t.Errorf("Language = %q, want he detected from the body", a.Language)
+	}
+	if !a.IsRTL() || a.Direction() != "rtl" {
+		t.Errorf("IsRTL = %t, Direction = %q; want a right-to-left article", a.IsRTL(), a.Direction())
+	}
+	if a.Title != "מכתב מירושלים" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	assertContains(t, "content", a.Content, "השבוע ירד גשם ראשון", "2 עוגיות")
+}
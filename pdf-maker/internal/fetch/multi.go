@@ -1,8 +1,14 @@
 package fetch
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +16,8 @@ import (
 	art "pdf-maker/internal/article"
 	"pdf-maker/internal/clean"
 	"pdf-maker/internal/media"
+	"pdf-maker/internal/netguard"
+	"pdf-maker/internal/useragent"
 )
 
 // ArticleResult holds the outcome of a single fetch attempt.
@@ -20,6 +28,11 @@ type ArticleResult struct {
 	Index      int
 	Elapsed    time.Duration
 	CleanStats clean.Stats
+
+	// Raw is the page body as fetched, before extraction and cleaning
+	// (nil on failure). It is not retained after OnResult returns; use
+	// FetchOptions.RawDir to keep every body without holding them all.
+	Raw []byte
 }
 
 // FetchArticlesConcurrent fetches multiple article URLs concurrently with a bounded level of parallelism.
@@ -31,12 +44,109 @@ func FetchArticlesConcurrent(ctx context.Context, urls []string, maxParallel int
 
 // FetchArticlesConcurrentWithImages fetches multiple articles and optionally downloads images.
 func FetchArticlesConcurrentWithImages(ctx context.Context, urls []string, maxParallel int, imageDownloader *media.Downloader) ([]*art.Article, []error) {
+	return FetchArticlesConcurrentWithOptions(ctx, urls, FetchOptions{
+		MaxParallel:     maxParallel,
+		ImageDownloader: imageDownloader,
+	})
+}
+
+// FetchOptions configures a concurrent batch fetch.
+type FetchOptions struct {
+	MaxParallel     int                // maximum concurrent fetches (default 4)
+	ImageDownloader *media.Downloader  // optional: download images and rewrite URLs to local paths
+	Retry           RetryPolicy        // per-article retries and deadline budgeting
+	Clean           clean.CleanOptions // extra cleaning rules applied to every article
+	ThinContent     ThinContentPolicy  // re-fetch once when extraction yields very little text
+
+	// CircuitBreakAfter skips a host's remaining URLs once this many of its
+	// fetches in a row have failed with network errors, 429 or 5xx
+	// (0 = never). Skipped URLs fail with a *CircuitOpenError.
+	CircuitBreakAfter int
+
+	// Client, when set, performs the page requests instead of a default
+	// client with a 15s timeout. Tests inject one backed by httptest (or a
+	// custom Transport) to serve the recorded pages in testdata/.
+	Client *http.Client
+
+	// Network guards page, archive and stylesheet requests (see netguard).
+	// nil refuses private, loopback and link-local addresses and allows
+	// every public host. Images follow the downloader's own policy.
+	Network *netguard.Policy
+
+	// Verbose logs fetch diagnostics to stderr, such as the redirect chain
+	// a page followed.
+	Verbose bool
+
+	// PreferAPI fetches Substack post URLs (<name>.substack.com/p/<slug>)
+	// through the publication's JSON post API instead of scraping the page,
+	// falling back to the page when the API has no such post. JSON
+	// responses are recognised by Content-Type either way.
+	PreferAPI bool
+
+	// CoverImages downloads each article's cover image (Article.CoverImage)
+	// with ImageDownloader, so layouts that show covers can render it; a
+	// cover that fails to download is dropped. Needs ImageDownloader.
+	CoverImages bool
+
+	// UserAgents, when set, rotates the page requests' User-Agent through a
+	// list instead of the fetcher's fixed agent.
+	UserAgents *useragent.Rotator
+
+	// ArticleIndex is the article's 1-based position, used to number image
+	// filenames when the downloader has IndexedFilenames set.
+	// FetchArticlesConcurrentWithOptions sets it per URL: ArticleNumbers[i]
+	// when given, else i+1.
+	ArticleIndex   int
+	ArticleNumbers []int
+
+	// Publications holds per-publication defaults (image removal, strip
+	// selectors, extraction ruleset), matched against each URL by
+	// PublicationID, else by host. PublicationIDs gives the ID of each URL
+	// of a batch, like ArticleNumbers; FetchArticlesConcurrentWithOptions
+	// sets PublicationID from it.
+	Publications   art.PublicationConfig
+	PublicationID  string
+	PublicationIDs []string
+
+	// Extractor names the content extraction ruleset (RulesetAuto when
+	// empty); a publication's ExtractorRuleset overrides it.
+	Extractor string
+
+	// SpillDir, when set, moves each fetched article's content into a file
+	// in this directory (art.SpillContent) as soon as it arrives, bounding
+	// memory for large batches. The caller creates and removes the directory.
+	SpillDir string
+
+	// RawDir, when set, receives each fetched page's raw body, before
+	// extraction and cleaning, as soon as it arrives: DeriveFilename(url),
+	// with a .json extension for Substack API responses. For archiving,
+	// caching and debugging extraction. The caller creates the directory;
+	// a failed write is a warning, not a fetch error.
+	RawDir string
+
+	// OnCleaned, when set, receives the cleaning statistics of every page
+	// cleaned, including thin-content re-fetches. Called concurrently.
+	OnCleaned func(pageURL string, stats clean.Stats)
+
+	// OnResult, when set, is called once per URL as soon as its fetch
+	// (including retries) finishes, successfully or not. Called concurrently.
+	OnResult func(ArticleResult)
+}
+
+// FetchArticlesConcurrentWithOptions fetches multiple articles as configured by opts.
+// When ctx has a deadline, each article (including its retries) is limited to a
+// share of the remaining time so one slow URL cannot starve the rest of the batch.
+func FetchArticlesConcurrentWithOptions(ctx context.Context, urls []string, opts FetchOptions) ([]*art.Article, []error) {
 	if len(urls) == 0 {
 		return nil, nil
 	}
+	maxParallel := opts.MaxParallel
 	if maxParallel <= 0 {
 		maxParallel = 4
 	}
+	policy := opts.Retry.withDefaults(maxParallel)
+	budget := articleBudget(ctx, len(urls), policy.BudgetFactor)
+	breaker := newHostBreaker(opts.CircuitBreakAfter)
 
 	results := make([]*art.Article, len(urls))
 	errs := make([]error, 0)
@@ -52,7 +162,32 @@ func FetchArticlesConcurrentWithImages(ctx context.Context, urls []string, maxPa
 			sem <- struct{}{} // acquire
 			defer func() { <-sem }()
 
-			artc, _, err := FetchArticleWithImages(ctx, u, imageDownloader)
+			aopts := opts
+			aopts.ArticleIndex = i + 1
+			if i < len(opts.ArticleNumbers) {
+				aopts.ArticleIndex = opts.ArticleNumbers[i]
+			}
+			if i < len(opts.PublicationIDs) {
+				aopts.PublicationID = opts.PublicationIDs[i]
+			}
+			var artc *art.Article
+			var raw []byte
+			err := breaker.check(u)
+			if err == nil {
+				artc, raw, err = fetchWithRetry(ctx, u, aopts, policy, budget)
+				breaker.record(u, err)
+			}
+			if err == nil && opts.RawDir != "" {
+				if _, werr := saveRaw(opts.RawDir, u, raw); werr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to save raw page for %s: %v\n", u, werr)
+				}
+			}
+			if err == nil && opts.SpillDir != "" {
+				err = art.SpillContent(artc, opts.SpillDir)
+			}
+			if opts.OnResult != nil {
+				opts.OnResult(ArticleResult{Article: artc, Err: err, URL: u, Index: i, Elapsed: time.Since(start), Raw: raw})
+			}
 
 			mu.Lock()
 			defer mu.Unlock()
@@ -61,7 +196,6 @@ func FetchArticlesConcurrentWithImages(ctx context.Context, urls []string, maxPa
 			} else {
 				results[i] = artc
 			}
-			_ = time.Since(start) // (future: could log elapsed per URL)
 			return nil // do not abort other goroutines
 		})
 	}
@@ -75,5 +209,85 @@ func FetchArticlesConcurrentWithImages(ctx context.Context, urls []string, maxPa
 			compacted = append(compacted, r)
 		}
 	}
+
+	compacted, dupes := DedupeArticles(compacted)
+	if dupes > 0 {
+		fmt.Fprintf(os.Stderr, "Collapsed %d duplicate article(s)\n", dupes)
+	}
 	return compacted, errs
 }
+
+// DedupeArticles removes articles that duplicate an earlier one, keeping the
+// first occurrence. Two articles are duplicates when they share a canonical URL
+// (falling back to the fetched link, normalized to ignore scheme, "www.",
+// tracking parameters, fragment and trailing slash; see normalizeArticleURL)
+// or the same ArticleFingerprint.
+// Returns the remaining articles in order and the number removed.
+func DedupeArticles(articles []*art.Article) ([]*art.Article, int) {
+	seenURL := make(map[string]bool)
+	seenPrint := make(map[string]bool)
+	kept := make([]*art.Article, 0, len(articles))
+	for _, a := range articles {
+		key := normalizeArticleURL(a.CanonicalURL)
+		if key == "" {
+			key = normalizeArticleURL(a.Link)
+		}
+		fp := art.ArticleFingerprint(a)
+		if (key != "" && seenURL[key]) || (fp != "" && seenPrint[fp]) {
+			continue
+		}
+		if key != "" {
+			seenURL[key] = true
+		}
+		if fp != "" {
+			seenPrint[fp] = true
+		}
+		kept = append(kept, a)
+	}
+	return kept, len(articles) - len(kept)
+}
+
+// normalizeArticleURL reduces a URL to host + path + query for duplicate
+// detection. The query is kept, as some sites address posts by it
+// (?p=123, ?id=...), but tracking parameters (utm_*, ref, fbclid) are
+// dropped and the rest sorted, so share links collapse onto the post.
+func normalizeArticleURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	key := host + strings.TrimRight(u.EscapedPath(), "/")
+	q := u.Query()
+	for k := range q {
+		if isTrackingParam(k) {
+			q.Del(k)
+		}
+	}
+	if len(q) > 0 {
+		key += "?" + q.Encode() // Encode sorts by key
+	}
+	return key
+}
+
+// isTrackingParam reports whether the query parameter name only records
+// where a link was shared or clicked.
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || name == "ref" || name == "fbclid"
+}
+
+// saveRaw writes raw, the body fetched from pageURL, into dir as
+// DeriveFilename(pageURL), swapping .html for .json when the body is a JSON
+// document, and returns the file's path.
+func saveRaw(dir, pageURL string, raw []byte) (string, error) {
+	name := DeriveFilename(pageURL)
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		name = strings.TrimSuffix(name, ".html") + ".json"
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
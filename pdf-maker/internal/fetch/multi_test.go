@@ -0,0 +1,38 @@
+package fetch
+
+import (
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestNormalizeArticleURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"https://www.Example.com/p/post/", "example.com/p/post"},
+		{"http://example.com/p/post#section", "example.com/p/post"},
+		{"https://example.com/p/post?utm_source=twitter&utm_medium=social", "example.com/p/post"},
+		{"https://example.com/p/post?ref=home&fbclid=abc", "example.com/p/post"},
+		{"https://blog.example.com/?p=123", "blog.example.com?p=123"},
+		{"https://blog.example.com/?utm_campaign=x&p=123", "blog.example.com?p=123"},
+		{"https://example.com/read?id=2&page=1", "example.com/read?id=2&page=1"},
+		{"https://example.com/read?page=1&id=2", "example.com/read?id=2&page=1"},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeArticleURL(tt.in); got != tt.want {
+			t.Errorf("normalizeArticleURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeArticlesKeepsQueryAddressedPosts(t *testing.T) {
+	articles := []*art.Article{
+		{Title: "One", Link: "https://blog.example.com/?p=1", Content: "<p>one</p>"},
+		{Title: "Two", Link: "https://blog.example.com/?p=2", Content: "<p>two</p>"},
+		{Title: "One again", Link: "https://blog.example.com/?p=1&utm_source=rss", Content: "<p>one, shared</p>"},
+	}
+	kept, removed := DedupeArticles(articles)
+	if removed != 1 || len(kept) != 2 || kept[0].Title != "One" || kept[1].Title != "Two" {
+		t.Errorf("DedupeArticles kept %d, removed %d; want One and Two", len(kept), removed)
+	}
+}
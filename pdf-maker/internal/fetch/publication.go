@@ -0,0 +1,111 @@
+package fetch
+
+import (
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+)
+
+// Content extraction rulesets, selectable per publication through
+// art.PubSettings.ExtractorRuleset or for a whole fetch through
+// FetchOptions.Extractor.
+const (
+	// RulesetAuto tries Substack's post body (div.available-content), then
+	// div#entry, then the page's densest text container (extractDensest),
+	// then keeps the whole page. The default.
+	RulesetAuto = "auto"
+	// RulesetSubstack accepts only Substack's post body and fails the fetch
+	// when the page has none, rather than printing the whole page.
+	RulesetSubstack = "substack"
+	// RulesetGeneric targets non-Substack blogs: the page's <article>,
+	// <main> or common CMS post-body containers, then the densest text
+	// container, then the whole page.
+	RulesetGeneric = "generic"
+	// RulesetGhost targets Ghost blogs: the post body (.gh-content or
+	// .post-content), with a missing title and authors filled from the
+	// theme's markup. RulesetAuto switches to it on pages whose generator
+	// meta tag names Ghost.
+	RulesetGhost = "ghost"
+)
+
+// genericContentSelectors are tried in order by RulesetGeneric.
+var genericContentSelectors = []string{
+	"article .entry-content", "article .post-content", ".gh-content",
+	"article", "main", "[role='main']", ".entry-content", ".post-content",
+}
+
+// ValidateExtractorRuleset reports whether name is a known extraction
+// ruleset. The empty string selects RulesetAuto.
+func ValidateExtractorRuleset(name string) error {
+	switch name {
+	case "", RulesetAuto, RulesetSubstack, RulesetGeneric, RulesetGhost:
+		return nil
+	}
+	return fmt.Errorf("invalid extractor ruleset %q: must be %q, %q, %q or %q", name, RulesetAuto, RulesetSubstack, RulesetGeneric, RulesetGhost)
+}
+
+// extractContent returns the inner HTML of the page's article body under
+// the named ruleset (see resolveRuleset) and the strategy that found it.
+func extractContent(doc *goquery.Document, raw []byte, ruleset string) (string, extractionStrategy, error) {
+	inner := func(sel string) string {
+		if s := doc.Find(sel).First(); s.Length() > 0 {
+			if h, err := s.Html(); err == nil {
+				return h
+			}
+		}
+		return ""
+	}
+	switch ruleset {
+	case RulesetSubstack:
+		if h := inner("div.available-content"); h != "" {
+			return h, strategyPrimary, nil
+		}
+		return "", strategyRawPage, fmt.Errorf("no Substack post body (div.available-content) found")
+	case RulesetGhost:
+		for _, sel := range ghostContentSelectors {
+			if h := inner(sel); h != "" {
+				return h, strategyPrimary, nil
+			}
+		}
+	case RulesetGeneric:
+		for _, sel := range genericContentSelectors {
+			if h := inner(sel); h != "" {
+				return h, strategyFallback, nil
+			}
+		}
+	default:
+		if h := inner("div.available-content"); h != "" {
+			return h, strategyPrimary, nil
+		}
+		if h := inner("div#entry"); h != "" {
+			return h, strategyFallback, nil
+		}
+	}
+	if h, ok := extractDensest(doc); ok {
+		return h, strategyDensity, nil
+	}
+	return string(raw), strategyRawPage, nil
+}
+
+// forPublication returns opts adjusted by the settings opts.Publications
+// holds for pageURL (matched by opts.PublicationID, else host): extra strip
+// selectors, the extraction ruleset, and no image downloads when the
+// publication's images are removed.
+func (opts FetchOptions) forPublication(pageURL string) (FetchOptions, art.PubSettings) {
+	s, ok := opts.Publications.Lookup(opts.PublicationID, pageURL)
+	if !ok {
+		return opts, s
+	}
+	if len(s.StripSelectors) > 0 {
+		sels := append([]string(nil), opts.Clean.ExtraRemoveSelectors...)
+		opts.Clean.ExtraRemoveSelectors = append(sels, s.StripSelectors...)
+	}
+	if s.ExtractorRuleset != "" {
+		opts.Extractor = s.ExtractorRuleset
+	}
+	if s.RemoveImages {
+		opts.ImageDownloader = nil
+	}
+	return opts, s
+}
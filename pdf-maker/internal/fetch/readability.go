@@ -0,0 +1,142 @@
+package fetch
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Readability-style fallback: when no known container holds the post, the
+// body is taken to be the element that collects the most paragraph text.
+// Each paragraph of real prose scores its parent fully and its grandparent
+// by half, so the innermost container of the article wins over the page
+// wrapper around it; class and id names nudge the score, and link-heavy
+// containers (navigation, "related posts") are discounted.
+
+// minDenseText is the text, in characters, the densest candidate must hold
+// to be taken for the article; less is more likely a teaser or a widget.
+const minDenseText = 200
+
+// minScoredParagraph is the length below which a paragraph is ignored, as
+// bylines, captions and button labels are.
+const minScoredParagraph = 25
+
+var (
+	// positiveNames mark class or id names of article bodies.
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	// negativeNames mark class or id names of page furniture.
+	negativeNames = regexp.MustCompile(`(?i)comment|footer|sidebar|\bnav|menu|share|social|related|widget|promo|sponsor|subscribe|newsletter|banner|masthead|header|breadcrumb|popup|modal|cookie|\bads?\b`)
+)
+
+// boilerplateSelectors are removed from the chosen container before it is
+// returned; the cleaner strips less, as it also serves known layouts.
+const boilerplateSelectors = "script, style, nav, aside, form, header, footer, iframe, [role='navigation'], [role='complementary']"
+
+// extractDensest returns the inner HTML of the element holding the page's
+// main text, with navigation, forms and link lists removed, or ok=false
+// when no element holds minDenseText characters of prose.
+func extractDensest(doc *goquery.Document) (string, bool) {
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		return "", false
+	}
+	body = body.Clone()
+	body.Find("script, style, noscript, template").Remove()
+
+	type candidate struct {
+		sel   *goquery.Selection
+		score float64
+	}
+	candidates := make(map[*html.Node]*candidate)
+	var order []*candidate // first-scored order, so ties go to the earlier element
+	add := func(s *goquery.Selection, v float64) {
+		if s.Length() == 0 {
+			return
+		}
+		c := candidates[s.Get(0)]
+		if c == nil {
+			c = &candidate{sel: s, score: nameWeight(s)}
+			candidates[s.Get(0)] = c
+			order = append(order, c)
+		}
+		c.score += v
+	}
+
+	body.Find("p, pre").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < minScoredParagraph {
+			return
+		}
+		// One point per paragraph, per comma and per 100 characters (max 3)
+		score := 1 + float64(strings.Count(text, ",")) + min(3, float64(len(text)/100))
+		add(p.Parent(), score)
+		add(p.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, c := range order {
+		if name := goquery.NodeName(c.sel); name == "body" || name == "html" {
+			continue
+		}
+		score := c.score * (1 - linkDensity(c.sel))
+		if best == nil || score > bestScore {
+			best, bestScore = c.sel, score
+		}
+	}
+	if best == nil || len(strings.TrimSpace(best.Text())) < minDenseText {
+		return "", false
+	}
+
+	best.Find(boilerplateSelectors).Remove()
+	best.Find("div, section, ul, ol, table").Each(func(_ int, s *goquery.Selection) {
+		// Furniture-named blocks inside the body, and link lists such as
+		// "related posts" (lists and tables of links may be the article's own)
+		names := classAndID(s)
+		isBlock := goquery.NodeName(s) == "div" || goquery.NodeName(s) == "section"
+		if (negativeNames.MatchString(names) && !positiveNames.MatchString(names)) || (isBlock && linkDensity(s) > 0.5) {
+			s.Remove()
+		}
+	})
+	h, err := best.Html()
+	if err != nil || len(strings.TrimSpace(best.Text())) < minDenseText {
+		return "", false
+	}
+	return h, true
+}
+
+// nameWeight is a container's starting score from its class and id names.
+func nameWeight(s *goquery.Selection) float64 {
+	names := classAndID(s)
+	if names == "" {
+		return 0
+	}
+	w := 0.0
+	if positiveNames.MatchString(names) {
+		w += 25
+	}
+	if negativeNames.MatchString(names) {
+		w -= 25
+	}
+	return w
+}
+
+// classAndID returns s's class and id attributes, space-separated.
+func classAndID(s *goquery.Selection) string {
+	return strings.TrimSpace(s.AttrOr("class", "") + " " + s.AttrOr("id", ""))
+}
+
+// linkDensity is the share of s's text that sits inside links.
+func linkDensity(s *goquery.Selection) float64 {
+	total := len(strings.TrimSpace(s.Text()))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linked += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linked) / float64(total)
+}
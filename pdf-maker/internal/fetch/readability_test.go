@@ -0,0 +1,58 @@
+package fetch
+
+import "testing"
+
+func TestExtractDensestFixtures(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		want     []string
+		unwanted []string
+	}{
+		{
+			fixture: "blog-divs.html",
+			want: []string{
+				"For a month I baked the same loaf",
+				"Timing matters more than the number",
+				"85%: beautiful when it works",
+				"change flour before you change water",
+				"crumb.jpg",
+			},
+			unwanted: []string{
+				"Share on Mastodon", "You might also like", // share and related blocks of #col-left
+				"Sample Kitchen is a blog about bread", // sidebar
+				"high-extraction flour",                // comments
+				"Privacy", "Recipes",                   // footer and menu
+			},
+		},
+		{
+			fixture: "blog-table.html",
+			want: []string{
+				"two hundred lines of Python",
+				"<blockquote>",
+				"failure modes you already understand",
+				"<th>Build time</th>",
+				"resist adding a plugin system",
+			},
+			unwanted: []string{
+				"Archive", "feed.xml", // navigation cell
+				"Powered by a shell script", // footer
+				"Example Weblog",            // masthead
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			content, ok := extractDensest(loadFixture(t, tt.fixture))
+			if !ok {
+				t.Fatal("extractDensest found no content")
+			}
+			assertContains(t, "extractDensest", content, tt.want...)
+			assertLacks(t, "extractDensest", content, tt.unwanted...)
+
+			// The same page fetched end to end falls back to the density pass
+			a := fetchFixture(t, tt.fixture)
+			assertContains(t, "fetched content", a.Content, tt.want...)
+			assertLacks(t, "fetched content", a.Content, tt.unwanted...)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects caps how many redirects a page fetch follows.
+const maxRedirects = 10
+
+// ErrTooManyRedirects matches every *RedirectError, for callers that only
+// need to know a URL never settled on a page.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// RedirectError reports a page fetch that was stopped while redirecting:
+// either the chain revisited a URL (a loop, typically www ↔ bare domain or
+// a trailing-slash/AMP canonical bounce) or it exceeded maxRedirects.
+type RedirectError struct {
+	Chain []string // every URL requested, in order, ending with the one refused
+	Loop  bool
+}
+
+func (e *RedirectError) Error() string {
+	if e.Loop {
+		return "redirect loop: " + strings.Join(e.Chain, " → ")
+	}
+	return fmt.Sprintf("%s (%d): %s", ErrTooManyRedirects, len(e.Chain)-1, strings.Join(e.Chain, " → "))
+}
+
+// Is lets errors.Is(err, ErrTooManyRedirects) match.
+func (e *RedirectError) Is(target error) bool { return target == ErrTooManyRedirects }
+
+// redirectTracker applies the page redirect policy to one fetch and keeps
+// the chain it followed.
+type redirectTracker struct {
+	chain []string
+}
+
+// client returns a shallow copy of c whose CheckRedirect records each hop,
+// stops loops and long chains with a *RedirectError, then defers to c's own
+// CheckRedirect (the network policy's, for default clients).
+func (t *redirectTracker) client(c *http.Client) *http.Client {
+	cp := *c
+	next := c.CheckRedirect
+	cp.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(t.chain) == 0 && len(via) > 0 {
+			t.chain = append(t.chain, via[0].URL.String())
+		}
+		target := req.URL.String()
+		for _, seen := range t.chain {
+			if seen == target {
+				return &RedirectError{Chain: append(append([]string(nil), t.chain...), target), Loop: true}
+			}
+		}
+		t.chain = append(t.chain, target)
+		if len(via) >= maxRedirects {
+			return &RedirectError{Chain: append([]string(nil), t.chain...)}
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+	return &cp
+}
+
+// String renders the followed chain for verbose logs.
+func (t *redirectTracker) String() string {
+	return strings.Join(t.chain, " → ")
+}
@@ -0,0 +1,132 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/netguard"
+)
+
+// RetryPolicy configures per-article retries with exponential backoff.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts per article (default 1: no retries)
+	BaseDelay   time.Duration // delay before the first retry, doubled each time (default 500ms)
+	MaxDelay    time.Duration // cap on a single backoff delay (default 8s)
+
+	// Jitter randomizes each backoff delay by up to ±Jitter of its length
+	// so parallel workers retrying the same host do not wake in lockstep
+	// (default 0.2; negative disables).
+	Jitter float64
+
+	// BudgetFactor bounds how much of the overall context deadline one
+	// article may consume, including retries and backoff: at most
+	// remaining/len(urls) × BudgetFactor. Defaults to the fetch parallelism,
+	// i.e. each worker slot's fair share of the batch.
+	BudgetFactor float64
+}
+
+// withDefaults fills zero-valued fields.
+func (p RetryPolicy) withDefaults(maxParallel int) RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 1
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 500 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 8 * time.Second
+	}
+	if p.BudgetFactor <= 0 {
+		p.BudgetFactor = float64(maxParallel)
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	return p
+}
+
+// articleBudget returns the longest one article may take when n articles share
+// ctx's deadline, or 0 when ctx has no deadline (no per-article cap).
+func articleBudget(ctx context.Context, n int, factor float64) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok || n <= 0 {
+		return 0
+	}
+	remaining := time.Until(deadline)
+	share := time.Duration(float64(remaining) / float64(n) * factor)
+	if share > remaining {
+		share = remaining
+	}
+	return share
+}
+
+// fetchWithRetry fetches one article, retrying transient failures with
+// exponential backoff. budget (if non-zero) caps the total time spent on the
+// article; a retry is not attempted when its backoff would overrun it.
+func fetchWithRetry(ctx context.Context, pageURL string, opts FetchOptions, policy RetryPolicy, budget time.Duration) (*art.Article, []byte, error) {
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
+	delay := policy.BaseDelay
+	var lastErr error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		a, raw, err := FetchArticleWithOptions(ctx, pageURL, opts)
+		if err == nil {
+			a, raw = refetchThinContent(ctx, pageURL, opts, a, raw, delay)
+			return a, raw, nil
+		}
+		lastErr = err
+		if attempt == policy.MaxAttempts || !isRetryable(err) {
+			break
+		}
+		wait := jittered(delay, policy.Jitter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return nil, nil, fmt.Errorf("%w (retry budget exhausted after %d attempt(s))", lastErr, attempt)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("%w (retry budget exhausted after %d attempt(s))", lastErr, attempt)
+		}
+		delay *= 2
+		if delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+	return nil, nil, lastErr
+}
+
+// jittered spreads d uniformly over [d×(1-frac), d×(1+frac)]; frac <= 0
+// returns d unchanged.
+func jittered(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 {
+		return d
+	}
+	if frac > 1 {
+		frac = 1
+	}
+	return time.Duration(float64(d) * (1 + frac*(2*rand.Float64()-1)))
+}
+
+// isRetryable reports whether a fetch error is likely transient: network
+// failures and timeouts, 429 Too Many Requests, and 5xx responses.
+func isRetryable(err error) bool {
+	if errors.Is(err, netguard.ErrBlocked) || errors.Is(err, ErrTooManyRedirects) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// partNumber matches one part number: digits, a Roman numeral or a number word.
+const partNumber = `(\d{1,3}|[ivxlc]{1,6}|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+
+var (
+	// seriesPartRe finds "Part 3", "Part III of V", "Pt. 2", "Part Two" and "Chapter 4".
+	seriesPartRe = regexp.MustCompile(`(?i)\b(?:part|pt\.?|chapter|ch\.)\s*` + partNumber + `\b(?:\s*(?:of|/)\s*` + partNumber + `\b)?`)
+	// seriesFractionRe finds a bare "(3/5)" or "[3 of 5]".
+	seriesFractionRe = regexp.MustCompile(`(?i)[(\[]\s*(\d{1,3})\s*(?:/|of)\s*\d{1,3}\s*[)\]]`)
+	// seriesTrim is stripped from the ends of the title left around the part marker.
+	seriesTrim = " \t-–—:|,;.([)]"
+)
+
+// maxSeriesPart bounds a believable part number.
+const maxSeriesPart = 100
+
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// extractSeries returns the series name and part number of a multi-part
+// article, or zero values when it is not recognisably part of a series.
+// The part comes from the title, then the subtitle; the name from a series
+// meta tag when the page has one, else the title text around the marker
+// ("The Fed Explained, Part 3: Rates" → "The Fed Explained"), else the title
+// itself when only the subtitle carries the marker.
+func extractSeries(doc *goquery.Document, title, subtitle string) (string, int) {
+	name := ""
+	for _, sel := range []string{"meta[property='article:series']", "meta[name='series']", "meta[name='parsely-series']"} {
+		if v := strings.TrimSpace(doc.Find(sel).AttrOr("content", "")); v != "" {
+			name = v
+			break
+		}
+	}
+
+	if rest, part := parseSeriesPart(title); part > 0 {
+		if name == "" {
+			name = rest
+		}
+		return name, part
+	}
+	if _, part := parseSeriesPart(subtitle); part > 0 {
+		if name == "" {
+			name = strings.Trim(title, seriesTrim)
+		}
+		return name, part
+	}
+	return "", 0
+}
+
+// parseSeriesPart finds a part marker in s and returns the text before it
+// (or after it, when the marker leads) together with the part number.
+// Returns 0 when s has no marker.
+func parseSeriesPart(s string) (string, int) {
+	var loc []int
+	var num string
+	if m := seriesPartRe.FindStringSubmatchIndex(s); m != nil {
+		loc, num = m[:2], s[m[2]:m[3]]
+	} else if m := seriesFractionRe.FindStringSubmatchIndex(s); m != nil {
+		loc, num = m[:2], s[m[2]:m[3]]
+	} else {
+		return "", 0
+	}
+	part := parsePartNumber(num)
+	if part <= 0 || part > maxSeriesPart { // also rejects words like "civil" that happen to be Roman letters
+		return "", 0
+	}
+	rest := strings.Trim(s[:loc[0]], seriesTrim)
+	if rest == "" {
+		rest = strings.Trim(s[loc[1]:], seriesTrim)
+	}
+	return rest, part
+}
+
+// parsePartNumber reads digits, a Roman numeral or a number word.
+func parsePartNumber(s string) int {
+	s = strings.ToLower(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	if n, ok := numberWords[s]; ok {
+		return n
+	}
+	return romanToInt(s)
+}
+
+// romanToInt converts a lower-case Roman numeral, returning 0 if s is not one.
+func romanToInt(s string) int {
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100}
+	total := 0
+	for i := 0; i < len(s); i++ {
+		v, ok := values[s[i]]
+		if !ok {
+			return 0
+		}
+		if i+1 < len(s) && values[s[i+1]] > v {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
@@ -0,0 +1,155 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+)
+
+// cssURLRe matches url(...) references inside a stylesheet.
+var cssURLRe = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+
+// extractStyles returns the absolute URLs of the page's <link rel="stylesheet">
+// elements and the concatenated text of its inline <style> elements, in
+// document order. Used by the "original" layout.
+func extractStyles(doc *goquery.Document, pageURL string) ([]string, string) {
+	base, _ := url.Parse(pageURL)
+
+	var urls []string
+	seen := make(map[string]bool)
+	doc.Find("link[rel~='stylesheet'][href]").Each(func(_ int, s *goquery.Selection) {
+		if media := strings.ToLower(s.AttrOr("media", "")); media != "" && !strings.Contains(media, "all") && !strings.Contains(media, "print") && !strings.Contains(media, "screen") {
+			return
+		}
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if u := resolveRef(base, href); u != "" && !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	})
+
+	var css []string
+	doc.Find("style").Each(func(_ int, s *goquery.Selection) {
+		if text := strings.TrimSpace(s.Text()); text != "" {
+			css = append(css, absolutizeCSSURLs(text, base))
+		}
+	})
+	return urls, strings.Join(css, "\n")
+}
+
+// InlineStylesheets downloads every article's StylesheetURLs and prepends the
+// CSS to its PageCSS, so the "original" layout can render without network
+// access from the PDF engine. Stylesheets shared between articles (the same
+// Substack theme) are fetched once. A stylesheet that fails to download is
+// reported as a warning and skipped.
+func InlineStylesheets(ctx context.Context, articles []*art.Article) {
+	InlineStylesheetsWithOptions(ctx, articles, FetchOptions{})
+}
+
+// InlineStylesheetsWithOptions is InlineStylesheets with the network policy
+// (opts.Network) of the fetch that produced the articles.
+func InlineStylesheetsWithOptions(ctx context.Context, articles []*art.Article, opts FetchOptions) {
+	client := opts.Network.Client(15 * time.Second)
+	cache := make(map[string]string)
+	for _, a := range articles {
+		var parts []string
+		for _, u := range a.StylesheetURLs {
+			css, ok := cache[u]
+			if !ok {
+				err := opts.Network.CheckURL(u)
+				if err == nil {
+					css, err = fetchStylesheet(ctx, client, u)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to fetch stylesheet %s: %v\n", u, err)
+				}
+				cache[u] = css
+			}
+			if css != "" {
+				parts = append(parts, css)
+			}
+		}
+		if a.PageCSS != "" {
+			parts = append(parts, a.PageCSS)
+		}
+		a.PageCSS = strings.Join(parts, "\n")
+		a.StylesheetURLs = nil
+	}
+}
+
+// fetchStylesheet downloads one stylesheet and rewrites its relative url()
+// references (fonts, background images) against the stylesheet's own URL.
+func fetchStylesheet(ctx context.Context, client *http.Client, cssURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cssURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	req.Header.Set("Accept", "text/css,*/*;q=0.1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http get: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &StatusError{Code: resp.StatusCode}
+	}
+
+	const maxSize = 5 * 1024 * 1024
+	limited := &io.LimitedReader{R: resp.Body, N: maxSize + 1}
+	raw, err := io.ReadAll(limited)
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	if limited.N <= 0 {
+		return "", errors.New("stylesheet exceeds size limit (5MB)")
+	}
+
+	base, _ := url.Parse(cssURL)
+	return absolutizeCSSURLs(string(raw), base), nil
+}
+
+// absolutizeCSSURLs resolves relative url() references in css against base.
+func absolutizeCSSURLs(css string, base *url.URL) string {
+	if base == nil {
+		return css
+	}
+	return cssURLRe.ReplaceAllStringFunc(css, func(m string) string {
+		sub := cssURLRe.FindStringSubmatch(m)
+		ref := strings.TrimSpace(sub[2])
+		if strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+			return m
+		}
+		abs := resolveRef(base, ref)
+		if abs == "" {
+			return m
+		}
+		return fmt.Sprintf("url(%s%s%s)", sub[1], abs, sub[3])
+	})
+}
+
+// resolveRef resolves ref against base, returning "" for empty or invalid refs.
+func resolveRef(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return u.String()
+	}
+	return base.ResolveReference(u).String()
+}
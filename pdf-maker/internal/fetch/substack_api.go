@@ -0,0 +1,144 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	art "pdf-maker/internal/article"
+)
+
+// substackPost is the part of Substack's /api/v1/posts/<slug> response that
+// maps onto an Article.
+type substackPost struct {
+	Title        string `json:"title"`
+	Subtitle     string `json:"subtitle"`
+	Description  string `json:"description"`
+	BodyHTML     string `json:"body_html"`
+	PostDate     string `json:"post_date"`
+	CanonicalURL string `json:"canonical_url"`
+	CoverImage   string `json:"cover_image"`
+	Bylines      []struct {
+		Name     string `json:"name"`
+		PhotoURL string `json:"photo_url"`
+
+		// The publications the author writes for; the post's own is among them
+		PublicationUsers []struct {
+			Publication struct {
+				Name      string `json:"name"`
+				Subdomain string `json:"subdomain"`
+			} `json:"publication"`
+		} `json:"publicationUsers"`
+	} `json:"publishedBylines"`
+}
+
+// FetchSubstackAPI fetches a Substack post through the publication's JSON
+// post API, which is sturdier than scraping the page. postURL may be the
+// post's web URL (…/p/<slug>) or the API URL itself. The Article is
+// processed like a scraped one (cleaning, excerpt, language).
+func FetchSubstackAPI(ctx context.Context, postURL string) (*art.Article, []byte, error) {
+	return FetchArticleWithOptions(ctx, postURL, FetchOptions{PreferAPI: true})
+}
+
+// substackAPIURL maps a Substack post URL, https://<name>.substack.com/p/<slug>,
+// to its API URL, https://<name>.substack.com/api/v1/posts/<slug>. API URLs
+// are returned as is. ok is false for anything else, including posts on
+// custom domains: /p/ paths there are not necessarily Substack's, so those
+// pages are scraped.
+func substackAPIURL(postURL string) (string, bool) {
+	u, err := url.Parse(postURL)
+	if err != nil || !isSubstackHost(u.Hostname()) {
+		return "", false
+	}
+	if strings.HasPrefix(u.Path, "/api/v1/posts/") {
+		return postURL, true
+	}
+	slug, found := strings.CutPrefix(u.Path, "/p/")
+	slug = strings.Trim(slug, "/")
+	if !found || slug == "" || strings.Contains(slug, "/") {
+		return "", false
+	}
+	return u.Scheme + "://" + u.Host + "/api/v1/posts/" + url.PathEscape(slug), true
+}
+
+// isSubstackHost reports whether host is substack.com or one of its
+// publication subdomains.
+func isSubstackHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "substack.com" || strings.HasSuffix(host, ".substack.com")
+}
+
+// isJSONContentType reports whether a Content-Type header names JSON.
+func isJSONContentType(header string) bool {
+	mt, _, err := mime.ParseMediaType(header)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+// articleFromSubstackJSON maps a Substack post API response onto an Article.
+// The description becomes the Excerpt; the body is left for finishArticle
+// to clean.
+func articleFromSubstackJSON(raw []byte, pageURL string) (*art.Article, error) {
+	var p substackPost
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("decode substack post: %w", err)
+	}
+	if strings.TrimSpace(p.BodyHTML) == "" {
+		return nil, fmt.Errorf("substack post has no body_html")
+	}
+	a := &art.Article{
+		Link:         pageURL,
+		Title:        strings.TrimSpace(p.Title),
+		Subtitle:     strings.TrimSpace(p.Subtitle),
+		Excerpt:      strings.Join(strings.Fields(p.Description), " "),
+		Content:      p.BodyHTML,
+		CanonicalURL: p.CanonicalURL,
+		CoverImage:   p.CoverImage,
+	}
+	for _, b := range p.Bylines {
+		if name := normalizeName(b.Name); name != "" {
+			a.Authors = append(a.Authors, name)
+		}
+	}
+	a.Author = art.JoinAuthors(a.Authors)
+	if len(p.Bylines) > 0 {
+		a.AuthorAvatar = p.Bylines[0].PhotoURL
+	}
+	if t, err := time.Parse(time.RFC3339, p.PostDate); err == nil {
+		a.PubDate = t
+	}
+	link := a.CanonicalURL
+	if link == "" {
+		link = pageURL
+	}
+	u, _ := url.Parse(link)
+	sub := ""
+	if u != nil {
+		sub = strings.Split(strings.TrimPrefix(u.Hostname(), "www."), ".")[0]
+	}
+	a.Publication = normalizePublication(substackPublicationName(p, sub))
+	return a, nil
+}
+
+// substackPublicationName returns the name of the post's publication, the
+// one with the given subdomain among its authors' publications, else the
+// subdomain capitalized.
+func substackPublicationName(p substackPost, subdomain string) string {
+	for _, b := range p.Bylines {
+		for _, pu := range b.PublicationUsers {
+			if name := strings.TrimSpace(pu.Publication.Name); name != "" && strings.EqualFold(pu.Publication.Subdomain, subdomain) {
+				return name
+			}
+		}
+	}
+	r, size := utf8.DecodeRuneInString(subdomain)
+	if r == utf8.RuneError {
+		return subdomain
+	}
+	return string(unicode.ToUpper(r)) + subdomain[size:]
+}
@@ -0,0 +1,101 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"pdf-maker/internal/netguard"
+)
+
+func TestSubstackAPIURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"https://example.substack.com/p/my-post", "https://example.substack.com/api/v1/posts/my-post", true},
+		{"https://Example.Substack.com/p/my-post/", "https://Example.Substack.com/api/v1/posts/my-post", true},
+		{"https://example.substack.com/api/v1/posts/my-post", "https://example.substack.com/api/v1/posts/my-post", true},
+		{"https://example.substack.com/archive", "", false},
+		{"https://example.substack.com/p/a/b", "", false},
+		{"https://www.platformer.news/p/my-post", "", false}, // custom domain: maybe not Substack
+		{"https://notsubstack.com/p/my-post", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := substackAPIURL(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("substackAPIURL(%q) = %q, %t; want %q, %t", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// rewriteHost sends every request to the test server, keeping its path.
+type rewriteHost struct{ target *url.URL }
+
+func (r rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = r.target.Scheme, r.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFetchSubstackAPI(t *testing.T) {
+	const page = `<html><head><title>Scraped</title></head><body><h1 class="post-title published">Scraped title</h1>` +
+		`<div class="available-content"><div class="body markup"><p>Scraped body.</p></div></div></body></html>`
+	const post = `{"title": "API title", "description": "From the API.", "body_html": "<p>API body.</p>",
+		"publishedBylines": [{"name": "Ann Author", "publicationUsers": [
+			{"publication": {"name": "Other Letter", "subdomain": "other"}},
+			{"publication": {"name": "The Example Letter", "subdomain": "example"}}]}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/posts/known":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(post))
+		case "/api/v1/posts/missing":
+			http.NotFound(w, r)
+		case "/api/v1/posts/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>Not an API</body></html>"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+		}
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	opts := FetchOptions{
+		PreferAPI: true,
+		Client:    &http.Client{Transport: rewriteHost{target}},
+		Network:   &netguard.Policy{AllowPrivate: true},
+	}
+
+	tests := []struct{ slug, title, publication string }{
+		{"known", "API title", "The Example Letter"},
+		{"missing", "Scraped title", ""},
+		{"html", "Scraped title", ""},
+	}
+	for _, tt := range tests {
+		a, _, err := FetchArticleWithOptions(context.Background(), "https://example.substack.com/p/"+tt.slug, opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.slug, err)
+			continue
+		}
+		if a.Title != tt.title {
+			t.Errorf("%s: Title = %q, want %q", tt.slug, a.Title, tt.title)
+		}
+		if tt.publication != "" && a.Publication != tt.publication {
+			t.Errorf("%s: Publication = %q, want %q", tt.slug, a.Publication, tt.publication)
+		}
+	}
+}
+
+func TestSubstackPublicationName(t *testing.T) {
+	if got := substackPublicationName(substackPost{}, "example"); got != "Example" {
+		t.Errorf("no bylines: got %q, want %q", got, "Example")
+	}
+	if got := substackPublicationName(substackPost{}, "ñandu"); got != "Ñandu" {
+		t.Errorf("non-ASCII subdomain: got %q, want %q", got, "Ñandu")
+	}
+}
@@ -0,0 +1,81 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	art "pdf-maker/internal/article"
+)
+
+// ThinContentPolicy re-fetches an article once when a successful fetch
+// extracted suspiciously little text, e.g. because the page had not finished
+// rendering server-side. The better of the two results is kept; a thin
+// article is never turned into an error.
+type ThinContentPolicy struct {
+	Enabled bool
+
+	// MinWords is the body length below which content is always considered
+	// thin (default 80).
+	MinWords int
+
+	// ExcerptRatio flags content shorter than ExcerptRatio × the word count of
+	// the article's excerpt (its og:description / meta description), since a
+	// full post is normally many times longer than its summary (default 3).
+	// Not applied when the excerpt was taken from the body itself.
+	ExcerptRatio float64
+}
+
+// withDefaults fills zero-valued fields.
+func (p ThinContentPolicy) withDefaults() ThinContentPolicy {
+	if p.MinWords <= 0 {
+		p.MinWords = 80
+	}
+	if p.ExcerptRatio <= 0 {
+		p.ExcerptRatio = 3
+	}
+	return p
+}
+
+// isThinContent reports whether a's body is short enough to suspect
+// under-extraction, along with its word count.
+func isThinContent(a *art.Article, p ThinContentPolicy) (bool, int) {
+	words := a.WordCount()
+	if words < p.MinWords {
+		return true, words
+	}
+	if a.ExcerptFromBody {
+		return false, words // measuring the body against its own opening proves nothing
+	}
+	excerpt := len(strings.Fields(strings.TrimSuffix(a.Excerpt, "…")))
+	return float64(words) < p.ExcerptRatio*float64(excerpt), words
+}
+
+// refetchThinContent applies the ThinContentPolicy to a fetched article: when
+// it looks thin, the page is fetched once more after delay and whichever
+// result has more text is returned.
+func refetchThinContent(ctx context.Context, pageURL string, opts FetchOptions, a *art.Article, raw []byte, delay time.Duration) (*art.Article, []byte) {
+	if !opts.ThinContent.Enabled {
+		return a, raw
+	}
+	thin, words := isThinContent(a, opts.ThinContent.withDefaults())
+	if !thin {
+		return a, raw
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return a, raw
+	}
+	fmt.Fprintf(os.Stderr, "Content for %s looks incomplete (%d words); re-fetching once\n", pageURL, words)
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return a, raw
+	}
+	again, againRaw, err := FetchArticleWithOptions(ctx, pageURL, opts)
+	if err != nil || again.WordCount() <= words {
+		return a, raw
+	}
+	return again, againRaw
+}
@@ -0,0 +1,44 @@
+package fetch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestIsThinContent(t *testing.T) {
+	body := func(n int) string { return "<p>" + strings.Repeat("word ", n) + "</p>" }
+	excerpt := strings.TrimSpace(strings.Repeat("teaser ", 50))
+	p := ThinContentPolicy{}.withDefaults()
+	tests := []struct {
+		name string
+		a    *art.Article
+		want bool
+	}{
+		{"below MinWords", &art.Article{Content: body(40)}, true},
+		{"long enough, no excerpt", &art.Article{Content: body(120)}, false},
+		{"short next to its description", &art.Article{Content: body(120), Excerpt: excerpt}, true},
+		{"long next to its description", &art.Article{Content: body(200), Excerpt: excerpt}, false},
+		{"excerpt taken from the body", &art.Article{Content: body(120), Excerpt: excerpt, ExcerptFromBody: true}, false},
+	}
+	for _, tt := range tests {
+		if got, _ := isThinContent(tt.a, p); got != tt.want {
+			t.Errorf("%s: isThinContent = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFinishArticleExcerptSource(t *testing.T) {
+	a := &art.Article{Content: "<p>The opening sentence. And more after it.</p>"}
+	finishArticle(context.Background(), a, strategyPrimary, "en", "", FetchOptions{}, "https://example.com/p/x", "https://example.com/p/x")
+	if !a.ExcerptFromBody || !strings.HasPrefix(a.Excerpt, "The opening sentence.") {
+		t.Errorf("no description: Excerpt = %q, ExcerptFromBody = %t", a.Excerpt, a.ExcerptFromBody)
+	}
+	a = &art.Article{Content: "<p>The opening sentence.</p>"}
+	finishArticle(context.Background(), a, strategyPrimary, "en", "A summary.", FetchOptions{}, "https://example.com/p/x", "https://example.com/p/x")
+	if a.ExcerptFromBody || a.Excerpt != "A summary." {
+		t.Errorf("with description: Excerpt = %q, ExcerptFromBody = %t", a.Excerpt, a.ExcerptFromBody)
+	}
+}
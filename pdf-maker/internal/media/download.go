@@ -1,23 +1,37 @@
 package media
 
 import (
+	"bufio"
+	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/sync/errgroup"
+	"pdf-maker/internal/clean"
+	"pdf-maker/internal/netguard"
+	"pdf-maker/internal/useragent"
 )
 
 // Downloader manages image downloading with configurable options.
 type Downloader struct {
 	opts      DownloadOptions
 	imagesDir string
+
+	mu     sync.Mutex
+	totals DownloadStats // accumulated over every ProcessHTML call
 }
 
 // NewDownloader creates a new image downloader with the given directory.
@@ -56,6 +70,16 @@ func NewDownloaderWithOptions(opts DownloadOptions) (*Downloader, error) {
 		opts.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
 	}
 
+	if err := ValidateOptimizeCommand(opts.OptimizeCommand); err != nil {
+		return nil, err
+	}
+	if len(opts.OptimizeCommand) > 0 {
+		if _, err := exec.LookPath(opts.OptimizeCommand[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: image optimizer %q not found; images will not be optimized\n", opts.OptimizeCommand[0])
+			opts.OptimizeCommand = nil
+		}
+	}
+
 	// Create images directory
 	if err := os.MkdirAll(opts.ImagesDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create images dir: %w", err)
@@ -73,11 +97,44 @@ func (d *Downloader) SetVerbose(verbose bool) {
 }
 
 // ProcessHTML is a convenience method that downloads images from HTML content.
-func (d *Downloader) ProcessHTML(htmlContent string) (string, error) {
-	modifiedHTML, _, err := DownloadAndCacheImages(htmlContent, d.opts)
+func (d *Downloader) ProcessHTML(ctx context.Context, htmlContent string) (string, error) {
+	modifiedHTML, stats, err := DownloadAndCacheImages(ctx, htmlContent, d.opts)
+	d.mu.Lock()
+	d.totals.Add(stats)
+	d.mu.Unlock()
 	return modifiedHTML, err
 }
 
+// ProcessArticleHTML is ProcessHTML for the article at 1-based position
+// article in the issue, whose page is at baseURL (may be empty); with
+// IndexedFilenames the saved images are named after it, and relative image
+// URLs are resolved against baseURL (see DownloadOptions.BaseURL).
+func (d *Downloader) ProcessArticleHTML(ctx context.Context, htmlContent string, article int, baseURL string) (string, error) {
+	opts := d.opts
+	opts.ArticleIndex = article
+	opts.BaseURL = baseURL
+	modifiedHTML, stats, err := DownloadAndCacheImages(ctx, htmlContent, opts)
+	d.mu.Lock()
+	d.totals.Add(stats)
+	d.mu.Unlock()
+	return modifiedHTML, err
+}
+
+// Totals returns the image statistics accumulated over every ProcessHTML
+// call on this downloader. Safe for concurrent use.
+func (d *Downloader) Totals() DownloadStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.totals
+	t.FailedURLs = append([]string(nil), d.totals.FailedURLs...)
+	return t
+}
+
+// ImagesDir returns the directory images are saved into.
+func (d *Downloader) ImagesDir() string {
+	return d.imagesDir
+}
+
 // Cleanup removes all downloaded images in the images directory.
 func (d *Downloader) Cleanup() error {
 	return os.RemoveAll(d.imagesDir)
@@ -90,6 +147,25 @@ type DownloadStats struct {
 	Cached      int
 	Failed      int
 	FailedURLs  []string // URLs that failed to download
+	Oversized   int      // images skipped for exceeding MaxImageBytes (not counted in Failed)
+	NotImage    int      // responses rejected as non-image content, e.g. HTML error pages (not counted in Failed)
+	Resized     int      // downloaded images shrunk or re-encoded per MaxImageWidth/JPEGQuality
+	Optimized   int      // downloaded images replaced by smaller OptimizeCommand output
+	BytesSaved  int64    // bytes saved by OptimizeCommand across Optimized images
+}
+
+// Add accumulates o's counts and failed URLs into s.
+func (s *DownloadStats) Add(o DownloadStats) {
+	s.TotalImages += o.TotalImages
+	s.Downloaded += o.Downloaded
+	s.Cached += o.Cached
+	s.Failed += o.Failed
+	s.FailedURLs = append(s.FailedURLs, o.FailedURLs...)
+	s.Oversized += o.Oversized
+	s.NotImage += o.NotImage
+	s.Resized += o.Resized
+	s.Optimized += o.Optimized
+	s.BytesSaved += o.BytesSaved
 }
 
 // DownloadOptions configures image downloading behavior.
@@ -98,6 +174,78 @@ type DownloadOptions struct {
 	Timeout   time.Duration // HTTP timeout per image (default: 10s)
 	UserAgent string        // Custom User-Agent header
 	Verbose   bool          // Enable verbose logging
+
+	// UserAgents, when set, rotates the User-Agent per request (or per
+	// host) instead of always sending UserAgent.
+	UserAgents *useragent.Rotator
+
+	// Client performs the image requests (default: a client with Timeout
+	// that enforces Network). Tests inject one backed by httptest.
+	Client *http.Client
+	// Network guards image requests (see netguard). nil refuses private,
+	// loopback and link-local addresses and allows every public host.
+	Network *netguard.Policy
+
+	// IndexedFilenames prefixes image filenames with the article position
+	// and the image's ordinal within the article, e.g. "a03-img05-<hash>.png",
+	// so the images directory can be matched to the PDF when debugging.
+	// Needs ArticleIndex; without it names stay plain URL hashes.
+	IndexedFilenames bool
+	// ArticleIndex is the 1-based position of the article being processed,
+	// normally set per article through ProcessArticleHTML.
+	ArticleIndex int
+	// BaseURL is the address of the page the HTML came from, normally set
+	// per article through ProcessArticleHTML: the fetcher passes the page's
+	// <base href> or its URL after redirects. Relative image URLs
+	// ("/content/images/a.jpg") are resolved against it and protocol-relative
+	// ones ("//cdn.example.com/a.jpg") take its scheme, or https without it.
+	BaseURL string
+
+	// MaxImageBytes skips any image larger than this many bytes (0 = unlimited).
+	// The download is aborted as soon as the limit is exceeded.
+	MaxImageBytes int64
+
+	// MaxImageWidth downscales downloaded JPEG and PNG images wider than this
+	// many pixels, preserving aspect ratio (0 = keep original size).
+	MaxImageWidth int
+	// JPEGQuality re-encodes downloaded JPEGs at this quality, 1-100
+	// (0 = keep the original encoding unless the image is downscaled).
+	JPEGQuality int
+	// Grayscale converts downloaded JPEG and PNG images to grayscale with a
+	// contrast stretch, for cheap black-and-white printing. Already-gray
+	// images, GIFs and formats the standard library cannot decode (WebP,
+	// SVG) pass through unchanged.
+	Grayscale bool
+	// OptimizeCommand, when set, is an argv template run on every newly
+	// downloaded JPEG and PNG image after resizing, e.g. {"pngquant",
+	// "--force", "--ext", ".png", "{file}"}; the image is replaced by the
+	// command's output when that is smaller (see optimizeImage for {file}
+	// and {out}). Other formats, SVG and GIF among them, are left alone. A
+	// program missing from PATH is skipped with a warning.
+	OptimizeCommand []string
+
+	// Workers is the number of one page's images downloaded concurrently
+	// (default 1: one after another). Pages are themselves fetched
+	// FetchOptions.MaxParallel at a time, each downloading its own images,
+	// so up to MaxParallel × Workers image requests can be in flight; use
+	// netguard.Policy.MaxRequests on Network to bound the total.
+	Workers int
+
+	// MaxAttempts is the total tries per image when the server answers 429
+	// or 503 (default 1: no retries). Retry-After is honoured when present.
+	MaxAttempts int
+	// MaxRetryWait caps a single rate-limit wait (default 30s); a longer
+	// Retry-After fails the image instead of stalling the run.
+	MaxRetryWait time.Duration
+}
+
+// httpClient returns opts.Client, or a new client with opts.Timeout that
+// enforces opts.Network.
+func (opts DownloadOptions) httpClient() *http.Client {
+	if opts.Client != nil {
+		return opts.Client
+	}
+	return opts.Network.Client(opts.Timeout)
 }
 
 // DownloadAndCacheImages downloads images from HTML content and replaces URLs with local file paths.
@@ -106,7 +254,7 @@ type DownloadOptions struct {
 // 2. Downloads images that aren't already cached
 // 3. Replaces src attributes with local file paths
 // 4. Returns modified HTML with local image references
-func DownloadAndCacheImages(htmlContent string, opts DownloadOptions) (string, DownloadStats, error) {
+func DownloadAndCacheImages(ctx context.Context, htmlContent string, opts DownloadOptions) (string, DownloadStats, error) {
 	stats := DownloadStats{}
 
 	// Set defaults
@@ -135,6 +283,9 @@ func DownloadAndCacheImages(htmlContent string, opts DownloadOptions) (string, D
 			fmt.Println("  - No images found in content")
 		}
 		html, _ := doc.Find("body").Html()
+		if !clean.HasBodyTag(htmlContent) {
+			html = strings.TrimSpace(html)
+		}
 		return html, stats, nil
 	}
 
@@ -147,50 +298,80 @@ func DownloadAndCacheImages(htmlContent string, opts DownloadOptions) (string, D
 		return "", stats, fmt.Errorf("create images dir: %w", err)
 	}
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: opts.Timeout,
+	// Work out each image's source and cache path, then download the
+	// uncached ones (Workers at a time) before rewriting the document, so
+	// that concurrent downloads never touch the DOM
+	type imageRef struct {
+		img                      *goquery.Selection
+		src, localPath, filename string
 	}
-
-	// Process each image
+	var refs []imageRef
+	outcomes := make(map[string]*imageOutcome) // cache path → download result
+	var pending []string                       // cache paths to download, in document order
 	images.Each(func(i int, img *goquery.Selection) {
-		src, exists := img.Attr("src")
-		if !exists || src == "" {
+		// Prefer data-src/srcset when src is a lazy-load placeholder
+		src := resolveImageURL(clean.ImageSource(img), opts.BaseURL)
+		if src == "" {
 			return
 		}
 
-		// Generate unique filename based on URL hash
-		urlHash := fmt.Sprintf("%x", md5.Sum([]byte(src)))
-
-		// Get file extension from URL
-		ext := getImageExtension(src)
-		filename := fmt.Sprintf("%s.%s", urlHash, ext)
-		localPath := filepath.Join(opts.ImagesDir, filename)
-
-		// Check if image already exists (cached)
-		if _, err := os.Stat(localPath); err == nil {
-			if opts.Verbose {
-				fmt.Printf("  - Using cached image: %s\n", filename)
-			}
-			img.SetAttr("src", localPath)
-			// Remove srcset to prevent browser/wkhtmltopdf from using remote URLs
-			img.RemoveAttr("srcset")
-			// Also remove srcset from parent picture/source elements
-			img.Parent().Find("source").RemoveAttr("srcset")
-			stats.Cached++
+		localPath, filename := localImagePath(src, opts.ImagesDir)
+		if opts.IndexedFilenames && opts.ArticleIndex > 0 {
+			filename = fmt.Sprintf("a%02d-img%02d-%s", opts.ArticleIndex, i+1, filename)
+			localPath = filepath.Join(opts.ImagesDir, filename)
+		}
+		refs = append(refs, imageRef{img: img, src: src, localPath: localPath, filename: filename})
+		if _, err := os.Stat(localPath); err == nil || outcomes[localPath] != nil {
 			return
 		}
+		outcomes[localPath] = &imageOutcome{src: src}
+		pending = append(pending, localPath)
+	})
+	fetchImages(ctx, pending, outcomes, opts)
 
-		// Download the image
-		if opts.Verbose {
-			truncatedSrc := src
-			if len(src) > 60 {
-				truncatedSrc = src[:60] + "..."
+	// Process each image
+	for _, ref := range refs {
+		img, src, localPath, filename := ref.img, ref.src, ref.localPath, ref.filename
+
+		// Check if image already exists (cached, or downloaded above for an
+		// earlier <img> with the same source)
+		out := outcomes[localPath]
+		if out == nil || out.claimed {
+			if _, err := os.Stat(localPath); err == nil {
+				if opts.Verbose {
+					fmt.Printf("  - Using cached image: %s\n", filename)
+				}
+				img.SetAttr("src", localPath)
+				// Remove srcset to prevent browser/wkhtmltopdf from using remote URLs
+				img.RemoveAttr("srcset")
+				// Also remove srcset from parent picture/source elements
+				img.Parent().Find("source").RemoveAttr("srcset")
+				stats.Cached++
+				continue
 			}
-			fmt.Printf("  - Downloading: %s\n", truncatedSrc)
 		}
+		if out == nil { // removed since it was found cached
+			out = &imageOutcome{src: src, err: fmt.Errorf("cached image %s disappeared", filename)}
+		}
+		out.claimed = true
 
-		if err := downloadImage(client, src, localPath, opts.UserAgent); err != nil {
+		if err := out.err; err != nil {
+			if errors.Is(err, errImageTooLarge) {
+				stats.Oversized++
+				if opts.Verbose {
+					fmt.Printf("    ⚠️  Skipped oversized image (limit %d bytes)\n", opts.MaxImageBytes)
+				}
+				img.Remove()
+				continue
+			}
+			if errors.Is(err, errNotImage) {
+				stats.NotImage++
+				if opts.Verbose {
+					fmt.Printf("    ⚠️  Skipped non-image response (%v)\n", err)
+				}
+				img.Remove()
+				continue
+			}
 			stats.Failed++
 			stats.FailedURLs = append(stats.FailedURLs, src)
 			if opts.Verbose {
@@ -202,7 +383,23 @@ func DownloadAndCacheImages(htmlContent string, opts DownloadOptions) (string, D
 			}
 			// Remove the img tag on failure
 			img.Remove()
-			return
+			continue
+		}
+
+		if out.resizeErr != nil {
+			if opts.Verbose {
+				fmt.Printf("    ⚠️  Could not resize image, keeping original: %v\n", out.resizeErr)
+			}
+		} else if out.resized {
+			stats.Resized++
+		}
+		if out.optimizeErr != nil {
+			if opts.Verbose {
+				fmt.Printf("    ⚠️  Could not optimize image, keeping it: %v\n", out.optimizeErr)
+			}
+		} else if out.saved > 0 {
+			stats.Optimized++
+			stats.BytesSaved += out.saved
 		}
 
 		// Update img src to local path
@@ -216,12 +413,24 @@ func DownloadAndCacheImages(htmlContent string, opts DownloadOptions) (string, D
 		if opts.Verbose {
 			fmt.Printf("    ✅ Saved as: %s\n", filename)
 		}
-	})
+	}
 
 	if opts.Verbose {
 		fmt.Printf("  - Downloaded: %d images\n", stats.Downloaded)
 		fmt.Printf("  - Cached: %d images\n", stats.Cached)
 		fmt.Printf("  - Failed: %d images\n", stats.Failed)
+		if stats.Oversized > 0 {
+			fmt.Printf("  - Oversized (skipped): %d images\n", stats.Oversized)
+		}
+		if stats.NotImage > 0 {
+			fmt.Printf("  - Not an image (skipped): %d responses\n", stats.NotImage)
+		}
+		if stats.Resized > 0 {
+			fmt.Printf("  - Resized/re-encoded: %d images\n", stats.Resized)
+		}
+		if stats.Optimized > 0 {
+			fmt.Printf("  - Optimized: %d images (%d bytes saved)\n", stats.Optimized, stats.BytesSaved)
+		}
 		fmt.Printf("  - Total processed: %d images\n", stats.TotalImages)
 	}
 
@@ -232,17 +441,154 @@ func DownloadAndCacheImages(htmlContent string, opts DownloadOptions) (string, D
 	}
 
 	// If original content was a fragment (no body tag), extract just the body content
-	if !strings.Contains(htmlContent, "<body") {
+	if !clean.HasBodyTag(htmlContent) {
 		html = strings.TrimSpace(html)
 	}
 
 	return html, stats, nil
 }
 
+// imageOutcome is the result of downloading one image for
+// DownloadAndCacheImages.
+type imageOutcome struct {
+	src         string
+	err         error // download failed; the image is dropped
+	resized     bool
+	resizeErr   error
+	saved       int64 // bytes saved by OptimizeCommand
+	optimizeErr error
+	claimed     bool // taken by an <img>; later ones with the same file count as cached
+}
+
+// fetchImages downloads, resizes and optimizes the image for each cache
+// path in pending, opts.Workers at a time (one at a time when Workers is
+// below 2), filling in outcomes. A missing optimizer is reported once and
+// then skipped for the rest of the batch.
+func fetchImages(ctx context.Context, pending []string, outcomes map[string]*imageOutcome, opts DownloadOptions) {
+	if len(pending) == 0 {
+		return
+	}
+	client := opts.httpClient()
+	var noOptimizer atomic.Bool
+	fetchOne := func(localPath string) {
+		out := outcomes[localPath]
+		if opts.Verbose {
+			truncatedSrc := out.src
+			if len(out.src) > 60 {
+				truncatedSrc = out.src[:60] + "..."
+			}
+			fmt.Printf("  - Downloading: %s\n", truncatedSrc)
+		}
+		if out.err = downloadWithRetry(ctx, client, out.src, localPath, opts); out.err != nil {
+			return
+		}
+		out.resized, out.resizeErr = shrinkImage(localPath, opts)
+		if noOptimizer.Load() {
+			return
+		}
+		out.saved, out.optimizeErr = optimizeImage(localPath, opts)
+		if errors.Is(out.optimizeErr, errOptimizerMissing) && noOptimizer.Swap(true) {
+			out.optimizeErr = nil // already reported for this batch
+		}
+	}
+
+	if opts.Workers < 2 {
+		for _, localPath := range pending {
+			fetchOne(localPath)
+		}
+		return
+	}
+	var g errgroup.Group
+	g.SetLimit(opts.Workers)
+	for _, localPath := range pending {
+		g.Go(func() error {
+			fetchOne(localPath)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// resolveImageURL makes an image src absolute against the page at base:
+// a protocol-relative src takes base's scheme (https when base has none),
+// and a relative one resolves against base. src is returned unchanged when
+// it is already absolute, a data: URI, or cannot be resolved.
+func resolveImageURL(src, base string) string {
+	src = strings.TrimSpace(src)
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return src
+	}
+	ref, err := url.Parse(src)
+	if err != nil || ref.IsAbs() {
+		return src
+	}
+	b, err := url.Parse(strings.TrimSpace(base))
+	if err != nil || !b.IsAbs() {
+		if strings.HasPrefix(src, "//") {
+			return "https:" + src
+		}
+		return src
+	}
+	return b.ResolveReference(ref).String()
+}
+
+// localImagePath returns the cache path and filename for an image URL: the MD5
+// of the URL plus the URL's image extension, inside imagesDir.
+func localImagePath(src, imagesDir string) (string, string) {
+	urlHash := fmt.Sprintf("%x", md5.Sum([]byte(src)))
+	filename := fmt.Sprintf("%s.%s", urlHash, getImageExtension(src))
+	return filepath.Join(imagesDir, filename), filename
+}
+
+// DownloadImage downloads a single image URL into the images directory (or
+// reuses the cached copy) and returns its local path. Used for images that
+// live outside the article body, such as author avatars.
+func (d *Downloader) DownloadImage(ctx context.Context, src string) (string, error) {
+	if strings.TrimSpace(src) == "" {
+		return "", fmt.Errorf("empty image url")
+	}
+	localPath, _ := localImagePath(src, d.opts.ImagesDir)
+	if _, err := os.Stat(localPath); err == nil {
+		return localPath, nil
+	}
+	if err := os.MkdirAll(d.opts.ImagesDir, 0o755); err != nil {
+		return "", fmt.Errorf("create images dir: %w", err)
+	}
+	client := d.opts.httpClient()
+	if err := downloadWithRetry(ctx, client, src, localPath, d.opts); err != nil {
+		return "", err
+	}
+	// A failed resize keeps the original, which is still a usable image
+	shrinkImage(localPath, d.opts)
+	return localPath, nil
+}
+
+// errImageTooLarge is returned by downloadImage when an image exceeds MaxImageBytes.
+var errImageTooLarge = errors.New("image exceeds size limit")
+
+// errNotImage is returned by downloadImage when the server answers with
+// something other than an image, typically an HTML error page served with
+// status 200.
+var errNotImage = errors.New("response is not an image")
+
+// checkImageContentType rejects a response whose Content-Type header or
+// sniffed leading bytes (http.DetectContentType) identify non-image content.
+// Generic binary types pass; validateImageFile checks those after saving.
+func checkImageContentType(header string, head []byte) error {
+	if mt := strings.ToLower(strings.TrimSpace(strings.Split(header, ";")[0])); mt != "" &&
+		!strings.HasPrefix(mt, "image/") && mt != "application/octet-stream" && mt != "binary/octet-stream" {
+		return fmt.Errorf("%w: content-type %s", errNotImage, mt)
+	}
+	if sniffed := http.DetectContentType(head); !strings.HasPrefix(sniffed, "image/") && !strings.HasPrefix(sniffed, "application/octet-stream") {
+		return fmt.Errorf("%w: sniffed %s", errNotImage, sniffed)
+	}
+	return nil
+}
+
 // downloadImage downloads an image from a URL and saves it to a local file.
-func downloadImage(client *http.Client, imageURL, localPath, userAgent string) error {
+func downloadImage(ctx context.Context, client *http.Client, imageURL, localPath, userAgent string, maxBytes int64) error {
 	// Create HTTP request
-	req, err := http.NewRequest("GET", imageURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
@@ -258,9 +604,27 @@ func downloadImage(client *http.Client, imageURL, localPath, userAgent string) e
 	defer resp.Body.Close()
 
 	// Check status code
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		wait, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return &rateLimitedError{status: resp.StatusCode, wait: wait}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("http status %d", resp.StatusCode)
 	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return errImageTooLarge
+	}
+
+	// Sniff the first 512 bytes before creating the file, so an error page
+	// is never saved under an image name
+	br := bufio.NewReaderSize(resp.Body, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if err := checkImageContentType(resp.Header.Get("Content-Type"), head); err != nil {
+		return err
+	}
 
 	// Create output file
 	outFile, err := os.Create(localPath)
@@ -269,13 +633,23 @@ func downloadImage(client *http.Client, imageURL, localPath, userAgent string) e
 	}
 	defer outFile.Close()
 
-	// Stream image data to file in chunks
-	_, err = io.Copy(outFile, resp.Body)
+	// Stream image data to file in chunks, reading one byte past the limit
+	// so an oversized image can be detected
+	var body io.Reader = br
+	if maxBytes > 0 {
+		body = &io.LimitedReader{R: br, N: maxBytes + 1}
+	}
+	n, err := io.Copy(outFile, body)
 	if err != nil {
 		// Clean up partial file on error
 		os.Remove(localPath)
 		return fmt.Errorf("write file: %w", err)
 	}
+	if maxBytes > 0 && n > maxBytes {
+		outFile.Close()
+		os.Remove(localPath)
+		return errImageTooLarge
+	}
 
 	// Close before reading for validation
 	outFile.Close()
@@ -321,35 +695,32 @@ func validateImageFile(path string) error {
 	return fmt.Errorf("unrecognised image format (header bytes: %d %d %d %d)", b[0], b[1], b[2], b[3])
 }
 
+// validImageExts lists the extensions getImageExtension will return.
+var validImageExts = map[string]bool{
+	"jpg":  true,
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+	"svg":  true,
+}
+
 // getImageExtension extracts the file extension from an image URL.
+// The extension is taken from the final element of the percent-decoded path
+// only, so dots in directory names ("/file.name.v2/image"), query strings and
+// fragments are ignored, and encoded paths (e.g. Substack CDN URLs that embed
+// the original URL as "https%3A%2F%2F...%2Fphoto.png") resolve correctly.
 // Returns a valid image extension or defaults to "jpg".
 func getImageExtension(imageURL string) string {
-	// Parse URL
-	parsedURL, err := url.Parse(imageURL)
+	parsedURL, err := url.Parse(strings.TrimSpace(imageURL))
 	if err != nil {
 		return "jpg" // Default fallback
 	}
 
-	// Get path and split by dots
-	path := parsedURL.Path
-	parts := strings.Split(path, ".")
-
-	if len(parts) > 1 {
-		ext := strings.ToLower(parts[len(parts)-1])
-
-		// Validate common image extensions
-		validExts := map[string]bool{
-			"jpg":  true,
-			"jpeg": true,
-			"png":  true,
-			"gif":  true,
-			"webp": true,
-			"svg":  true,
-		}
-
-		if validExts[ext] {
-			return ext
-		}
+	// url.Parse has already split off the query/fragment and decoded the path.
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(parsedURL.Path), "."))
+	if validImageExts[ext] {
+		return ext
 	}
 
 	// Default fallback
@@ -0,0 +1,80 @@
+package media
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// A fragment whose text or attributes mention <body> is still a fragment:
+// it comes back trimmed, like any other fragment.
+func TestDownloadAndCacheImagesFragmentMentioningBody(t *testing.T) {
+	in := "\n  <p title=\"<body>\">Close the <code>&lt;body&gt;</code> last.</p>\n"
+	out, _, err := DownloadAndCacheImages(context.Background(), in, DownloadOptions{ImagesDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p title="&lt;body&gt;">Close the <code>&lt;body&gt;</code> last.</p>`; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestGetImageExtension(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"https://example.com/photo.png", "png"},
+		{"https://example.com/PHOTO.JPEG", "jpeg"},
+		{"https://example.com/photo.webp?w=800&format=.gif", "webp"},
+		{"https://example.com/photo.gif#frame.png", "gif"},
+		{"https://example.com/file.name.v2/image", "jpg"},
+		{"https://example.com/releases.v1.2/chart.svg", "svg"},
+		{"https://substackcdn.com/image/fetch/w_1456,c_limit,f_auto/https%3A%2F%2Fbucket.s3.amazonaws.com%2Fpublic%2Fimages%2Fphoto.png", "png"},
+		{"https://example.com/image.php?id=3", "jpg"},
+		{"/relative/path/pic.gif", "gif"},
+		{"https://example.com/no-extension", "jpg"},
+		{"https://example.com/%zz.png", "jpg"}, // malformed escape
+		{"  https://example.com/padded.png  ", "png"},
+	}
+	for _, tt := range tests {
+		if got := getImageExtension(tt.in); got != tt.want {
+			t.Errorf("getImageExtension(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveImageURL(t *testing.T) {
+	const base = "https://example.com/p/post"
+	tests := []struct{ src, base, want string }{
+		{"https://cdn.example.com/a.png", base, "https://cdn.example.com/a.png"},
+		{"//cdn.example.com/a.png", base, "https://cdn.example.com/a.png"},
+		{"//cdn.example.com/a.png", "http://example.com/", "http://cdn.example.com/a.png"},
+		{"//cdn.example.com/a.png", "", "https://cdn.example.com/a.png"},
+		{"/images/a.png", base, "https://example.com/images/a.png"},
+		{"a.png", base, "https://example.com/p/a.png"},
+		{"../a.png", "https://example.com/p/post/", "https://example.com/p/a.png"},
+		{"images/a.png", "", "images/a.png"},
+		{"data:image/png;base64,AAAA", base, "data:image/png;base64,AAAA"},
+		{"  /a.png ", base, "https://example.com/a.png"},
+	}
+	for _, tt := range tests {
+		if got := resolveImageURL(tt.src, tt.base); got != tt.want {
+			t.Errorf("resolveImageURL(%q, %q) = %q, want %q", tt.src, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestLocalImagePath(t *testing.T) {
+	p1, name1 := localImagePath("https://example.com/a/photo.png?w=800", "imgs")
+	p2, name2 := localImagePath("https://example.com/b/photo.png?w=800", "imgs")
+	if name1 == name2 {
+		t.Error("different URLs share a cache file")
+	}
+	if p1 != filepath.Join("imgs", name1) || p2 != filepath.Join("imgs", name2) {
+		t.Errorf("paths %q, %q not inside imgs/", p1, p2)
+	}
+	if len(name1) != 32+len(".png") || name1[32:] != ".png" {
+		t.Errorf("filename %q is not <md5>.png", name1)
+	}
+	if _, again := localImagePath("https://example.com/a/photo.png?w=800", "imgs"); again != name1 {
+		t.Error("the same URL maps to different files")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"pdf-maker/internal/clean"
 )
 
 // FixImagePathsToAbsolute converts relative image paths to absolute file:// URLs.
@@ -47,7 +48,7 @@ func FixImagePathsToAbsolute(htmlContent string, imagesDir string) (string, erro
 	}
 
 	// If original was a full document, get the whole thing
-	if strings.Contains(htmlContent, "<html") {
+	if clean.HasHTMLTag(htmlContent) {
 		html, err = goquery.OuterHtml(doc.Selection)
 		if err != nil {
 			return "", fmt.Errorf("extract full html: %w", err)
@@ -0,0 +1,135 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// optimizeTimeout bounds one run of the OptimizeCommand.
+const optimizeTimeout = 60 * time.Second
+
+// errOptimizerMissing is returned when the OptimizeCommand binary is not
+// on PATH; the image is then kept as downloaded.
+var errOptimizerMissing = errors.New("image optimizer not found")
+
+// ValidateOptimizeCommand reports whether argv is a usable OptimizeCommand:
+// empty (off), or a program followed by arguments of which at least one
+// contains the {file} placeholder.
+func ValidateOptimizeCommand(argv []string) error {
+	if len(argv) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(argv[0]) == "" {
+		return errors.New("invalid optimize command: missing program")
+	}
+	for _, arg := range argv[1:] {
+		if strings.Contains(arg, "{file}") {
+			return nil
+		}
+	}
+	return errors.New("invalid optimize command: no argument contains the {file} placeholder")
+}
+
+// optimizeImage runs opts.OptimizeCommand on the saved image at path and
+// replaces the file with the optimized output when that is a valid image
+// and smaller. The command runs on a copy: {file} is replaced by the copy's
+// path and {out}, when present, by a path for the tool to write its output
+// to. Without {out}, non-empty stdout is taken as the output (cwebp -o -),
+// else the copy is assumed to have been optimized in place (pngquant
+// --force --ext .png). Only JPEG and PNG files, by content rather than
+// extension, are handed to the command; SVG, GIF and other formats are
+// kept as downloaded, as the optimizers it is meant for would reject or
+// flatten them. Returns the number of bytes saved.
+func optimizeImage(path string, opts DownloadOptions) (int64, error) {
+	argv := opts.OptimizeCommand
+	if len(argv) == 0 || !isJPEGOrPNG(path) {
+		return 0, nil
+	}
+	bin, err := exec.LookPath(argv[0])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errOptimizerMissing, argv[0])
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	ext := filepath.Ext(path)
+	work, err := os.CreateTemp(filepath.Dir(path), ".optimize-*"+ext)
+	if err != nil {
+		return 0, fmt.Errorf("create optimizer input: %w", err)
+	}
+	in, out := work.Name(), strings.TrimSuffix(work.Name(), ext)+".out"+ext
+	defer os.Remove(in)
+	defer os.Remove(out)
+	_, err = work.Write(orig)
+	if cerr := work.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("write optimizer input: %w", err)
+	}
+
+	usesOut := false
+	args := make([]string, 0, len(argv)-1)
+	for _, a := range argv[1:] {
+		usesOut = usesOut || strings.Contains(a, "{out}")
+		args = append(args, strings.NewReplacer("{file}", in, "{out}", out).Replace(a))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), optimizeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("%s: %w: %s", argv[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	result := in
+	switch {
+	case usesOut:
+		result = out
+	case stdout.Len() > 0:
+		if err := os.WriteFile(out, stdout.Bytes(), 0o644); err != nil {
+			return 0, fmt.Errorf("write optimizer output: %w", err)
+		}
+		result = out
+	}
+	optimized, err := os.Stat(result)
+	if err != nil {
+		return 0, fmt.Errorf("%s produced no output: %w", argv[0], err)
+	}
+	if optimized.Size() == 0 || optimized.Size() >= info.Size() {
+		return 0, nil
+	}
+	if err := validateImageFile(result); err != nil {
+		return 0, fmt.Errorf("%s output: %w", argv[0], err)
+	}
+	if err := os.Rename(result, path); err != nil {
+		return 0, fmt.Errorf("replace image: %w", err)
+	}
+	return info.Size() - optimized.Size(), nil
+}
+
+// isJPEGOrPNG reports whether the file at path decodes as a JPEG or PNG.
+func isJPEGOrPNG(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, format, err := image.DecodeConfig(f)
+	return err == nil && (format == "jpeg" || format == "png")
+}
@@ -0,0 +1,39 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Only JPEG and PNG reach the optimizer, whatever the file is named. The
+// command here would fail on any file it is given.
+func TestOptimizeImageSkipsOtherFormats(t *testing.T) {
+	dir := t.TempDir()
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"drawing.svg": []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="4" height="4"></svg>`),
+		"anim.gif":    []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"),
+		"photo.png":   pngData.Bytes(),
+	}
+	opts := DownloadOptions{OptimizeCommand: []string{"false", "{file}"}}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := optimizeImage(path, opts)
+		if ran := err != nil; ran != (name == "photo.png") {
+			t.Errorf("%s: optimizer ran = %t (err %v)", name, ran, err)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+			t.Errorf("%s: file changed", name)
+		}
+	}
+}
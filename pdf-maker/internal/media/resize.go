@@ -0,0 +1,250 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+
+	_ "image/gif" // register the GIF decoder for DecodeConfig
+)
+
+// ValidateJPEGQuality reports whether q is a usable JPEGQuality value:
+// 0 (keep the original encoding) or 1-100.
+func ValidateJPEGQuality(q int) error {
+	if q < 0 || q > 100 {
+		return fmt.Errorf("invalid JPEG quality %d: expected 1-100 (or 0 to keep the original)", q)
+	}
+	return nil
+}
+
+// shrinkImage downscales the image at path to at most opts.MaxImageWidth
+// pixels wide (preserving aspect ratio), converts it to grayscale when
+// opts.Grayscale is set, and re-encodes JPEGs at opts.JPEGQuality. PNGs stay
+// PNG; GIFs (possibly animated) and formats the standard library cannot
+// decode, such as WebP and SVG, are left untouched, as are images that are
+// already grayscale. A rewrite that would not make the file smaller is
+// discarded unless it converted the image to grayscale. A JPEG that needs
+// no other change is only re-encoded when JPEGQuality is set. Reports
+// whether the file was rewritten.
+func shrinkImage(path string, opts DownloadOptions) (bool, error) {
+	maxWidth, quality := opts.MaxImageWidth, opts.JPEGQuality
+	if maxWidth <= 0 && quality <= 0 && !opts.Grayscale {
+		return false, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil || (format != "jpeg" && format != "png") {
+		f.Close()
+		return false, nil // unsupported or undecodable: keep as-is
+	}
+	needsResize := maxWidth > 0 && cfg.Width > maxWidth
+	needsGray := opts.Grayscale && cfg.ColorModel != color.GrayModel && cfg.ColorModel != color.Gray16Model
+	reencode := format == "jpeg" && quality > 0
+	if !needsResize && !needsGray && !reencode {
+		f.Close()
+		return false, nil
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		f.Close()
+		return false, err
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return false, nil
+	}
+	if needsGray && isGrayImage(img) {
+		needsGray = false
+		if !needsResize && !reencode {
+			return false, nil
+		}
+	}
+
+	if needsResize {
+		h := cfg.Height * maxWidth / cfg.Width
+		if h < 1 {
+			h = 1
+		}
+		img = downscale(img, maxWidth, h)
+	}
+	if needsGray {
+		img = grayscale(img)
+	}
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		q := quality
+		if q <= 0 {
+			q = jpeg.DefaultQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: q})
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	}
+	if err != nil {
+		return false, fmt.Errorf("encode %s: %w", format, err)
+	}
+
+	if info, err := os.Stat(path); err == nil && !needsGray && int64(buf.Len()) >= info.Size() {
+		return false, nil // the rewrite would not shrink the file
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return false, fmt.Errorf("write resized image: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, fmt.Errorf("replace image: %w", err)
+	}
+	return true, nil
+}
+
+// downscale resizes src to w×h with a box filter: each destination pixel is
+// the average of the source pixels it covers. Only used for shrinking.
+func downscale(src image.Image, w, h int) *image.RGBA {
+	rgba := toRGBA(src)
+	sw, sh := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					bl += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// toRGBA returns src as a tightly packed *image.RGBA anchored at the
+// origin, converting only when it is not one already.
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	if rgba, ok := src.(*image.RGBA); ok && b.Min == (image.Point{}) && rgba.Stride == 4*b.Dx() {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	return rgba
+}
+
+// isGrayImage reports whether every pixel of img has equal red, green and
+// blue, i.e. a color-encoded image that is already grayscale.
+func isGrayImage(img image.Image) bool {
+	switch img.(type) {
+	case *image.Gray, *image.Gray16:
+		return true
+	}
+	rgba := toRGBA(img)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		p := rgba.Pix[i : i+3]
+		if p[0] != p[1] || p[1] != p[2] {
+			return false
+		}
+	}
+	return true
+}
+
+// grayClip is the fraction of the darkest and of the lightest pixels that
+// grayscale's contrast stretch clips to black and white.
+const grayClip = 0.005
+
+// grayscale converts src to luminance (Rec. 601 weights) and stretches the
+// result so the darkest and lightest grayClip of pixels map to black and
+// white; washed-out photos otherwise print as flat grey. Opaque images
+// become *image.Gray (one channel, so smaller files); images with
+// transparency keep their alpha as gray RGBA.
+func grayscale(src image.Image) image.Image {
+	rgba := toRGBA(src)
+	n := len(rgba.Pix) / 4
+	lum := make([]uint8, n)
+	var hist [256]int
+	opaque, counted := true, 0
+	for i := 0; i < n; i++ {
+		p := rgba.Pix[i*4 : i*4+4]
+		a := int(p[3])
+		if a == 0 {
+			opaque = false
+			continue
+		}
+		if a != 255 {
+			opaque = false
+		}
+		// Un-premultiply so transparency does not darken the histogram
+		y := (299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])) * 255 / (1000 * a)
+		if y > 255 {
+			y = 255
+		}
+		lum[i] = uint8(y)
+		hist[y]++
+		counted++
+	}
+
+	lo, hi := 0, 255
+	if clip := int(float64(counted) * grayClip); counted > 0 {
+		for sum := 0; lo < 255 && sum+hist[lo] <= clip; lo++ {
+			sum += hist[lo]
+		}
+		for sum := 0; hi > 0 && sum+hist[hi] <= clip; hi-- {
+			sum += hist[hi]
+		}
+	}
+	stretch := func(y uint8) uint8 {
+		if hi-lo < 32 {
+			return y // nearly uniform: stretching would only amplify noise
+		}
+		v := (int(y) - lo) * 255 / (hi - lo)
+		if v < 0 {
+			return 0
+		}
+		if v > 255 {
+			return 255
+		}
+		return uint8(v)
+	}
+
+	b := rgba.Bounds()
+	if opaque {
+		gray := image.NewGray(b)
+		for i, y := range lum {
+			gray.Pix[i] = stretch(y)
+		}
+		return gray
+	}
+	out := image.NewRGBA(b)
+	for i, y := range lum {
+		a := int(rgba.Pix[i*4+3])
+		v := uint8(int(stretch(y)) * a / 255) // re-premultiply
+		d := out.Pix[i*4 : i*4+4]
+		d[0], d[1], d[2], d[3] = v, v, v, uint8(a)
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for retrying rate-limited image downloads.
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultMaxRetryWait   = 30 * time.Second
+)
+
+// rateLimitedError is returned by downloadImage for 429 Too Many Requests and
+// 503 Service Unavailable. wait is the server's Retry-After delay, or 0 when
+// it sent none.
+type rateLimitedError struct {
+	status int
+	wait   time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	if e.wait > 0 {
+		return fmt.Sprintf("http status %d (retry after %s)", e.status, e.wait)
+	}
+	return fmt.Sprintf("http status %d", e.status)
+}
+
+// parseRetryAfter reads a Retry-After header in either of its forms: a
+// number of seconds or an HTTP date. Dates in the past yield 0.
+func parseRetryAfter(h string, now time.Time) (time.Duration, bool) {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(h)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// downloadWithRetry calls downloadImage up to opts.MaxAttempts times,
+// retrying only rate-limited responses. It waits for the server's
+// Retry-After when given, otherwise backs off exponentially from 500ms as
+// article fetching does. A wait longer than opts.MaxRetryWait gives up
+// rather than stalling the run, and cancelling ctx ends a wait early.
+func downloadWithRetry(ctx context.Context, client *http.Client, src, localPath string, opts DownloadOptions) error {
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	maxWait := opts.MaxRetryWait
+	if maxWait <= 0 {
+		maxWait = defaultMaxRetryWait
+	}
+
+	if err := opts.Network.CheckURL(src); err != nil {
+		return err
+	}
+	host := ""
+	if u, err := url.Parse(src); err == nil {
+		host = u.Hostname()
+	}
+
+	delay := defaultRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := downloadImage(ctx, client, src, localPath, opts.UserAgents.Pick(host, opts.UserAgent), opts.MaxImageBytes)
+		var rl *rateLimitedError
+		if err == nil || attempt >= attempts || !errors.As(err, &rl) {
+			return err
+		}
+		wait := delay
+		if rl.wait > 0 {
+			wait = rl.wait
+		}
+		if wait > maxWait {
+			return fmt.Errorf("%w: retry wait %s exceeds limit %s", err, wait, maxWait)
+		}
+		if opts.Verbose {
+			fmt.Printf("    ⏳ Rate limited (status %d), retrying in %s\n", rl.status, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", err, ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
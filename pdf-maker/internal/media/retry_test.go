@@ -0,0 +1,42 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pdf-maker/internal/netguard"
+)
+
+// Cancelling the context ends a Retry-After wait instead of sleeping it out.
+func TestDownloadWithRetryCancelledWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "20")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	opts := DownloadOptions{
+		Client:      srv.Client(),
+		Network:     &netguard.Policy{AllowPrivate: true},
+		MaxAttempts: 3,
+	}
+	start := time.Now()
+	err := downloadWithRetry(ctx, srv.Client(), srv.URL+"/a.png", filepath.Join(t.TempDir(), "a.png"), opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context's deadline", err)
+	}
+	var rl *rateLimitedError
+	if !errors.As(err, &rl) {
+		t.Errorf("err = %v, want it to keep the rate-limit status", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %s, want the wait cut short", elapsed)
+	}
+}
@@ -0,0 +1,65 @@
+package netguard
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// requestSlots returns the semaphore enforcing p.MaxRequests, creating it
+// on first use, or nil when requests are not capped.
+func (p *Policy) requestSlots() chan struct{} {
+	if p == nil || p.MaxRequests <= 0 {
+		return nil
+	}
+	p.slotsOnce.Do(func() {
+		p.slots = make(chan struct{}, p.MaxRequests)
+	})
+	return p.slots
+}
+
+// limitedTransport holds one of slots for every request from the moment it
+// is sent until its response body is read to the end or closed.
+type limitedTransport struct {
+	base  http.RoundTripper
+	slots chan struct{}
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, req.Context().Err()
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-t.slots
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: sync.OnceFunc(func() { <-t.slots })}
+	return resp, nil
+}
+
+// releaseBody frees a limitedTransport slot once the body is exhausted or
+// closed, whichever comes first.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,297 @@
+// Package netguard keeps the fetcher from being pointed at internal network
+// services. makepdf fetches arbitrary user-supplied article and image URLs;
+// without a guard, a URL such as http://169.254.169.254/ (a cloud metadata
+// endpoint) or http://localhost:8080/admin would be requested from the
+// machine running the tool.
+//
+// A Policy is checked twice: against the URL before any request is made,
+// and against every address a name resolves to when the connection is
+// dialed, so a public name that resolves to a private address (or a
+// redirect to one) is refused too.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBlocked matches every error returned for a request the Policy refuses.
+var ErrBlocked = errors.New("blocked by network policy")
+
+// BlockedError reports a refused host and why.
+type BlockedError struct {
+	Host   string
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", e.Host, ErrBlocked, e.Reason)
+}
+
+// Is lets errors.Is(err, ErrBlocked) match.
+func (e *BlockedError) Is(target error) bool { return target == ErrBlocked }
+
+// Policy decides which hosts outgoing requests may reach. The zero value,
+// and a nil *Policy, refuse private, loopback, link-local and other
+// non-public addresses and allow every public host.
+type Policy struct {
+	// AllowPrivate permits non-public addresses, e.g. for a self-hosted
+	// newsletter on the local network or tests against httptest servers.
+	AllowPrivate bool
+	// AllowHosts, when non-empty, restricts requests to these hosts and
+	// their subdomains ("example.com" also allows "www.example.com").
+	AllowHosts []string
+	// BlockHosts refuses these hosts and their subdomains, even when
+	// AllowHosts lists them.
+	BlockHosts []string
+	// Proxy sends every request through this proxy (http://, https:// or
+	// socks5:// URL, see ValidateProxy) instead of the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables, which apply when it
+	// is empty. A proxy resolves destination names itself, so only the URL
+	// checks apply to proxied requests.
+	Proxy string
+	// MaxRequests caps the requests in flight at once across every client
+	// made from this Policy (0 = no cap). Page and image fetches share one
+	// Policy, so this bounds their combined load on the network whatever
+	// their own parallelism settings. A request holds its slot until its
+	// response body is read to the end or closed; further requests wait.
+	MaxRequests int
+
+	slotsOnce sync.Once
+	slots     chan struct{}
+}
+
+// ValidateProxy reports whether raw is a usable Policy.Proxy: empty, or an
+// http, https or socks5 URL with a host.
+func ValidateProxy(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	_, err := parseProxy(raw)
+	return err
+}
+
+// parseProxy parses a proxy URL, defaulting a bare host:port to http.
+func parseProxy(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https or socks5", raw)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", raw)
+	}
+	return u, nil
+}
+
+// nonPublic lists ranges that are not reachable public unicast space and
+// are not covered by the netip.Addr predicates used in blockedAddr.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, incl. broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001:10::/28"),    // deprecated ORCHID
+	netip.MustParsePrefix("192.88.99.0/24"),  // deprecated 6to4 relay anycast
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+}
+
+// blockedAddr reports why ip is not a public unicast address, or "".
+func blockedAddr(ip netip.Addr) string {
+	ip = ip.Unmap()
+	switch {
+	case ip.IsLoopback():
+		return "loopback address " + ip.String()
+	case ip.IsPrivate():
+		return "private address " + ip.String()
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return "link-local address " + ip.String()
+	case ip.IsUnspecified(), ip.IsMulticast(), ip.IsInterfaceLocalMulticast():
+		return "non-unicast address " + ip.String()
+	}
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return "reserved address " + ip.String()
+		}
+	}
+	return ""
+}
+
+// matchHost reports whether host is one of list or a subdomain of one.
+func matchHost(host string, list []string) bool {
+	for _, h := range list {
+		h = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(h)), "*.")
+		if h != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL reports whether p allows a request to rawURL, judged on the URL
+// alone: the scheme must be http or https, the host must pass the allow
+// and block lists, and a literal IP (or "localhost") must be public unless
+// AllowPrivate is set. Names are checked again, resolved, when dialed.
+func (p *Policy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &BlockedError{Host: u.Host, Reason: fmt.Sprintf("unsupported scheme %q", u.Scheme)}
+	}
+	return p.checkHost(u.Hostname())
+}
+
+// checkHost applies the host lists and, for IP literals and localhost
+// names, the address rules.
+func (p *Policy) checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return &BlockedError{Host: host, Reason: "missing host"}
+	}
+	pol := p
+	if pol == nil {
+		pol = &Policy{}
+	}
+	if matchHost(host, pol.BlockHosts) {
+		return &BlockedError{Host: host, Reason: "host is blocklisted"}
+	}
+	if len(pol.AllowHosts) > 0 && !matchHost(host, pol.AllowHosts) {
+		return &BlockedError{Host: host, Reason: "host is not on the allowlist"}
+	}
+	if pol.AllowPrivate {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &BlockedError{Host: host, Reason: "loopback host"}
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		if reason := blockedAddr(ip); reason != "" {
+			return &BlockedError{Host: host, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client that enforces p on every request,
+// redirect and dialed address, and p.MaxRequests across every client from
+// p, going through p.Proxy or else the proxies configured in the
+// environment. The proxy itself may be a local address,
+// and the URL checks still apply to the destination. An invalid p.Proxy
+// (see ValidateProxy) falls back to the environment.
+func (p *Policy) Client(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	proxies := proxyAddrs()
+	if p != nil && p.Proxy != "" {
+		if u, err := parseProxy(p.Proxy); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+			proxies = map[string]bool{hostPort(u): true}
+		}
+	}
+	transport.DialContext = p.dialContext(proxies)
+	var rt http.RoundTripper = transport
+	if slots := p.requestSlots(); slots != nil {
+		rt = &limitedTransport{base: transport, slots: slots}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: rt,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.CheckURL(req.URL.String())
+		},
+	}
+}
+
+// dialContext resolves the destination itself and refuses to connect when
+// any of its addresses is non-public, then dials the vetted address so a
+// second lookup cannot return a different one. Addresses in proxies are
+// dialed as is.
+func (p *Policy) dialContext(proxies map[string]bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if (p != nil && p.AllowPrivate) || proxies[addr] {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if reason := blockedAddr(ip); reason != "" {
+				return nil, &BlockedError{Host: host, Reason: "resolves to " + reason}
+			}
+		}
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// proxyAddrs returns the host:port of the HTTP(S) proxies configured in
+// the environment (the variables http.ProxyFromEnvironment reads).
+func proxyAddrs() map[string]bool {
+	addrs := make(map[string]bool)
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		addrs[hostPort(u)] = true
+	}
+	return addrs
+}
+
+// hostPort returns the host:port a proxy URL is dialed at, filling in the
+// scheme's default port.
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"https": "443", "socks5": "1080"}[u.Scheme]
+		if port == "" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,115 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckURLRefusesNonPublicAddresses(t *testing.T) {
+	var p *Policy // nil: the default policy
+	for _, u := range []string{
+		"http://127.0.0.1/",
+		"http://127.8.9.10:8080/admin",
+		"http://[::1]/",
+		"http://10.1.2.3/",
+		"http://192.168.0.10/",
+		"http://172.16.5.4/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://[::ffff:169.254.169.254]/",
+		"http://[fe80::1]/",
+		"http://0.0.0.0/",
+		"http://localhost:8080/",
+		"http://api.localhost/",
+	} {
+		if err := p.CheckURL(u); !errors.Is(err, ErrBlocked) {
+			t.Errorf("CheckURL(%s) = %v, want ErrBlocked", u, err)
+		}
+	}
+	for _, u := range []string{"https://example.substack.com/p/post", "http://93.184.216.34/", "https://[2606:4700::1111]/"} {
+		if err := p.CheckURL(u); err != nil {
+			t.Errorf("CheckURL(%s) = %v, want allowed", u, err)
+		}
+	}
+	if err := p.CheckURL("file:///etc/passwd"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("file URL: %v, want ErrBlocked", err)
+	}
+	if err := (&Policy{AllowPrivate: true}).CheckURL("http://169.254.169.254/"); err != nil {
+		t.Errorf("AllowPrivate: %v", err)
+	}
+}
+
+func TestCheckURLHostLists(t *testing.T) {
+	p := &Policy{
+		AllowHosts: []string{"substack.com", "*.example.org"},
+		BlockHosts: []string{"spam.substack.com"},
+	}
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://substack.com/", true},
+		{"https://writer.substack.com/p/x", true},
+		{"https://WRITER.Substack.com./p/x", true},
+		{"https://news.example.org/", true},
+		{"https://example.org/", true},
+		{"https://spam.substack.com/p/x", false},
+		{"https://deep.spam.substack.com/", false},
+		{"https://notsubstack.com/", false},
+		{"https://substack.com.evil.net/", false},
+		{"https://example.com/", false},
+	}
+	for _, tt := range tests {
+		err := p.CheckURL(tt.url)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("CheckURL(%s) = %v, want allowed = %t", tt.url, err, tt.allowed)
+		}
+	}
+
+	// The blocklist alone leaves every other public host allowed
+	p = &Policy{BlockHosts: []string{"tracker.example.com"}}
+	if err := p.CheckURL("https://img.tracker.example.com/p.gif"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("blocked subdomain: %v", err)
+	}
+	if err := p.CheckURL("https://example.com/"); err != nil {
+		t.Errorf("unlisted host: %v", err)
+	}
+}
+
+// A public URL that redirects to a loopback address is refused at the
+// redirect. The test server acts as the proxy, which is dialed as is, so
+// the first request can name a public host.
+func TestClientRefusesRedirectToLoopback(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the loopback server")
+	}))
+	defer internal.Close()
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/admin", http.StatusFound)
+	}))
+	defer public.Close()
+
+	client := (&Policy{Proxy: public.URL}).Client(5 * time.Second)
+	resp, err := client.Get("http://news.example.com/p/post")
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("followed a redirect to a loopback address")
+	}
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("err = %v, want ErrBlocked", err)
+	}
+}
+
+// A name that resolves to a loopback address is refused when dialed, after
+// passing the URL checks.
+func TestDialRefusesNameResolvingToLoopback(t *testing.T) {
+	var p *Policy
+	_, err := p.dialContext(nil)(context.Background(), "tcp", "localhost:80")
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("dial localhost = %v, want ErrBlocked", err)
+	}
+}
@@ -0,0 +1,152 @@
+package pdf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	art "pdf-maker/internal/article"
+)
+
+// Every article starts at an anchor named by ArticleAnchors, so a web UI
+// can link straight to it with file.pdf#<anchor>, which Chrome, Firefox and
+// Acrobat open at the named destination of that name. The Typst layouts
+// (newspaper, essay) label the article's heading with the anchor, and Typst
+// writes labelled headings as named destinations. The original layout gives
+// the anchor as the id of the article's element, but wkhtmltopdf exports
+// destinations under generated names, so its PDFs cannot be deep-linked;
+// Manifest.NamedDestinations tells the UI which case it has.
+//
+// Anchors depend only on the article, not on its position in the issue, so
+// a link stays valid when the issue is regenerated with articles added,
+// removed or reordered.
+
+// ArticleAnchors returns each article's anchor: "article-" followed by the
+// slug of its URL's last path segment (which Substack and most blogs keep
+// when a title is edited), else of its title, else its 1-based position.
+// An anchor already taken by an earlier article gets "-2", "-3", ... added.
+func ArticleAnchors(articles []*art.Article) []string {
+	anchors := make([]string, len(articles))
+	used := make(map[string]bool, len(articles))
+	for i, a := range articles {
+		base := "article-" + anchorSlug(a, i+1)
+		anchor := base
+		for n := 2; used[anchor]; n++ {
+			anchor = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[anchor] = true
+		anchors[i] = anchor
+	}
+	return anchors
+}
+
+// anchorSlug is the slug part of an article's anchor; see ArticleAnchors.
+func anchorSlug(a *art.Article, num int) string {
+	var candidates []string
+	if a.Link != "" {
+		segs := strings.Split(strings.TrimRight(strings.SplitN(a.Link, "?", 2)[0], "/"), "/")
+		if len(segs) > 3 { // scheme, "", host: a bare domain has no slug
+			candidates = append(candidates, segs[len(segs)-1])
+		}
+	}
+	candidates = append(candidates, a.Title)
+	for _, c := range candidates {
+		if slug := slugify(c); slug != "" {
+			return slug
+		}
+	}
+	return fmt.Sprint(num)
+}
+
+// Manifest describes a generated issue for the web UI: where its PDF was
+// written and the anchor each article can be linked to (see
+// ArticleAnchors). Written by WriteManifest.
+type Manifest struct {
+	Title     string    `json:"title,omitempty"`
+	Layout    string    `json:"layout"`
+	Generated time.Time `json:"generated"`
+	PDF       string    `json:"pdf,omitempty"` // combined PDF; empty in split mode
+
+	// NamedDestinations reports whether the PDFs hold a named destination
+	// for every anchor, i.e. whether file.pdf#<anchor> links work (false
+	// for the original layout).
+	NamedDestinations bool `json:"named_destinations"`
+
+	Articles []ManifestArticle `json:"articles"`
+}
+
+// ManifestArticle is one article of a Manifest. PDF is set in split mode,
+// where each article has its own file; it is empty when that render failed.
+type ManifestArticle struct {
+	Index       int    `json:"index"`
+	Title       string `json:"title"`
+	URL         string `json:"url,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Publication string `json:"publication,omitempty"`
+	Anchor      string `json:"anchor"`
+	PDF         string `json:"pdf,omitempty"`
+}
+
+// NewManifest builds the manifest for articles rendered with opts into
+// results: one combined PDF, or with split one PDF per article, in article
+// order.
+func NewManifest(articles []*art.Article, results []GenerateResult, opts GenerateOptions, split bool) Manifest {
+	layout := opts.LayoutType
+	if layout == "" {
+		layout = "newspaper"
+	}
+	m := Manifest{
+		Title:             opts.Title,
+		Layout:            layout,
+		Generated:         time.Now().UTC(),
+		NamedDestinations: layout != "original",
+	}
+	if !split && len(results) > 0 {
+		m.PDF = results[0].PDFPath
+	}
+	anchors := ArticleAnchors(articles)
+	for i, a := range articles {
+		entry := ManifestArticle{
+			Index:       i + 1,
+			Title:       a.DisplayTitle(),
+			URL:         a.Link,
+			Author:      a.Author,
+			Publication: a.Publication,
+			Anchor:      anchors[i],
+		}
+		if split {
+			// Each article is alone in its PDF, so its anchor never needs a suffix
+			entry.Anchor = ArticleAnchors([]*art.Article{a})[0]
+			if i < len(results) && results[i].Success {
+				entry.PDF = results[i].PDFPath
+			}
+		}
+		m.Articles = append(m.Articles, entry)
+	}
+	return m
+}
+
+// WriteManifest writes m to path as indented JSON, replacing the file
+// atomically so a reader never sees a partial manifest.
+func WriteManifest(path string, m Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".manifest-*.json")
+	if err != nil {
+		return fmt.Errorf("create manifest: %w", err)
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,197 @@
+package pdf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Column balancing for the Typst newspaper layout.
+//
+// Typst fills page columns sequentially, so the final page of an issue often
+// has a full first column and a nearly empty last one. Balancing is done in
+// two passes:
+//  1. Compile with an end-of-issue marker that records its page and position
+//     as metadata, then read it back with `typst query`.
+//  2. From that position work out how much text sits on the last page and add
+//     a page-scoped bottom float that shortens every column on that page to
+//     about a third of it. The balanced PDF is kept only if the page count is
+//     unchanged; otherwise the first-pass PDF is used.
+//
+// The wkhtmltopdf renderer balances table columns per page in
+// distributeToColumns; its CSS column support (column-fill) is too limited to
+// do better, so BalanceColumns only affects Typst output.
+
+// issueEndLabel labels the metadata marker written at the end of the document.
+const issueEndLabel = "issue-end"
+
+// typstGeometry describes a page layout in points, for locating positions
+// reported by `typst query`.
+type typstGeometry struct {
+	PageWidth  float64
+	PageHeight float64
+	MarginX    float64
+	MarginY    float64
+	Columns    int
+	Gutter     float64 // column gutter
+}
+
+// newspaperGeometry matches the #set page and #set columns rules in
+// AssembleNewspaperTypst: US Letter with 0.75in margins,
+// newspaperColumns(opts) columns and the opts.Gutter gutter.
+func newspaperGeometry(opts GenerateOptions) typstGeometry {
+	landscape := orientationOrDefault(opts) == OrientationLandscape
+	g := typstGeometry{
+		PageWidth:  612,
+		PageHeight: 792,
+		MarginX:    54,
+		MarginY:    54,
+		Columns:    newspaperColumns(opts),
+	}
+	if landscape {
+		g.PageWidth, g.PageHeight = g.PageHeight, g.PageWidth
+	}
+	g.Gutter = gutterPoints(opts.Gutter, g.PageWidth-2*g.MarginX)
+	return g
+}
+
+// gutterPoints returns the column gutter in pt for a validated CSS length
+// gutter, as typstLength renders it, on a page contentW pt wide. Typst's
+// default gutter, for "", is 4% of the content width; em is relative to
+// the newspaper's 10pt text.
+func gutterPoints(gutter string, contentW float64) float64 {
+	if gutter == "" {
+		return contentW * 0.04
+	}
+	v, unit := splitCSSLength(gutter)
+	switch unit {
+	case "px":
+		return v * 0.75
+	case "pc":
+		return v * 12
+	case "mm":
+		return v * 72 / 25.4
+	case "cm":
+		return v * 72 / 2.54
+	case "in":
+		return v * 72
+	case "em", "rem":
+		return v * 10
+	case "ex":
+		return v * 5
+	case "%":
+		return contentW * v / 100
+	}
+	return v // pt, or a bare 0
+}
+
+// typstPosition is the page and absolute position (in pt) of a Typst location.
+type typstPosition struct {
+	Page int     `json:"page"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// typstEndMarker returns Typst markup that records where the document ends.
+func typstEndMarker() string {
+	return fmt.Sprintf("#context [#metadata((page: here().page(), x: here().position().x / 1pt, y: here().position().y / 1pt)) <%s>]\n", issueEndLabel)
+}
+
+// queryEndPosition runs `typst query` to read the end-of-issue marker.
+func queryEndPosition(ctx context.Context, typstPath, typPath string) (typstPosition, error) {
+	var pos typstPosition
+	cmd := exec.CommandContext(ctx, typstPath, "query", "--root", "/", typPath,
+		fmt.Sprintf("<%s>", issueEndLabel), "--field", "value", "--one")
+	out, err := cmd.Output()
+	if err != nil {
+		return pos, fmt.Errorf("typst query: %w", err)
+	}
+	if err := json.Unmarshal(out, &pos); err != nil {
+		return pos, fmt.Errorf("parse typst query output: %w", err)
+	}
+	return pos, nil
+}
+
+// balancingFloat returns the Typst float that shortens the columns of the last
+// page so its content is split evenly, or "" when balancing would not help
+// (single-page issue, or the last page is already nearly full).
+func balancingFloat(pos typstPosition, g typstGeometry) string {
+	const slack = 24.0 // ~2 lines, absorbs paragraph-breaking differences
+	if pos.Page <= 1 || g.Columns < 2 {
+		return ""
+	}
+	contentW := g.PageWidth - 2*g.MarginX
+	colH := g.PageHeight - 2*g.MarginY
+	gutter := g.Gutter
+	colW := (contentW - float64(g.Columns-1)*gutter) / float64(g.Columns)
+
+	col := int((pos.X - g.MarginX) / (colW + gutter))
+	if col < 0 {
+		col = 0
+	}
+	if col >= g.Columns {
+		col = g.Columns - 1
+	}
+	filled := float64(col)*colH + math.Max(0, pos.Y-g.MarginY)
+	target := math.Ceil(filled/float64(g.Columns)) + slack
+	shrink := colH - target
+	if shrink < slack {
+		return ""
+	}
+	return fmt.Sprintf("#place(bottom, scope: \"parent\", float: true, clearance: 0pt, block(height: %.1fpt))\n", shrink)
+}
+
+// balanceTypstColumns performs the second pass described above. typContent
+// must already end with typstEndMarker and absPDFPath must hold the first-pass
+// PDF. Failures are reported as warnings; the first-pass PDF is kept.
+func balanceTypstColumns(ctx context.Context, opts GenerateOptions, typContent, absTypPath, absPDFPath string) {
+	warn := func(err error) {
+		fmt.Fprintf(os.Stderr, "⚠️  column balancing skipped: %v\n", err)
+	}
+
+	first, err := queryEndPosition(ctx, opts.TypstPath, absTypPath)
+	if err != nil {
+		warn(err)
+		return
+	}
+	float := balancingFloat(first, newspaperGeometry(opts))
+	if float == "" {
+		return
+	}
+
+	marker := typstEndMarker()
+	balanced := strings.TrimSuffix(typContent, marker) + float + marker
+	if err := os.WriteFile(absTypPath, []byte(balanced), 0o644); err != nil {
+		warn(err)
+		return
+	}
+	restore := func() { _ = os.WriteFile(absTypPath, []byte(typContent), 0o644) }
+
+	tmpPDF := strings.TrimSuffix(absPDFPath, ".pdf") + ".balanced.pdf"
+	cmd := exec.CommandContext(ctx, opts.TypstPath, typstCompileArgs(opts, absTypPath, tmpPDF)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		restore()
+		warn(fmt.Errorf("second pass: %w (output: %s)", err, string(out)))
+		return
+	}
+
+	second, err := queryEndPosition(ctx, opts.TypstPath, absTypPath)
+	if err != nil || second.Page != first.Page {
+		_ = os.Remove(tmpPDF)
+		restore()
+		if err == nil {
+			err = fmt.Errorf("balanced layout changed page count (%d → %d)", first.Page, second.Page)
+		}
+		warn(err)
+		return
+	}
+	if err := os.Rename(tmpPDF, absPDFPath); err != nil {
+		_ = os.Remove(tmpPDF)
+		restore()
+		warn(err)
+	}
+}
@@ -0,0 +1,28 @@
+package pdf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewspaperGeometryGutter(t *testing.T) {
+	const contentW = 612 - 2*54
+	tests := []struct {
+		gutter string
+		want   float64
+	}{
+		{"", contentW * 0.04},
+		{"0", 0},
+		{"12pt", 12},
+		{"24px", 18},
+		{"0.25in", 18},
+		{"1em", 10},
+		{"5%", contentW * 0.05},
+	}
+	for _, tt := range tests {
+		g := newspaperGeometry(GenerateOptions{Gutter: tt.gutter, Orientation: OrientationPortrait})
+		if math.Abs(g.Gutter-tt.want) > 1e-9 {
+			t.Errorf("gutter %q = %gpt, want %gpt", tt.gutter, g.Gutter, tt.want)
+		}
+	}
+}
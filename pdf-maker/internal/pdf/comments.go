@@ -0,0 +1,67 @@
+package pdf
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	art "pdf-maker/internal/article"
+)
+
+// commentsHTML renders an article's reader comments as an appendix section
+// when opts.IncludeComments is set, or "" when it is off or there are none.
+func commentsHTML(a *art.Article, opts GenerateOptions) string {
+	blocks := commentBlocks(a, opts)
+	if len(blocks) == 0 {
+		return ""
+	}
+	return "<div class=\"article-comments\">\n" + strings.Join(blocks, "") + "</div>\n"
+}
+
+// commentBlocks returns the appendix heading followed by one block per
+// comment, so the newspaper layout can pack them into columns separately.
+func commentBlocks(a *art.Article, opts GenerateOptions) []string {
+	if !opts.IncludeComments || len(a.Comments) == 0 {
+		return nil
+	}
+	blocks := []string{fmt.Sprintf("<h3 class=\"comments-heading\">Comments (%d)</h3>\n", len(a.Comments))}
+	for _, c := range a.Comments {
+		var sb strings.Builder
+		sb.WriteString("<div class=\"comment\">\n")
+		if c.Author != "" {
+			sb.WriteString(fmt.Sprintf("  <p class=\"comment-author\">%s</p>\n", html.EscapeString(c.Author)))
+		}
+		for _, para := range strings.Split(c.Text, "\n\n") {
+			sb.WriteString(fmt.Sprintf("  <p>%s</p>\n", html.EscapeString(para)))
+		}
+		sb.WriteString("</div>\n")
+		blocks = append(blocks, sb.String())
+	}
+	return blocks
+}
+
+// typstComments is the Typst counterpart of commentsHTML: a small heading
+// followed by each comment with its author in bold.
+func typstComments(a *art.Article, opts GenerateOptions, size string) string {
+	if !opts.IncludeComments || len(a.Comments) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("#v(0.6em)\n")
+	sb.WriteString(fmt.Sprintf("#text(size: %s, weight: \"bold\")[Comments (%d)]\n\n", size, len(a.Comments)))
+	sb.WriteString(fmt.Sprintf("#block(inset: (left: 0.8em), stroke: (left: 0.5pt + gray))[\n#set text(size: %s)\n#set par(first-line-indent: 0pt)\n", size))
+	for i, c := range a.Comments {
+		if i > 0 {
+			sb.WriteString("#v(0.4em)\n")
+		}
+		if c.Author != "" {
+			sb.WriteString(fmt.Sprintf("*%s*\n\n", escapeTypstContent(c.Author)))
+		}
+		for _, para := range strings.Split(c.Text, "\n\n") {
+			sb.WriteString(escapeTypstContent(para))
+			sb.WriteString("\n\n")
+		}
+	}
+	sb.WriteString("]\n\n")
+	return sb.String()
+}
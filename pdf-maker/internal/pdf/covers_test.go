@@ -0,0 +1,65 @@
+package pdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestTOCThumbnails(t *testing.T) {
+	dir := t.TempDir()
+	fallback := filepath.Join(dir, "default.png")
+	if err := os.WriteFile(fallback, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	articles := []*art.Article{
+		{Title: "Local", Link: "https://x.substack.com/p/local", Content: "<p>a</p>", CoverImage: "/tmp/images/cover.jpg"},
+		{Title: "Remote", Link: "https://x.substack.com/p/remote", Content: "<p>b</p>", CoverImage: "https://cdn.example.com/cover.jpg"},
+		{Title: "None", Link: "https://x.substack.com/p/none", Content: "<p>c</p>"},
+	}
+	opts := GenerateOptions{TOCThumbnails: true, DefaultCoverImage: fallback}
+	prepared, err := prepareArticles(articles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if articles[2].CoverImage != "" {
+		t.Error("prepareArticles mutated its input")
+	}
+	if got := prepared[2].CoverImage; got != fallback {
+		t.Errorf("default cover = %q, want %q", got, fallback)
+	}
+
+	thumb := func(path, title string) string {
+		return `image("` + path + `", width: 2.6em, height: 2.6em, fit: "cover", alt: "Cover image of ` + title + `")`
+	}
+	newspaper, err := AssembleNewspaperTypstWithOptions(prepared, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toc := newspaper[strings.Index(newspaper, "IN THIS EDITION"):]
+	assertOrder(t, toc, thumb("/tmp/images/cover.jpg", "Local"), "Local", "Remote", thumb(fallback, "None"), "None")
+	if strings.Contains(newspaper, "cdn.example.com") {
+		t.Error("remote cover reached the Typst source")
+	}
+
+	opts.SummarySpread = true
+	essay, err := AssembleEssayTypstWithOptions(prepared, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(essay, `image("/tmp/images/cover.jpg", width: 4.5em, height: 4.5em`) {
+		t.Error("summary spread has no cover thumbnail")
+	}
+
+	opts.TOCThumbnails = false
+	plain, err := AssembleNewspaperTypstWithOptions(prepared, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(plain, "Cover image of") {
+		t.Error("thumbnails rendered without TOCThumbnails")
+	}
+}
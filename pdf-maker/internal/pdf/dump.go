@@ -0,0 +1,48 @@
+package pdf
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	art "pdf-maker/internal/article"
+)
+
+// dumpArticles writes each article's content, exactly as the renderer is
+// about to receive it (after cleaning, image processing and the
+// render-time content passes), to dir/<slug>.html. Each file is a minimal
+// standalone page with image paths made absolute, so a malformed article
+// can be opened in a browser on its own. Slugs shared by several articles
+// get a numeric suffix.
+func dumpArticles(articles []*art.Article, dir, imagesDir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create dump dir: %w", err)
+	}
+	used := make(map[string]int)
+	for i, a := range articles {
+		slug := articleSlug(a, i+1)
+		used[slug]++
+		if n := used[slug]; n > 1 {
+			slug = fmt.Sprintf("%s-%d", slug, n)
+		}
+		content, err := a.LoadContent()
+		if err != nil {
+			return fmt.Errorf("load content for '%s': %w", a.Title, err)
+		}
+		var sb strings.Builder
+		sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+		sb.WriteString(fmt.Sprintf("<title>%s</title>\n</head>\n<body>\n", html.EscapeString(a.Title)))
+		if a.Link != "" {
+			sb.WriteString(fmt.Sprintf("<!-- source: %s -->\n", strings.ReplaceAll(a.Link, "--", "%2D%2D")))
+		}
+		sb.WriteString(fixImagePaths(content, imagesDir))
+		sb.WriteString("\n</body>\n</html>\n")
+		path := filepath.Join(dir, slug+".html")
+		if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+	}
+	return nil
+}
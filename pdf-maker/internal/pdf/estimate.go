@@ -0,0 +1,152 @@
+package pdf
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	art "pdf-maker/internal/article"
+)
+
+// PageModel holds the tunable constants used by EstimatePages for one layout.
+// Values are calibrated on US Letter with the layout's default margins. Only
+// the original layout honours PageSize and the margin options (the Typst
+// templates fix both), so only its estimate is scaled by area.
+type PageModel struct {
+	WordsPerPage    float64 // body words that fill one page of text
+	PagesPerImage   float64 // fraction of a page consumed by one image
+	PagesPerArticle float64 // fixed overhead per article (heading, byline, separator)
+	FrontMatter     float64 // masthead + table of contents on the first page
+	MarginXIn       float64 // default left/right margin in inches
+	MarginYIn       float64 // default top/bottom margin in inches
+}
+
+// Page-count estimation models, exported so they can be tuned without
+// touching the estimator. Newspaper: 3 columns of 10pt text, landscape.
+// Essay: single column of 12pt text, portrait. Original: each article in
+// its source page's own styles, typically one column of ~16px text, with
+// every article starting a new page (about half a page lost to each).
+var (
+	NewspaperPageModel = PageModel{
+		WordsPerPage:    1450,
+		PagesPerImage:   0.12,
+		PagesPerArticle: 0.04,
+		FrontMatter:     0.15,
+		MarginXIn:       0.75,
+		MarginYIn:       0.75,
+	}
+	EssayPageModel = PageModel{
+		WordsPerPage:    420,
+		PagesPerImage:   0.35,
+		PagesPerArticle: 0.1,
+		FrontMatter:     0.15,
+		MarginXIn:       1,
+		MarginYIn:       0.75,
+	}
+	OriginalPageModel = PageModel{
+		WordsPerPage:    380,
+		PagesPerImage:   0.4,
+		PagesPerArticle: 0.5,
+		FrontMatter:     0,
+		MarginXIn:       12 / 25.4, // generateWkhtmlPDF's defaults
+		MarginYIn:       15 / 25.4,
+	}
+)
+
+// paperSizesIn maps supported PageSize names to portrait width × height in inches.
+var paperSizesIn = map[string][2]float64{
+	"letter": {8.5, 11},
+	"legal":  {8.5, 14},
+	"a4":     {8.27, 11.69},
+	"a5":     {5.83, 8.27},
+}
+
+// EstimatePages returns a rough page count for rendering articles with opts,
+// without running the PDF engine. It sums body words, images and per-article
+// overhead against the layout's PageModel; for the original layout, the
+// words per page are scaled by the usable page area implied by
+// opts.PageSize and the margin options. The result is only directionally
+// accurate and is always at least 1 for a non-empty issue.
+func EstimatePages(articles []*art.Article, opts GenerateOptions) int {
+	if len(articles) == 0 {
+		return 0
+	}
+
+	model := NewspaperPageModel
+	switch opts.LayoutType {
+	case "essay":
+		model = EssayPageModel
+	case "original":
+		model = OriginalPageModel
+	}
+
+	words, images := 0, 0
+	for _, a := range articles {
+		content, _ := a.LoadContent()
+		words += len(strings.Fields(htmlTagRe.ReplaceAllString(content, " ")))
+		if !a.RemoveImages && !opts.RemoveImages {
+			images += len(imgTagRe.FindAllString(content, -1))
+		}
+	}
+
+	wordsPerPage := model.WordsPerPage
+	if opts.LayoutType == "original" {
+		wordsPerPage *= usableAreaRatio(model, opts)
+	}
+	pages := model.FrontMatter +
+		float64(words)/wordsPerPage +
+		float64(images)*model.PagesPerImage +
+		float64(len(articles))*model.PagesPerArticle
+
+	return int(math.Max(1, math.Ceil(pages)))
+}
+
+// usableAreaRatio compares the printable area implied by opts with the area
+// the model was calibrated for (US Letter with the model's default margins).
+func usableAreaRatio(model PageModel, opts GenerateOptions) float64 {
+	ref := (8.5 - 2*model.MarginXIn) * (11 - 2*model.MarginYIn)
+
+	size, ok := paperSizesIn[strings.ToLower(opts.PageSize)]
+	if !ok {
+		size = paperSizesIn["letter"]
+	}
+	left := marginInches(opts.MarginLeft, model.MarginXIn)
+	right := marginInches(opts.MarginRight, model.MarginXIn)
+	top := marginInches(opts.MarginTop, model.MarginYIn)
+	bottom := marginInches(opts.MarginBottom, model.MarginYIn)
+
+	w := size[0] - left - right
+	h := size[1] - top - bottom
+	if w <= 0 || h <= 0 || ref <= 0 {
+		return 1
+	}
+	return (w * h) / ref
+}
+
+// marginInches parses a margin such as "10mm", "0.5in", "1.2cm" or "36pt"
+// into inches, returning def when the value is empty or unparseable.
+func marginInches(v string, def float64) float64 {
+	v = strings.TrimSpace(strings.ToLower(v))
+	if v == "" {
+		return def
+	}
+	units := []struct {
+		suffix string
+		perIn  float64
+	}{
+		{"mm", 25.4},
+		{"cm", 2.54},
+		{"in", 1},
+		{"pt", 72},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), 64)
+			if err != nil || n < 0 {
+				return def
+			}
+			return n / u.perIn
+		}
+	}
+	return def
+}
@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+// wordsArticle returns an article of n words and no images.
+func wordsArticle(n int) *art.Article {
+	return &art.Article{Title: "T", Content: "<p>" + strings.Repeat("word ", n) + "</p>"}
+}
+
+func TestEstimatePagesScalesWithWords(t *testing.T) {
+	for _, layout := range []string{"newspaper", "essay", "original"} {
+		opts := GenerateOptions{LayoutType: layout}
+		prev := 0
+		for _, n := range []int{0, 100, 1000, 5000, 20000, 80000} {
+			got := EstimatePages([]*art.Article{wordsArticle(n)}, opts)
+			if got < 1 {
+				t.Errorf("%s, %d words: %d pages, want at least 1", layout, n, got)
+			}
+			if got < prev {
+				t.Errorf("%s: %d words gave %d pages, fewer than the %d before", layout, n, got, prev)
+			}
+			prev = got
+		}
+		small := EstimatePages([]*art.Article{wordsArticle(20000)}, opts)
+		large := EstimatePages([]*art.Article{wordsArticle(80000)}, opts)
+		if ratio := float64(large) / float64(small); ratio < 3.5 || ratio > 4.5 {
+			t.Errorf("%s: 4× the words gave %d → %d pages (×%.2f), want about ×4", layout, small, large, ratio)
+		}
+	}
+	if got := EstimatePages(nil, GenerateOptions{}); got != 0 {
+		t.Errorf("no articles: %d pages", got)
+	}
+	if EstimatePages([]*art.Article{wordsArticle(20000)}, GenerateOptions{LayoutType: "essay"}) <=
+		EstimatePages([]*art.Article{wordsArticle(20000)}, GenerateOptions{LayoutType: "newspaper"}) {
+		t.Error("the single-column essay should need more pages than the newspaper")
+	}
+}
+
+// PageSize and the margins only change the original layout's estimate;
+// the Typst templates ignore them.
+func TestEstimatePagesPageSize(t *testing.T) {
+	articles := []*art.Article{wordsArticle(20000)}
+	for _, layout := range []string{"newspaper", "essay"} {
+		letter := EstimatePages(articles, GenerateOptions{LayoutType: layout})
+		a5 := EstimatePages(articles, GenerateOptions{LayoutType: layout, PageSize: "A5", MarginLeft: "30mm"})
+		if a5 != letter {
+			t.Errorf("%s: A5 with wide margins gave %d pages, Letter %d; want them equal", layout, a5, letter)
+		}
+	}
+	letter := EstimatePages(articles, GenerateOptions{LayoutType: "original"})
+	a5 := EstimatePages(articles, GenerateOptions{LayoutType: "original", PageSize: "A5"})
+	margins := EstimatePages(articles, GenerateOptions{LayoutType: "original", MarginLeft: "1.5in", MarginRight: "1.5in"})
+	if a5 <= letter || margins <= letter {
+		t.Errorf("original: Letter %d, A5 %d, wide margins %d pages; want more pages on the smaller areas", letter, a5, margins)
+	}
+}
+
+func TestEstimatePagesOriginalArticleOverhead(t *testing.T) {
+	one := EstimatePages([]*art.Article{wordsArticle(50)}, GenerateOptions{LayoutType: "original"})
+	four := EstimatePages([]*art.Article{wordsArticle(50), wordsArticle(50), wordsArticle(50), wordsArticle(50)}, GenerateOptions{LayoutType: "original"})
+	if one != 1 || four < 3 {
+		t.Errorf("short articles one per page: 1 article %d pages, 4 articles %d pages", one, four)
+	}
+}
@@ -0,0 +1,42 @@
+package pdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestSplitFileNames(t *testing.T) {
+	now := time.Date(2025, 10, 18, 9, 30, 5, 0, time.UTC)
+	articles := []*art.Article{
+		{Title: "Rates & Spreads"},
+		{Title: "", Link: "https://x.substack.com/p/the-slug?utm_source=x"},
+		{Title: "Rates & Spreads"},
+	}
+	tests := []struct {
+		tmpl string
+		want []string
+	}{
+		{"", []string{"rates-spreads", "the-slug", "rates-spreads-2"}},
+		{"{date}_{title-slug}", []string{"2025-10-18_rates-spreads", "2025-10-18_the-slug", "2025-10-18_rates-spreads-2"}},
+		{"{date}_{title-slug}_{count}.pdf", []string{"2025-10-18_rates-spreads_1", "2025-10-18_the-slug_1", "2025-10-18_rates-spreads_1-2"}},
+		{"issue-{time}", []string{"issue-093005", "issue-093005-2", "issue-093005-3"}},
+		{"///", []string{"rates-spreads", "the-slug", "rates-spreads-2"}},
+	}
+	for _, tt := range tests {
+		got := splitFileNames(articles, tt.tmpl, now)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("template %q: %v, want %v", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultOutputPathTemplate(t *testing.T) {
+	got := defaultOutputPath(GenerateOptions{Title: "Weekly Digest", FilenameTemplate: "{title-slug}_{count}"}, 4)
+	if want := filepath.Join("newspapers", "weekly-digest_4.pdf"); got != want {
+		t.Errorf("defaultOutputPath = %s, want %s", got, want)
+	}
+}
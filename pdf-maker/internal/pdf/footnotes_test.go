@@ -0,0 +1,78 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/clean"
+)
+
+// substackFootnotes is a post body in Substack's footnote markup.
+func substackFootnotes(word string) string {
+	return `<p>` + word + ` claim<a class="footnote-anchor" id="footnote-anchor-1" href="#footnote-1">1</a></p>` +
+		`<div class="footnote"><a class="footnote-number" id="footnote-1" href="#footnote-anchor-1">1</a>` +
+		`<div class="footnote-content"><p>note ` + word + `</p></div></div>` +
+		`<p>after ` + word + `</p>`
+}
+
+// footnoteArticles cleans two posts with FootnoteSection and prepares them
+// as one issue.
+func footnoteArticles(t *testing.T) []*art.Article {
+	t.Helper()
+	var articles []*art.Article
+	for _, w := range []string{"one", "two"} {
+		content, _, err := clean.CleanHTMLWithOptions(substackFootnotes(w), false, clean.CleanOptions{FootnoteSection: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		articles = append(articles, &art.Article{Title: "Post " + w, Link: "https://x.substack.com/p/" + w, Content: content})
+	}
+	prepared, err := prepareArticles(articles, GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prepared
+}
+
+// assertOrder fails unless every string in want occurs in s, in order.
+func assertOrder(t *testing.T, s string, want ...string) {
+	t.Helper()
+	pos := 0
+	for _, w := range want {
+		i := strings.Index(s[pos:], w)
+		if i < 0 {
+			t.Fatalf("%q missing or out of order in:\n%s", w, s)
+		}
+		pos += i + len(w)
+	}
+}
+
+func TestFootnotesStayWithTheirArticle(t *testing.T) {
+	articles := footnoteArticles(t)
+
+	t.Run("html", func(t *testing.T) {
+		var sb strings.Builder
+		for i, a := range articles {
+			sb.WriteString(renderArticle(a, ArticleAnchors(articles)[i], GenerateOptions{}))
+		}
+		out := sb.String()
+		assertOrder(t, out,
+			`id="article-one"`, `href="#a1-footnote-1"`, "after one", `class="article-notes"`, `id="a1-footnote-1"`, "note one",
+			`id="article-two"`, `href="#a2-footnote-1"`, "after two", `class="article-notes"`, `id="a2-footnote-1"`, "note two")
+	})
+
+	t.Run("typst", func(t *testing.T) {
+		for _, assemble := range []func([]*art.Article, GenerateOptions) (string, error){
+			AssembleNewspaperTypstWithOptions, AssembleEssayTypstWithOptions,
+		} {
+			out, err := assemble(articles, GenerateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertOrder(t, out,
+				"Post one <article-one>", "#link(<a1-footnote-1>)", "after one", "Notes", "<a1-footnote-1>", "note one",
+				"Post two <article-two>", "#link(<a2-footnote-1>)", "after two", "Notes", "<a2-footnote-1>", "note two")
+		}
+	})
+}
@@ -6,7 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	art "pdf-maker/internal/article"
@@ -15,20 +18,143 @@ import (
 
 // GenerateOptions configures PDF generation behavior.
 type GenerateOptions struct {
-	OutputPath      string        // Full path to output PDF file
-	TempHTMLPath    string        // Optional: path to save intermediate HTML/Typst (for debugging)
-	KeepHTML        bool          // Whether to preserve intermediate source file (HTML or .typ)
-	Title           string        // PDF metadata title (default: "Your Articles")
-	LayoutType      string        // Layout type: "essay" or "newspaper" (default)
-	RemoveImages    bool          // Whether to remove all images from the PDF
-	PageSize        string        // e.g., "Letter", "A4" (default: Letter) — wkhtmltopdf only
-	MarginTop       string        // e.g., "10mm" — wkhtmltopdf only
-	MarginBottom    string        // e.g., "10mm" — wkhtmltopdf only
-	MarginLeft      string        // e.g., "10mm" — wkhtmltopdf only
-	MarginRight     string        // e.g., "10mm" — wkhtmltopdf only
-	Timeout         time.Duration // subprocess execution timeout
-	WkhtmltopdfPath string        // Override wkhtmltopdf binary path (default: "wkhtmltopdf")
-	TypstPath       string        // Override typst binary path (default: "typst")
+	OutputPath       string        // Full path to output PDF file
+	FilenameTemplate string        // Used when OutputPath is empty, e.g. "{date}_{title-slug}_{count}.pdf"
+	TempHTMLPath     string        // With KeepHTML, where to keep the intermediate HTML/Typst (default: next to the PDF, named after it)
+	KeepHTML         bool          // Whether to preserve intermediate source file (HTML or .typ)
+	Title            string        // PDF metadata title (default: "Your Articles")
+	LayoutType       string        // Layout type: "essay", "original" or "newspaper" (default)
+	RemoveImages     bool          // Whether to remove all images from the PDF
+	DedupeImages     bool          // Drop an image identical to the one right before it (no text between)
+	ReaderMode       bool          // Reduce content to headings, paragraphs, lists, quotes and images
+	DropCaps         bool          // Enlarge the first letter of each article's opening paragraph
+	BalanceColumns   bool          // Two-pass Typst layout that evens out the newspaper's last-page columns
+	ShowAvatars      bool          // Show the author's avatar next to each article byline
+	ScaleTables      bool          // Scale wide tables down to the page/column width instead of clipping
+	ImagesDir        string        // Directory the media downloader saved images into (default: "images")
+	SplitParallel    int           // GenerateSplitPDFs concurrency (default 2, capped at 8 and the CPU count)
+	Orientation      string        // "Portrait" or "Landscape" (default: Landscape for newspaper, Portrait otherwise)
+	Theme            string        // Color theme: "light" (default), "dark" or "sepia"
+	Gutter           string        // Newspaper column gutter as a CSS length, e.g. "24px" or "2%" (default 20px)
+	TOCColumnRatio   float64       // Newspaper first-page TOC column width relative to the others (default 1) — HTML template only; Typst columns are equal
+	NewspaperColumns int           // Newspaper column count, clamped to 1–4 (default: 3 landscape, 2 portrait)
+	DarkenImages     bool          // Dim images to suit a dark page — HTML layouts only (Typst has no image filters)
+	PageSize         string        // e.g., "Letter", "A4" (default: Letter) — wkhtmltopdf only
+	MarginTop        string        // e.g., "10mm" — wkhtmltopdf only
+	MarginBottom     string        // e.g., "10mm" — wkhtmltopdf only
+	MarginLeft       string        // e.g., "10mm" — wkhtmltopdf only
+	MarginRight      string        // e.g., "10mm" — wkhtmltopdf only
+	Timeout          time.Duration // subprocess execution timeout
+	WkhtmltopdfPath  string        // Override wkhtmltopdf binary path (default: "wkhtmltopdf")
+	TypstPath        string        // Override typst binary path (default: "typst")
+
+	// HeaderHTML and FooterHTML are custom sections (an editor's note, a
+	// colophon) inserted after the issue header and at the end of the issue.
+	// Both pass through the same sanitizer as article content.
+	HeaderHTML      string
+	FooterHTML      string
+	HeaderPageBreak bool // start the articles on a new page after HeaderHTML
+	FooterPageBreak bool // start FooterHTML on a new page
+
+	// ShowSourceLink ends each article with "Originally published at" and
+	// its canonical URL (or Link), printed in full for paper readers.
+	ShowSourceLink bool
+
+	// ShowSourceQR ends each article with a small QR code encoding its
+	// canonical URL (or Link), so print readers can continue online. The
+	// images are saved as qr-<hash>.png in ImagesDir.
+	ShowSourceQR bool
+
+	// ImageGalleries lays out runs of three or more consecutive images as
+	// a grid of two or three per row, captions kept with their images
+	// (see clean.GroupImageGalleries).
+	ImageGalleries bool
+
+	// DefaultCoverImage is a local image used as Article.CoverImage for
+	// articles whose page declares no lead picture, so every article has
+	// one. Ignored for articles whose images are removed. See
+	// ValidateDefaultCoverImage.
+	DefaultCoverImage string
+
+	// TOCThumbnails shows each article's cover image (Article.CoverImage,
+	// downloaded with fetch.FetchOptions.CoverImages) as a small thumbnail
+	// beside its entry in the newspaper's IN THIS EDITION box and on the
+	// SummarySpread page. Covers that are still remote URLs are left out.
+	// Typst layouts only.
+	TOCThumbnails bool
+
+	// IncludeComments renders each article's reader comments (Article.Comments)
+	// as an appendix after the article.
+	IncludeComments bool
+
+	// Author, Subject and Keywords fill the PDF's document properties next
+	// to Title, e.g. Author = the issue's publications and Keywords = their
+	// tags, for cataloguing. Typst layouts only (Subject needs typst 0.13+):
+	// wkhtmltopdf can set no document property but the title, so "original"
+	// ignores them with a warning.
+	Author   string
+	Subject  string
+	Keywords []string
+
+	// Watermark, when non-empty, is drawn diagonally and semi-transparently
+	// behind the content of every page, e.g. "DRAFT" for review copies.
+	Watermark string
+
+	// MetaFormat, when set, composes each article's byline from a template
+	// such as "{author} · {publication} · {date}" instead of the layout's
+	// default. Placeholders: {author}, {publication}, {date}, {updated}
+	// (the revision date, empty unless Article.WasUpdated). An empty
+	// placeholder is dropped with its separator. See ValidateMetaFormat.
+	MetaFormat string
+
+	// SummarySpread adds a "This Week in Review" page after the masthead
+	// page: each article's title, byline, reading time and excerpt
+	// (Article.Excerpt, Article.ReadingMinutes), set as a contents spread.
+	// The newspaper layout keeps its IN THIS EDITION box too, at the top of
+	// the first page of articles. Typst layouts only; ignored by "original".
+	SummarySpread bool
+
+	// DateRange dates the issue header with the span of its articles'
+	// publication dates ("Oct 1–7, 2025"), for digests gathered over several
+	// days, instead of the generation date, which stays the fallback when
+	// no article is dated.
+	DateRange bool
+
+	// DumpArticlesDir, when set, receives one <slug>.html per article with
+	// its content as handed to the renderer, for isolating which article's
+	// content is malformed. Finer-grained than KeepHTML.
+	DumpArticlesDir string
+
+	// Tagged produces an accessible, tagged PDF (PDF/UA-1) with a logical
+	// reading order: every image gets alt text and article headings are
+	// renumbered so none skips a level. Only the Typst layouts (newspaper,
+	// essay) support it — wkhtmltopdf cannot tag, and there is no Chromium
+	// backend — so "original" with Tagged is an error. Needs typst 0.14+.
+	Tagged bool
+
+	// HeadingOffset demotes every article's content headings by this many
+	// levels (see clean.CleanOptions.HeadingOffset), applied after Tagged
+	// renumbers them so the offset survives, and to content supplied in an
+	// issue's JSON as well as fetched pages. See clean.ValidateHeadingOffset.
+	HeadingOffset int
+
+	// AllowMissingStyles lets AssembleHTML proceed when styles/<layout>.css
+	// does not exist, for callers that intentionally supply only custom CSS.
+	// By default a missing stylesheet is an error rather than an unstyled PDF.
+	AllowMissingStyles bool
+
+	// HTMLTransform, when set, post-processes the fully assembled HTML document
+	// before image paths are fixed and wkhtmltopdf runs. Returning an error
+	// aborts generation. Only the "original" layout produces an HTML
+	// document, so setting it with a Typst layout is an error; use
+	// ContentTransform for changes every layout should see.
+	HTMLTransform func(html string) (string, error)
+
+	// ContentTransform, when set, is called with each article's publication
+	// and content HTML after cleaning and the built-in content passes, before
+	// image paths are fixed and the issue is assembled. Applied by both
+	// renderers. Returning an error aborts generation.
+	ContentTransform func(publication, html string) (string, error)
 }
 
 // GenerateResult holds the outcome of PDF generation.
@@ -44,17 +170,317 @@ type GenerateResult struct {
 // Routing:
 //   - "newspaper" (default) → Typst: native 3-column landscape layout.
 //   - "essay" → Typst: single-column portrait layout.
+//   - "original" → wkhtmltopdf: each article with its source page's own CSS
+//     (see fetch.InlineStylesheets), one article per page.
 func GeneratePDF(ctx context.Context, articles []*art.Article, opts GenerateOptions) GenerateResult {
 	if opts.LayoutType == "" {
 		opts.LayoutType = "newspaper"
 	}
+	if err := ValidateTheme(opts.Theme); err != nil {
+		return GenerateResult{Error: err}
+	}
+	if opts.Gutter != "" {
+		if err := ValidateCSSLength(opts.Gutter); err != nil {
+			return GenerateResult{Error: fmt.Errorf("gutter: %w", err)}
+		}
+	}
+	if err := ValidateTOCColumnRatio(opts.TOCColumnRatio); err != nil {
+		return GenerateResult{Error: err}
+	}
+	if err := ValidateOrientation(opts.Orientation); err != nil {
+		return GenerateResult{Error: err}
+	}
+	if err := ValidateMetaFormat(opts.MetaFormat); err != nil {
+		return GenerateResult{Error: err}
+	}
+	if err := ValidateDefaultCoverImage(opts.DefaultCoverImage); err != nil {
+		return GenerateResult{Error: err}
+	}
+	if err := clean.ValidateHeadingOffset(opts.HeadingOffset); err != nil {
+		return GenerateResult{Error: err}
+	}
+	if opts.LayoutType == "original" {
+		if opts.Tagged {
+			return GenerateResult{Error: fmt.Errorf("tagged PDF output needs a Typst layout (newspaper or essay); wkhtmltopdf cannot tag PDFs")}
+		}
+		if opts.Author != "" || opts.Subject != "" || len(opts.Keywords) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: the original layout cannot set the PDF's author, subject or keywords (wkhtmltopdf only sets the title)\n")
+		}
+		return generateWkhtmlPDF(ctx, articles, opts)
+	}
+	if opts.HTMLTransform != nil {
+		return GenerateResult{Error: fmt.Errorf("HTMLTransform needs the original layout; the %s layout renders with Typst and never assembles an HTML document (use ContentTransform)", opts.LayoutType)}
+	}
 	return generateTypstPDF(ctx, articles, opts)
 }
 
-// generateTypstPDF renders the newspaper layout via Typst.
-func generateTypstPDF(ctx context.Context, articles []*art.Article, opts GenerateOptions) GenerateResult {
-	result := GenerateResult{}
+// Page orientations accepted by GenerateOptions.Orientation.
+const (
+	OrientationPortrait  = "Portrait"
+	OrientationLandscape = "Landscape"
+)
 
+// ValidateOrientation reports whether o is a supported Orientation value
+// (case-insensitive; empty selects the layout default).
+func ValidateOrientation(o string) error {
+	switch strings.ToLower(o) {
+	case "", "portrait", "landscape":
+		return nil
+	}
+	return fmt.Errorf("invalid orientation %q: must be %q or %q", o, OrientationPortrait, OrientationLandscape)
+}
+
+// ValidateDefaultCoverImage reports whether path is a usable
+// GenerateOptions.DefaultCoverImage: empty, or an existing image file.
+func ValidateDefaultCoverImage(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("default cover image: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("default cover image %q is not a file", path)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg":
+		return nil
+	}
+	return fmt.Errorf("default cover image %q: unsupported format (want PNG, JPEG, GIF, WebP or SVG)", path)
+}
+
+// renderWorkDir creates the temporary directory a render writes its
+// intermediate files into, so they never land in the output directory and
+// a deferred os.RemoveAll clears them after a failure, timeout or panic.
+func renderWorkDir() (string, error) {
+	dir, err := os.MkdirTemp("", "makepdf-render-")
+	if err != nil {
+		return "", fmt.Errorf("create render temp dir: %w", err)
+	}
+	return dir, nil
+}
+
+// keepSource copies the intermediate source at src out of the render's temp
+// directory for KeepHTML: to opts.TempHTMLPath, else next to the PDF under
+// the PDF's name, with extension ext (".html" or ".typ"). Returns the kept
+// path, or "" when the copy fails.
+func keepSource(src string, opts GenerateOptions, ext string) string {
+	dst := opts.TempHTMLPath
+	if dst == "" {
+		dst = opts.OutputPath
+	}
+	dst = strings.TrimSuffix(dst, filepath.Ext(dst)) + ext
+	data, err := os.ReadFile(src)
+	if err == nil {
+		err = os.WriteFile(dst, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to keep intermediate source: %v\n", err)
+		return ""
+	}
+	return dst
+}
+
+// orientationOrDefault returns the effective orientation for opts: the
+// newspaper layout defaults to landscape, every other layout to portrait.
+func orientationOrDefault(opts GenerateOptions) string {
+	switch strings.ToLower(opts.Orientation) {
+	case "landscape":
+		return OrientationLandscape
+	case "portrait":
+		return OrientationPortrait
+	}
+	if opts.LayoutType == "newspaper" || opts.LayoutType == "" {
+		return OrientationLandscape
+	}
+	return OrientationPortrait
+}
+
+// Bounds for GenerateOptions.NewspaperColumns.
+const (
+	minNewspaperColumns = 1
+	maxNewspaperColumns = 4
+)
+
+// newspaperColumns is the newspaper layout's column count: opts.NewspaperColumns
+// clamped to 1–4 when set, else 3 across a landscape page and 2 on the
+// narrower portrait page.
+func newspaperColumns(opts GenerateOptions) int {
+	if n := opts.NewspaperColumns; n != 0 {
+		return min(max(n, minNewspaperColumns), maxNewspaperColumns)
+	}
+	if orientationOrDefault(opts) == OrientationLandscape {
+		return 3
+	}
+	return 2
+}
+
+// Split-mode concurrency bounds. Each worker runs its own typst/wkhtmltopdf
+// process, which can use hundreds of MB, so the default is deliberately low.
+const (
+	defaultSplitParallel = 2
+	maxSplitParallel     = 8
+)
+
+// splitParallelism returns the number of concurrent split-mode workers:
+// opts.SplitParallel (default 2), capped at maxSplitParallel and the CPU count.
+func splitParallelism(opts GenerateOptions) int {
+	n := opts.SplitParallel
+	if n <= 0 {
+		n = defaultSplitParallel
+	}
+	if n > maxSplitParallel {
+		n = maxSplitParallel
+	}
+	if cpus := runtime.NumCPU(); n > cpus {
+		n = cpus
+	}
+	return n
+}
+
+// GenerateSplitPDFs renders every article into its own PDF by calling
+// GeneratePDF once per article, running up to splitParallelism(opts) renders
+// concurrently. Output files are named after the article
+// title (falling back to the URL slug), or by opts.FilenameTemplate with
+// {title-slug} standing for that and {count} for 1, and written to the
+// directory of opts.OutputPath; when OutputPath has no .pdf extension it is
+// treated as the directory itself. All other options are applied unchanged to every article.
+// One result is returned per input article, in input order.
+func GenerateSplitPDFs(ctx context.Context, articles []*art.Article, opts GenerateOptions) []GenerateResult {
+	outDir := "newspapers"
+	if opts.OutputPath != "" {
+		if strings.EqualFold(filepath.Ext(opts.OutputPath), ".pdf") {
+			outDir = filepath.Dir(opts.OutputPath)
+		} else {
+			outDir = opts.OutputPath
+		}
+	}
+
+	results := make([]GenerateResult, len(articles))
+	names := splitFileNames(articles, opts.FilenameTemplate, time.Now())
+	sem := make(chan struct{}, splitParallelism(opts))
+	var wg sync.WaitGroup
+	for i, a := range articles {
+		articleOpts := opts
+		articleOpts.OutputPath = filepath.Join(outDir, names[i]+".pdf")
+		// With KeepHTML each article's source is kept next to its PDF
+		articleOpts.TempHTMLPath = ""
+
+		wg.Add(1)
+		go func(i int, a *art.Article, articleOpts GenerateOptions) {
+			defer wg.Done()
+			sem <- struct{}{} // acquire
+			defer func() { <-sem }()
+			results[i] = GeneratePDF(ctx, []*art.Article{a}, articleOpts)
+		}(i, a, articleOpts)
+	}
+	wg.Wait()
+	return results
+}
+
+// splitFileNames returns the file stem of each article's PDF for
+// GenerateSplitPDFs: its articleSlug, or tmpl expanded with that slug as
+// {title-slug} and 1 as {count}, with "-2", "-3", ... appended to repeats.
+func splitFileNames(articles []*art.Article, tmpl string, now time.Time) []string {
+	names := make([]string, len(articles))
+	used := make(map[string]int)
+	for i, a := range articles {
+		name := articleSlug(a, i+1)
+		if tmpl != "" {
+			if expanded := expandFilenameTemplate(tmpl, name, 1, now); expanded != "" {
+				name = expanded
+			}
+		}
+		used[name]++
+		if n := used[name]; n > 1 {
+			name = fmt.Sprintf("%s-%d", name, n)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+var (
+	slugUnsafe     = regexp.MustCompile(`[^a-z0-9]+`)
+	filenameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// slugify lower-cases s and collapses every run of non-alphanumerics into a
+// single hyphen, truncated to 80 characters.
+func slugify(s string) string {
+	const maxLen = 80
+	slug := strings.Trim(slugUnsafe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > maxLen {
+		slug = strings.TrimRight(slug[:maxLen], "-")
+	}
+	return slug
+}
+
+// articleSlug derives a filesystem-safe file stem for an article from its
+// title, then its URL's last path segment, then its position in the issue.
+func articleSlug(a *art.Article, num int) string {
+	candidates := []string{a.Title}
+	if a.Link != "" {
+		segs := strings.Split(strings.TrimRight(a.Link, "/"), "/")
+		candidates = append(candidates, strings.SplitN(segs[len(segs)-1], "?", 2)[0])
+	}
+	for _, c := range candidates {
+		if slug := slugify(c); slug != "" {
+			return slug
+		}
+	}
+	return fmt.Sprintf("article-%d", num)
+}
+
+// defaultOutputPath resolves the output path used when OutputPath is empty:
+// newspapers/articles_<timestamp>.pdf, or opts.FilenameTemplate with its
+// placeholders expanded:
+//
+//	{date}        2006-01-02
+//	{time}        150405
+//	{title-slug}  slug of opts.Title ("issue" when blank)
+//	{count}       number of articles
+//
+// The expanded name is sanitized to a single safe path element with a .pdf
+// extension.
+func defaultOutputPath(opts GenerateOptions, count int) string {
+	now := time.Now()
+	if opts.FilenameTemplate == "" {
+		return filepath.Join("newspapers", fmt.Sprintf("articles_%s.pdf", now.Format("20060102-150405")))
+	}
+
+	titleSlug := slugify(opts.Title)
+	if titleSlug == "" {
+		titleSlug = "issue"
+	}
+	name := expandFilenameTemplate(opts.FilenameTemplate, titleSlug, count, now)
+	if name == "" {
+		name = fmt.Sprintf("articles_%s", now.Format("20060102-150405"))
+	}
+	return filepath.Join("newspapers", name+".pdf")
+}
+
+// expandFilenameTemplate fills in the placeholders of a FilenameTemplate
+// (see defaultOutputPath) and returns the result as a file stem: any .pdf
+// extension dropped and unsafe characters replaced. Returns "" when
+// nothing usable is left.
+func expandFilenameTemplate(tmpl, titleSlug string, count int, now time.Time) string {
+	name := strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{title-slug}", titleSlug,
+		"{count}", fmt.Sprintf("%d", count),
+	).Replace(tmpl)
+
+	if strings.EqualFold(filepath.Ext(name), ".pdf") {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return strings.Trim(filenameUnsafe.ReplaceAllString(name, "-"), "-._")
+}
+
+// generateTypstPDF renders the newspaper layout via Typst.
+func generateTypstPDF(ctx context.Context, articles []*art.Article, opts GenerateOptions) (result GenerateResult) {
 	if len(articles) == 0 {
 		result.Error = fmt.Errorf("no articles provided")
 		return result
@@ -70,8 +496,7 @@ func generateTypstPDF(ctx context.Context, articles []*art.Article, opts Generat
 		opts.TypstPath = "typst"
 	}
 	if opts.OutputPath == "" {
-		timestamp := time.Now().Format("20060102-150405")
-		opts.OutputPath = filepath.Join("newspapers", fmt.Sprintf("articles_%s.pdf", timestamp))
+		opts.OutputPath = defaultOutputPath(opts, len(articles))
 	}
 
 	outDir := filepath.Dir(opts.OutputPath)
@@ -80,13 +505,23 @@ func generateTypstPDF(ctx context.Context, articles []*art.Article, opts Generat
 		return result
 	}
 
+	articles, err := prepareArticles(articles, opts)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if opts.DumpArticlesDir != "" {
+		if err := dumpArticles(articles, opts.DumpArticlesDir, opts.ImagesDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to dump article HTML: %v\n", err)
+		}
+	}
+
 	// Assemble the .typ document (dispatch by layout type)
 	var typContent string
-	var err error
 	if opts.LayoutType == "essay" {
-		typContent, err = AssembleEssayTypst(articles, opts.Title)
+		typContent, err = AssembleEssayTypstWithOptions(articles, opts)
 	} else {
-		typContent, err = AssembleNewspaperTypst(articles, opts.Title)
+		typContent, err = AssembleNewspaperTypstWithOptions(articles, opts)
 	}
 	if err != nil {
 		result.Error = fmt.Errorf("assemble typst: %w", err)
@@ -95,22 +530,30 @@ func generateTypstPDF(ctx context.Context, articles []*art.Article, opts Generat
 
 	// Convert any relative image paths (images/hash.ext) to absolute paths so
 	// Typst can find them regardless of where the .typ file is written.
-	absImagesDir, _ := filepath.Abs("images")
-	typContent = fixTypstImagePaths(typContent, absImagesDir)
+	typContent = fixTypstImagePaths(typContent, opts.ImagesDir)
+
+	balance := opts.BalanceColumns && opts.LayoutType != "essay"
+	if balance {
+		typContent += typstEndMarker()
+	}
 
-	// Write .typ source to a temp file in the same directory as the output PDF
-	typPath := opts.TempHTMLPath
-	if typPath == "" {
-		typPath = filepath.Join(outDir, fmt.Sprintf("temp_%d.typ", time.Now().UnixNano()))
-	} else if !strings.HasSuffix(typPath, ".typ") {
-		// Caller passed an .html debug path; honour it but use .typ extension
-		typPath = strings.TrimSuffix(typPath, ".html") + ".typ"
+	// Write .typ source into this render's temp directory
+	workDir, err := renderWorkDir()
+	if err != nil {
+		result.Error = err
+		return result
 	}
+	defer os.RemoveAll(workDir)
+	typPath := filepath.Join(workDir, "issue.typ")
 	if err := os.WriteFile(typPath, []byte(typContent), 0o644); err != nil {
 		result.Error = fmt.Errorf("write typst source: %w", err)
 		return result
 	}
-	result.HTMLPath = typPath
+	if opts.KeepHTML {
+		// Runs before the RemoveAll, and after failures too, which is when
+		// the source is most wanted
+		defer func() { result.HTMLPath = keepSource(typPath, opts, ".typ") }()
+	}
 
 	absTypPath, _ := filepath.Abs(typPath)
 	absPDFPath, _ := filepath.Abs(opts.OutputPath)
@@ -124,7 +567,7 @@ func generateTypstPDF(ctx context.Context, articles []*art.Article, opts Generat
 	var output []byte
 	var compileErr error
 	for i := 0; i < maxImgRetries; i++ {
-		cmd := exec.CommandContext(execCtx, opts.TypstPath, "compile", "--root", "/", absTypPath, absPDFPath)
+		cmd := exec.CommandContext(execCtx, opts.TypstPath, typstCompileArgs(opts, absTypPath, absPDFPath)...)
 		output, compileErr = cmd.CombinedOutput()
 		if compileErr == nil {
 			break
@@ -156,9 +599,13 @@ func generateTypstPDF(ctx context.Context, articles []*art.Article, opts Generat
 		fmt.Fprintf(os.Stderr, "typst output:\n%s\n", string(output))
 	}
 
-	if !opts.KeepHTML {
-		_ = os.Remove(typPath)
-		result.HTMLPath = ""
+	if balance {
+		balanceTypstColumns(execCtx, opts, typContent, absTypPath, absPDFPath)
+	}
+
+	if err := ValidatePDF(absPDFPath); err != nil {
+		result.Error = fmt.Errorf("typst produced an invalid PDF: %w", err)
+		return result
 	}
 
 	result.Success = true
@@ -166,10 +613,8 @@ func generateTypstPDF(ctx context.Context, articles []*art.Article, opts Generat
 	return result
 }
 
-// generateWkhtmlPDF renders the essay layout via wkhtmltopdf (unchanged path).
-func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts GenerateOptions) GenerateResult {
-	result := GenerateResult{}
-
+// generateWkhtmlPDF renders the original layout via wkhtmltopdf.
+func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts GenerateOptions) (result GenerateResult) {
 	if len(articles) == 0 {
 		result.Error = fmt.Errorf("no articles provided")
 		return result
@@ -200,9 +645,14 @@ func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts Genera
 	if opts.WkhtmltopdfPath == "" {
 		opts.WkhtmltopdfPath = "wkhtmltopdf"
 	}
+	// The Docker image ships only Typst, so say so up front rather than
+	// after the HTML is assembled and exec fails with a bare "not found".
+	if _, err := exec.LookPath(opts.WkhtmltopdfPath); err != nil {
+		result.Error = fmt.Errorf("the original layout needs wkhtmltopdf, which was not found (install it or set WkhtmltopdfPath): %w", err)
+		return result
+	}
 	if opts.OutputPath == "" {
-		timestamp := time.Now().Format("20060102-150405")
-		opts.OutputPath = filepath.Join("newspapers", fmt.Sprintf("articles_%s.pdf", timestamp))
+		opts.OutputPath = defaultOutputPath(opts, len(articles))
 	}
 
 	// Ensure output directory exists
@@ -212,8 +662,19 @@ func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts Genera
 		return result
 	}
 
+	articles, err := prepareArticles(articles, opts)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if opts.DumpArticlesDir != "" {
+		if err := dumpArticles(articles, opts.DumpArticlesDir, opts.ImagesDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to dump article HTML: %v\n", err)
+		}
+	}
+
 	// Generate combined HTML
-	html, err := AssembleHTML(articles, opts.Title, opts.LayoutType)
+	html, err := AssembleHTMLWithOptions(articles, opts)
 	if err != nil {
 		result.Error = fmt.Errorf("assemble html: %w", err)
 		return result
@@ -232,24 +693,37 @@ func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts Genera
 		}
 	}
 
+	if opts.HTMLTransform != nil {
+		transformed, err := opts.HTMLTransform(html)
+		if err != nil {
+			result.Error = fmt.Errorf("html transform: %w", err)
+			return result
+		}
+		html = transformed
+	}
+
 	// Fix image paths to be absolute file:// URLs for wkhtmltopdf (skip if images removed)
 	// This is necessary because wkhtmltopdf needs absolute paths when HTML file
 	// is in a different directory than the images
 	if !opts.RemoveImages {
-		absImagesDir, _ := filepath.Abs("images")
-		html = fixImagePaths(html, absImagesDir)
+		html = fixImagePaths(html, opts.ImagesDir)
 	}
 
-	// Write HTML to temp file
-	htmlPath := opts.TempHTMLPath
-	if htmlPath == "" {
-		htmlPath = filepath.Join(outDir, fmt.Sprintf("temp_%d.html", time.Now().UnixNano()))
+	// Write HTML into this render's temp directory
+	workDir, err := renderWorkDir()
+	if err != nil {
+		result.Error = err
+		return result
 	}
+	defer os.RemoveAll(workDir)
+	htmlPath := filepath.Join(workDir, "issue.html")
 	if err := os.WriteFile(htmlPath, []byte(html), 0o644); err != nil {
 		result.Error = fmt.Errorf("write html: %w", err)
 		return result
 	}
-	result.HTMLPath = htmlPath
+	if opts.KeepHTML {
+		defer func() { result.HTMLPath = keepSource(htmlPath, opts, ".html") }()
+	}
 
 	// Invoke wkhtmltopdf with xvfb-run wrapper for proper image rendering
 	absHTMLPath, _ := filepath.Abs(htmlPath)
@@ -282,12 +756,9 @@ func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts Genera
 		absPDFPath,
 	}
 
-	// Newspaper layout requires landscape orientation.
-	// The CSS @page rule should handle this, but wkhtmltopdf's CLI flag is
-	// more reliable than the CSS @page size directive.
-	if opts.LayoutType == "newspaper" || opts.LayoutType == "" {
-		args = append([]string{"--orientation", "Landscape"}, args...)
-	}
+	// Orientation is passed on the CLI: wkhtmltopdf's flag is more reliable
+	// than the CSS @page size directive.
+	args = append([]string{"--orientation", orientationOrDefault(opts)}, args...)
 
 	execCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
 	defer cancel()
@@ -310,10 +781,10 @@ func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts Genera
 		fmt.Fprintf(os.Stderr, "wkhtmltopdf output:\n%s\n", string(output))
 	}
 
-	// Cleanup temp HTML unless requested to keep
-	if !opts.KeepHTML {
-		_ = os.Remove(htmlPath)
-		result.HTMLPath = ""
+	// wkhtmltopdf can exit 0 after running out of memory mid-write
+	if err := ValidatePDF(absPDFPath); err != nil {
+		result.Error = fmt.Errorf("wkhtmltopdf produced an invalid PDF: %w", err)
+		return result
 	}
 
 	result.Success = true
@@ -321,6 +792,121 @@ func generateWkhtmlPDF(ctx context.Context, articles []*art.Article, opts Genera
 	return result
 }
 
+// prepareArticles loads content spilled to disk, then applies the optional
+// per-article content passes requested in opts and opts.ContentTransform,
+// scopes each article's in-page anchors (clean.ScopeAnchors) when there are
+// several, and gives articles without a cover image opts.DefaultCoverImage. The input
+// articles are never mutated; a shallow copy is returned for every article
+// whose content was loaded or changed or that took the default cover.
+func prepareArticles(articles []*art.Article, opts GenerateOptions) ([]*art.Article, error) {
+	if len(articles) < 2 && !opts.DedupeImages && !opts.ReaderMode && !opts.ScaleTables && !opts.ImageGalleries && !opts.Tagged && opts.HeadingOffset == 0 && opts.ContentTransform == nil && opts.DefaultCoverImage == "" && !anySpilled(articles) {
+		return articles, nil
+	}
+	prepared := make([]*art.Article, len(articles))
+	for i, a := range articles {
+		content, err := a.LoadContent()
+		if err != nil {
+			return nil, fmt.Errorf("load content for '%s': %w", a.Title, err)
+		}
+		if opts.ReaderMode {
+			simplified, err := clean.ReaderMode(content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: reader mode failed for '%s': %v\n", a.Title, err)
+			} else {
+				content = simplified
+			}
+		}
+		if opts.DedupeImages && !a.RemoveImages && !opts.RemoveImages {
+			deduped, removed, err := clean.DedupeConsecutiveImages(content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to dedupe images for '%s': %v\n", a.Title, err)
+			} else if removed > 0 {
+				content = deduped
+				fmt.Fprintf(os.Stderr, "Removed %d repeated image(s) from '%s'\n", removed, a.Title)
+			}
+		}
+		if opts.ImageGalleries && !a.RemoveImages && !opts.RemoveImages {
+			grouped, n, err := clean.GroupImageGalleries(content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to group image galleries for '%s': %v\n", a.Title, err)
+			} else if n > 0 {
+				content = grouped
+			}
+		}
+		if opts.ScaleTables {
+			fitted, n, err := clean.FitTables(content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to mark tables for '%s': %v\n", a.Title, err)
+			} else if n > 0 {
+				content = fitted
+			}
+		}
+
+		if opts.Tagged {
+			accessible, _, err := clean.AccessibleHTML(content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to add alt text and heading structure for '%s': %v\n", a.Title, err)
+			} else {
+				content = accessible
+			}
+		}
+		if opts.HeadingOffset > 0 {
+			demoted, _, err := clean.DemoteHeadings(content, opts.HeadingOffset)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to demote headings for '%s': %v\n", a.Title, err)
+			} else {
+				content = demoted
+			}
+		}
+
+		if opts.ContentTransform != nil {
+			transformed, err := opts.ContentTransform(a.Publication, content)
+			if err != nil {
+				return nil, fmt.Errorf("content transform for '%s': %w", a.Title, err)
+			}
+			content = transformed
+		}
+
+		// Footnote and other in-page anchors must stay within their own
+		// article once several share the document
+		if len(articles) > 1 {
+			scoped, _, err := clean.ScopeAnchors(content, fmt.Sprintf("a%d-", i+1))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to scope anchors for '%s': %v\n", a.Title, err)
+			} else {
+				content = scoped
+			}
+		}
+
+		cover := a.CoverImage
+		if cover == "" && !a.RemoveImages && !opts.RemoveImages && opts.DefaultCoverImage != "" {
+			// Absolute, as Typst resolves relative paths against the .typ file
+			cover, _ = filepath.Abs(opts.DefaultCoverImage)
+		}
+
+		prepared[i] = a
+		if content != a.Content || cover != a.CoverImage {
+			cp := *a
+			cp.Content = content
+			cp.ContentPath = ""
+			cp.CoverImage = cover
+			prepared[i] = &cp
+		}
+	}
+	return prepared, nil
+}
+
+// anySpilled reports whether any article's content lives on disk
+// (art.SpillContent) and must be loaded before assembly.
+func anySpilled(articles []*art.Article) bool {
+	for _, a := range articles {
+		if a.Content == "" && a.ContentPath != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // extractImagePathFromTypstError parses a Typst "failed to decode image" error
 // and returns the local file path that caused the failure.
 func extractImagePathFromTypstError(output string) string {
@@ -337,11 +923,15 @@ func extractImagePathFromTypstError(output string) string {
 	return output[start : start+end]
 }
 
-// stripBadImage removes the #figure(...) block that references the given image
-// path from the Typst source content, so compilation can be retried without it.
+// stripBadImage removes the #figure(...) block (or author avatar) that references
+// the given image path from the Typst source content, so compilation can be retried without it.
 func stripBadImage(typContent, imagePath string) string {
+	// Author avatars are a standalone box rather than a figure
+	if avatar := typstAvatar(imagePath); strings.Contains(typContent, avatar) {
+		return strings.ReplaceAll(typContent, avatar, "")
+	}
 	// Locate the image() call
-	searchFor := fmt.Sprintf("image(%q, width: 100%%),", imagePath)
+	searchFor := fmt.Sprintf("image(%s, width: 100%%", clean.TypstString(imagePath))
 	idx := strings.Index(typContent, searchFor)
 	if idx < 0 {
 		return typContent
@@ -362,21 +952,32 @@ func stripBadImage(typContent, imagePath string) string {
 
 // fixImagePaths converts relative image paths to absolute file:// URLs.
 // This is necessary for wkhtmltopdf to find images when the HTML file is in a different directory.
-func fixImagePaths(htmlContent string, absImagesDir string) string {
-	// Simple string replacement approach - replace relative image paths with absolute file:// URLs
-	// This handles the common case where images are in "images/filename.ext"
+// imagesDir is the directory the media downloader wrote to ("images" when empty).
+func fixImagePaths(htmlContent string, imagesDir string) string {
+	prefix, absImagesDir := imagePathPrefix(imagesDir)
 
 	// Replace src="images/ with src="file:///absolute/path/to/images/
-	htmlContent = strings.ReplaceAll(htmlContent, `src="images/`, fmt.Sprintf(`src="file://%s/`, absImagesDir))
+	htmlContent = strings.ReplaceAll(htmlContent, `src="`+prefix, fmt.Sprintf(`src="file://%s/`, absImagesDir))
 
 	// Also handle single quotes
-	htmlContent = strings.ReplaceAll(htmlContent, `src='images/`, fmt.Sprintf(`src='file://%s/`, absImagesDir))
+	htmlContent = strings.ReplaceAll(htmlContent, `src='`+prefix, fmt.Sprintf(`src='file://%s/`, absImagesDir))
 
 	return htmlContent
 }
 
 // fixTypstImagePaths rewrites relative "images/..." paths inside #image(...) calls to absolute
 // paths so Typst can find them regardless of where the .typ source file is written.
-func fixTypstImagePaths(typContent, absImagesDir string) string {
-	return strings.ReplaceAll(typContent, `image("images/`, fmt.Sprintf(`image("%s/`, absImagesDir))
+func fixTypstImagePaths(typContent, imagesDir string) string {
+	prefix, absImagesDir := imagePathPrefix(imagesDir)
+	return strings.ReplaceAll(typContent, `image("`+prefix, fmt.Sprintf(`image("%s/`, absImagesDir))
+}
+
+// imagePathPrefix returns the src prefix the media downloader writes for
+// images saved in imagesDir (e.g. "images/") and the directory's absolute path.
+func imagePathPrefix(imagesDir string) (string, string) {
+	if imagesDir == "" {
+		imagesDir = "images"
+	}
+	absImagesDir, _ := filepath.Abs(imagesDir)
+	return filepath.ToSlash(filepath.Clean(imagesDir)) + "/", absImagesDir
 }
@@ -0,0 +1,121 @@
+package pdf
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cssLengthRe matches a non-negative CSS length: a number with a unit, or a
+// bare zero.
+var cssLengthRe = regexp.MustCompile(`^(\d+(?:\.\d+)?|\.\d+)(px|pt|pc|mm|cm|in|em|rem|ex|%)$`)
+
+// maxTOCColumnRatio bounds GenerateOptions.TOCColumnRatio so the remaining
+// columns stay readable.
+const maxTOCColumnRatio = 4
+
+// ValidateCSSLength reports whether s is a non-negative CSS length such as
+// "12px", "0.5in", "2%" or "0".
+func ValidateCSSLength(s string) error {
+	if s == "0" || cssLengthRe.MatchString(s) {
+		return nil
+	}
+	return fmt.Errorf("invalid CSS length %q: want a number with a unit (px, pt, pc, mm, cm, in, em, rem, ex or %%)", s)
+}
+
+// ValidateTOCColumnRatio reports whether r is a usable TOCColumnRatio
+// (0 selects the default of equal columns).
+func ValidateTOCColumnRatio(r float64) error {
+	if r < 0 || r > maxTOCColumnRatio {
+		return fmt.Errorf("invalid TOC column ratio %g: must be between 0 and %d", r, maxTOCColumnRatio)
+	}
+	return nil
+}
+
+// splitCSSLength separates a validated CSS length into value and unit.
+func splitCSSLength(s string) (float64, string) {
+	m := cssLengthRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, "px"
+	}
+	v, _ := strconv.ParseFloat(m[1], 64)
+	return v, m[2]
+}
+
+// formatLength formats a length value without trailing zeros.
+func formatLength(v float64, unit string) string {
+	return strconv.FormatFloat(v, 'f', -1, 64) + unit
+}
+
+// npGridCSS returns the newspaper grid overrides for opts.Gutter,
+// opts.TOCColumnRatio and opts.NewspaperColumns, or "" when all are at their
+// defaults. The column count itself is applied by the table's cols-N class
+// in newspaper.css; --np-columns only exposes it. Like themeCSS
+// it declares --np-* custom properties for user stylesheets but writes the
+// rules with literal values, as wkhtmltopdf's WebKit lacks var() and calc().
+func npGridCSS(opts GenerateOptions, numCols int) string {
+	ratio := opts.TOCColumnRatio
+	if opts.Gutter == "" && (ratio == 0 || ratio == 1) && opts.NewspaperColumns == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(":root {")
+	if opts.NewspaperColumns != 0 {
+		fmt.Fprintf(&sb, " --np-columns: %d;", numCols)
+	}
+	if opts.Gutter != "" {
+		fmt.Fprintf(&sb, " --np-gutter: %s;", opts.Gutter)
+	}
+	if ratio > 0 {
+		fmt.Fprintf(&sb, " --np-toc-column-ratio: %g;", ratio)
+	}
+	sb.WriteString(" }\n")
+
+	if opts.Gutter != "" {
+		// The gutter is split between the facing paddings of adjacent columns
+		v, unit := splitCSSLength(opts.Gutter)
+		half := formatLength(v/2, unit)
+		fmt.Fprintf(&sb, ".page-col { padding: 0 %s; }\n", half)
+		sb.WriteString(".page-col:first-child { padding-left: 0; }\n")
+		sb.WriteString(".page-col:last-child { padding-right: 0; }\n")
+	}
+
+	if ratio > 0 && ratio != 1 {
+		// Only the first page carries the TOC; later pages keep equal columns
+		total := ratio + float64(numCols-1)
+		first := formatLength(float64(int(ratio/total*10000))/100, "%")
+		other := formatLength(float64(int(1/total*10000))/100, "%")
+		fmt.Fprintf(&sb, ".newspaper-page-first .page-table .page-col { width: %s; }\n", other)
+		fmt.Fprintf(&sb, ".newspaper-page-first .page-table .page-col:first-child { width: %s; }\n", first)
+	}
+	return sb.String()
+}
+
+// npGridStyle is npGridCSS typed for the HTML template.
+func npGridStyle(opts GenerateOptions, numCols int) template.CSS {
+	return template.CSS(npGridCSS(opts, numCols))
+}
+
+// typstLength converts a validated CSS length to Typst syntax: Typst has no
+// px, pc, rem or ex, so those are converted (96px = 72pt; rem and ex are
+// approximated from em).
+func typstLength(css string) string {
+	if css == "0" {
+		return "0pt"
+	}
+	v, unit := splitCSSLength(css)
+	switch unit {
+	case "px":
+		return formatLength(v*0.75, "pt")
+	case "pc":
+		return formatLength(v*12, "pt")
+	case "rem":
+		return formatLength(v, "em")
+	case "ex":
+		return formatLength(v/2, "em")
+	}
+	return formatLength(v, unit)
+}
@@ -0,0 +1,34 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+// The offset is applied after Tagged renumbers headings from h2, so it is
+// not undone, and it reaches content that never went through fetching.
+func TestPrepareArticlesHeadingOffset(t *testing.T) {
+	articles := []*art.Article{{Title: "From JSON", Content: "<h1>Top</h1><p>a</p><h2>Sub</h2><p>b</p>"}}
+	for _, tt := range []struct {
+		name string
+		opts GenerateOptions
+		want string
+	}{
+		{"offset only", GenerateOptions{HeadingOffset: 2}, "<h3>Top</h3><p>a</p><h4>Sub</h4>"},
+		{"tagged", GenerateOptions{Tagged: true, HeadingOffset: 1}, "<h3>Top</h3><p>a</p><h4>Sub</h4>"},
+		{"tagged without offset", GenerateOptions{Tagged: true}, "<h2>Top</h2><p>a</p><h3>Sub</h3>"},
+	} {
+		prepared, err := prepareArticles(articles, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := prepared[0].Content; !strings.Contains(got, tt.want) {
+			t.Errorf("%s: content = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if articles[0].Content != "<h1>Top</h1><p>a</p><h2>Sub</h2><p>b</p>" {
+		t.Error("prepareArticles mutated its input")
+	}
+}
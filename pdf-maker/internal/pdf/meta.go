@@ -0,0 +1,138 @@
+package pdf
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	art "pdf-maker/internal/article"
+)
+
+// metaPlaceholderRe matches one {name} placeholder in a MetaFormat.
+var metaPlaceholderRe = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// metaPlaceholders are the names a MetaFormat may use.
+var metaPlaceholders = map[string]bool{
+	"author":      true,
+	"publication": true,
+	"date":        true,
+	"updated":     true,
+}
+
+// ValidateMetaFormat reports whether f is a usable GenerateOptions.MetaFormat:
+// empty, or text with at least one placeholder and only the placeholders
+// {author}, {publication}, {date} and {updated}.
+func ValidateMetaFormat(f string) error {
+	if f == "" {
+		return nil
+	}
+	matches := metaPlaceholderRe.FindAllStringSubmatch(f, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("invalid meta format %q: no {author}, {publication}, {date} or {updated} placeholder", f)
+	}
+	for _, m := range matches {
+		if !metaPlaceholders[m[1]] {
+			return fmt.Errorf("invalid meta format %q: unknown placeholder {%s}", f, m[1])
+		}
+	}
+	if rest := metaPlaceholderRe.ReplaceAllString(f, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("invalid meta format %q: unbalanced braces", f)
+	}
+	return nil
+}
+
+// formatMeta composes a's byline from format (see GenerateOptions.MetaFormat),
+// writing dates with dateLayout. Empty placeholders are dropped together with
+// the separator before them (or after them, for the first value), so
+// "{author} · {publication} · {date}" with no publication gives
+// "Jane Doe · May 1, 2025". Returns plain text, or "" when every value is
+// empty; callers escape it for their markup.
+func formatMeta(format string, a *art.Article, dateLayout string) string {
+	value := func(name string) string {
+		switch name {
+		case "author":
+			return a.Author
+		case "publication":
+			return a.Publication
+		case "date":
+			if !a.PubDate.IsZero() {
+				return a.PubDate.Format(dateLayout)
+			}
+		case "updated":
+			if a.WasUpdated() {
+				return a.UpdatedDate.Format(dateLayout)
+			}
+		}
+		return ""
+	}
+
+	locs := metaPlaceholderRe.FindAllStringSubmatchIndex(format, -1)
+	if len(locs) == 0 {
+		return format
+	}
+	var sb strings.Builder
+	kept := 0
+	for i, loc := range locs {
+		v := strings.TrimSpace(value(format[loc[2]:loc[3]]))
+		if v == "" {
+			continue
+		}
+		if kept > 0 {
+			sb.WriteString(format[locs[i-1][1]:loc[0]]) // the separator before this value
+		}
+		sb.WriteString(v)
+		kept++
+	}
+	if kept == 0 {
+		return ""
+	}
+	return format[:locs[0][0]] + sb.String() + format[locs[len(locs)-1][1]:]
+}
+
+// issueDateLayout dates an issue header that shows a single day.
+const issueDateLayout = "Monday, January 2, 2006"
+
+// issueDate is the date shown in the issue header: with opts.DateRange the
+// span of the articles' publication dates (see dateRange), otherwise, or
+// when no article is dated, the generation date.
+func issueDate(articles []*art.Article, opts GenerateOptions) string {
+	if opts.DateRange {
+		if r := dateRange(articles); r != "" {
+			return r
+		}
+	}
+	return time.Now().Format(issueDateLayout)
+}
+
+// dateRange formats the span from the earliest to the latest PubDate of
+// articles, naming a shared month and year once: "Oct 1–7, 2025",
+// "Sep 28 – Oct 4, 2025", "Dec 29, 2025 – Jan 4, 2026". Articles all from
+// one day give that day in issueDateLayout. Undated articles are skipped;
+// returns "" when none is dated.
+func dateRange(articles []*art.Article) string {
+	var first, last time.Time
+	for _, a := range articles {
+		if a.PubDate.IsZero() {
+			continue
+		}
+		d := a.PubDate.In(time.Local)
+		if first.IsZero() || d.Before(first) {
+			first = d
+		}
+		if last.IsZero() || d.After(last) {
+			last = d
+		}
+	}
+	switch {
+	case first.IsZero():
+		return ""
+	case first.Year() != last.Year():
+		return first.Format("Jan 2, 2006") + " – " + last.Format("Jan 2, 2006")
+	case first.Month() != last.Month():
+		return first.Format("Jan 2") + " – " + last.Format("Jan 2, 2006")
+	case first.Day() != last.Day():
+		return fmt.Sprintf("%s–%d, %d", first.Format("Jan 2"), last.Day(), last.Year())
+	}
+	return first.Format(issueDateLayout)
+}
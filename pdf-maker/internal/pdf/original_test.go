@@ -0,0 +1,29 @@
+package pdf
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestOriginalLayoutNeedsWkhtmltopdf(t *testing.T) {
+	dir := t.TempDir()
+	articles := []*art.Article{{Title: "A", Link: "https://x.substack.com/p/a", Content: "<p>a</p>"}}
+	result := GeneratePDF(context.Background(), articles, GenerateOptions{
+		LayoutType:      "original",
+		OutputPath:      filepath.Join(dir, "out.pdf"),
+		WkhtmltopdfPath: filepath.Join(dir, "no-such-wkhtmltopdf"),
+	})
+	if result.Error == nil {
+		t.Fatal("GeneratePDF succeeded without a wkhtmltopdf binary")
+	}
+	if !strings.Contains(result.Error.Error(), "needs wkhtmltopdf") {
+		t.Errorf("error = %v, want it to name the missing wkhtmltopdf", result.Error)
+	}
+	if result.HTMLPath != "" {
+		t.Errorf("HTML was assembled (%s) before the binary check", result.HTMLPath)
+	}
+}
@@ -0,0 +1,57 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+// mixedDirectionIssue is an English article followed by a Hebrew one.
+func mixedDirectionIssue() []*art.Article {
+	return []*art.Article{
+		{Title: "English post", Link: "https://example.com/p/english", Content: "<p>Left to right.</p>"},
+		{Title: "מכתב מירושלים", Link: "https://example.org/p/letter", Language: "he-IL", Content: "<p>השבוע ירד גשם ראשון בעיר.</p>"},
+	}
+}
+
+func TestRTLArticleTypst(t *testing.T) {
+	for name, assemble := range map[string]func([]*art.Article, GenerateOptions) (string, error){
+		"newspaper": AssembleNewspaperTypstWithOptions,
+		"essay":     AssembleEssayTypstWithOptions,
+	} {
+		out, err := assemble(mixedDirectionIssue(), GenerateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		const scope = "#[\n#set text(lang: \"he\", dir: rtl)\n"
+		english, hebrew, ok := strings.Cut(out[strings.Index(out, "== English post"):], scope)
+		if !ok {
+			t.Errorf("%s: no right-to-left scope for the Hebrew article", name)
+			continue
+		}
+		if strings.Contains(english, "dir: rtl") {
+			t.Errorf("%s: the English article is set right-to-left", name)
+		}
+		if !strings.Contains(hebrew, "השבוע ירד גשם ראשון") || !strings.Contains(hebrew, "]\n") {
+			t.Errorf("%s: Hebrew article not inside its scope", name)
+		}
+	}
+}
+
+func TestRTLArticleHTML(t *testing.T) {
+	for _, layout := range []string{"newspaper", "essay", "original"} {
+		out, err := AssembleHTMLWithOptions(mixedDirectionIssue(), GenerateOptions{LayoutType: layout, AllowMissingStyles: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		english, _, _ := strings.Cut(out, "השבוע")
+		english = english[strings.Index(english, "English post"):]
+		if strings.Contains(english[:strings.Index(english, "Left to right.")], `dir="rtl"`) {
+			t.Errorf("%s: English article rendered right-to-left", layout)
+		}
+		if i := strings.LastIndex(english, `dir="rtl"`); i < strings.Index(english, "Left to right.") {
+			t.Errorf("%s: Hebrew article has no dir=\"rtl\" of its own", layout)
+		}
+	}
+}
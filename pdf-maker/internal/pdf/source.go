@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/clean"
+)
+
+// sourceQRPixels is the width of the saved QR code image. It is printed at
+// about 2cm, so this leaves several pixels per module even for long URLs.
+const sourceQRPixels = 256
+
+// sourceURL returns the address an article is attributed to: its canonical
+// URL when the page declared one, else the link it was fetched from.
+func sourceURL(a *art.Article) string {
+	if a.CanonicalURL != "" {
+		return a.CanonicalURL
+	}
+	return a.Link
+}
+
+// sourceLabel is the link text of the attribution line: the publication, or
+// the source host when the publication is unknown.
+func sourceLabel(a *art.Article, src string) string {
+	if a.Publication != "" {
+		return a.Publication
+	}
+	if u, err := url.Parse(src); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return src
+}
+
+// sourceLinkHTML returns the "Originally published at" line closing an
+// article when opts.ShowSourceLink is set, or "" when it is off or the
+// article has no URL. The full URL follows in parentheses because the link
+// itself is not clickable on paper.
+func sourceLinkHTML(a *art.Article, opts GenerateOptions) string {
+	src := sourceURL(a)
+	if !opts.ShowSourceLink || src == "" {
+		return ""
+	}
+	return fmt.Sprintf("<p class=\"article-source\">Originally published at <a href=\"%s\">%s</a> (%s)</p>\n",
+		html.EscapeString(src), html.EscapeString(sourceLabel(a, src)), html.EscapeString(src))
+}
+
+// typstSourceLink is the Typst counterpart of sourceLinkHTML.
+func typstSourceLink(a *art.Article, opts GenerateOptions, size string) string {
+	src := sourceURL(a)
+	if !opts.ShowSourceLink || src == "" {
+		return ""
+	}
+	return fmt.Sprintf("#text(size: %s, style: \"italic\")[Originally published at #link(%s)[%s] (#link(%s))]\n\n",
+		size, clean.TypstString(src), escapeTypstContent(sourceLabel(a, src)), clean.TypstString(src))
+}
+
+// sourceQR saves the QR code for a's source URL into the images directory
+// when opts.ShowSourceQR is set and returns its path, which starts with the
+// images directory like downloaded images do so that fixImagePaths and
+// fixTypstImagePaths resolve it. The file is named after the URL's hash, so
+// it is written once per source. Returns "" when the option is off, the
+// article has no URL, or the image could not be written.
+func sourceQR(a *art.Article, opts GenerateOptions) string {
+	src := sourceURL(a)
+	if !opts.ShowSourceQR || src == "" {
+		return ""
+	}
+	dir := opts.ImagesDir
+	if dir == "" {
+		dir = "images"
+	}
+	path := filepath.ToSlash(filepath.Join(dir, fmt.Sprintf("qr-%x.png", sha1.Sum([]byte(src)))))
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		err = qrcode.WriteFile(src, qrcode.Medium, sourceQRPixels, path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  skipping source QR code for %s: %v\n", src, err)
+		return ""
+	}
+	return path
+}
+
+// sourceQRHTML returns the QR code block closing an article when
+// opts.ShowSourceQR is set, or "" when there is none (see sourceQR). The
+// size is set inline because the "original" layout applies no project
+// stylesheet.
+func sourceQRHTML(a *art.Article, opts GenerateOptions) string {
+	path := sourceQR(a, opts)
+	if path == "" {
+		return ""
+	}
+	return fmt.Sprintf("<div class=\"article-source-qr\"><img src=\"%s\" alt=\"QR code linking to %s\" style=\"width: 2cm; height: 2cm;\"></div>\n",
+		html.EscapeString(path), html.EscapeString(sourceURL(a)))
+}
+
+// typstSourceQR is the Typst counterpart of sourceQRHTML. The image carries
+// alt text like every other image, which Tagged (PDF/UA-1) requires.
+func typstSourceQR(a *art.Article, opts GenerateOptions) string {
+	path := sourceQR(a, opts)
+	if path == "" {
+		return ""
+	}
+	return fmt.Sprintf("#align(right)[#image(%s, width: 2cm, alt: %s)]\n\n", clean.TypstString(path), clean.TypstString("QR code linking to "+sourceURL(a)))
+}
@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+
+	art "pdf-maker/internal/article"
+)
+
+// typstSummarySpread returns the "This Week in Review" page for
+// GenerateOptions.SummarySpread, or "" when it is off: a dedicated
+// single-column page listing every article's title (linked to the article),
+// byline, reading time and excerpt, set like a magazine contents spread.
+//
+// The page is emitted with #page, which ends the current page first, so it
+// follows the masthead page (the cover) and the articles start on a fresh
+// page after it. Long issues continue the spread onto further pages.
+func typstSummarySpread(articles []*art.Article, opts GenerateOptions) string {
+	if !opts.SummarySpread {
+		return ""
+	}
+	anchors := ArticleAnchors(articles)
+	landscape := orientationOrDefault(opts) == OrientationLandscape
+	var sb strings.Builder
+	sb.WriteString("#page(columns: 1)[\n")
+	sb.WriteString("#align(center)[#text(size: 22pt, weight: \"bold\")[This Week in Review]]\n")
+	sb.WriteString("#v(0.2em)\n")
+	sb.WriteString("#line(length: 100%, stroke: 1pt)\n")
+	sb.WriteString("#v(0.8em)\n")
+	if landscape && len(articles) > 3 {
+		sb.WriteString("#columns(2, gutter: 2em)[\n")
+	}
+	for i, a := range articles {
+		sb.WriteString("#block(breakable: false, below: 1.1em)[\n")
+		thumb := typstCoverThumb(a, opts, "4.5em")
+		columns := "(2.2em, 1fr)"
+		if thumb != "" {
+			columns = "(2.2em, 1fr, 4.5em)"
+		}
+		sb.WriteString(fmt.Sprintf("#grid(columns: %s, column-gutter: 0.6em,\n  text(size: 18pt, weight: \"bold\", fill: luma(140))[%d],\n  [\n", columns, i+1))
+		sb.WriteString(fmt.Sprintf("#link(<%s>)[#text(size: 13pt, weight: \"bold\")[%s]]\\\n",
+			anchors[i], escapeTypstContent(a.DisplayTitle())))
+		if meta := summaryMeta(a); meta != "" {
+			sb.WriteString(fmt.Sprintf("#text(size: 8.5pt, style: \"italic\", fill: luma(90))[%s]\n", escapeTypstContent(meta)))
+		}
+		if a.Excerpt != "" {
+			sb.WriteString(fmt.Sprintf("\n#text(size: 9.5pt)[%s]\n", escapeTypstContent(a.Excerpt)))
+		}
+		sb.WriteString("  ],\n")
+		if thumb != "" {
+			sb.WriteString("  " + thumb + ",\n")
+		}
+		sb.WriteString(")\n]\n")
+	}
+	if landscape && len(articles) > 3 {
+		sb.WriteString("]\n")
+	}
+	sb.WriteString("]\n\n")
+	return sb.String()
+}
+
+// summaryMeta is the spread's byline for a: author, publication and reading
+// time, e.g. "Jane Doe · The Weekly · 7 min read".
+func summaryMeta(a *art.Article) string {
+	var parts []string
+	if a.Author != "" {
+		parts = append(parts, a.Author)
+	}
+	if a.Publication != "" {
+		parts = append(parts, a.Publication)
+	}
+	if m := a.ReadingMinutes(); m > 0 {
+		parts = append(parts, fmt.Sprintf("%d min read", m))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,21 @@
+package pdf
+
+import (
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+// The newspaper keeps its IN THIS EDITION box with the spread on, after
+// the spread and before the first article.
+func TestSummarySpreadKeepsNewspaperTOC(t *testing.T) {
+	articles := []*art.Article{
+		{Title: "First", Link: "https://x.substack.com/p/first", Content: "<p>a</p>", Excerpt: "Opening."},
+		{Title: "Second", Link: "https://x.substack.com/p/second", Content: "<p>b</p>"},
+	}
+	src, err := AssembleNewspaperTypstWithOptions(articles, GenerateOptions{SummarySpread: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, src, "This Week in Review", "IN THIS EDITION", "== First", "== Second")
+}
@@ -0,0 +1,49 @@
+package pdf
+
+import (
+	"strings"
+
+	"pdf-maker/internal/clean"
+)
+
+// typstPDFStandard is the standard requested from Typst for
+// GenerateOptions.Tagged. PDF/UA-1 makes Typst emit a tagged PDF with a
+// logical reading order and fail the build on images without alt text, so
+// an inaccessible issue is never produced silently. Needs typst 0.14+.
+const typstPDFStandard = "ua-1"
+
+// typstCompileArgs returns the arguments for compiling typPath to pdfPath.
+func typstCompileArgs(opts GenerateOptions, typPath, pdfPath string) []string {
+	args := []string{"compile", "--root", "/"}
+	if opts.Tagged {
+		args = append(args, "--pdf-standard", typstPDFStandard)
+	}
+	return append(args, typPath, pdfPath)
+}
+
+// typstDocumentRules sets the PDF's document properties: the title PDF/UA
+// requires when opts.Tagged is on, and opts.Author, opts.Subject and
+// opts.Keywords when given. Returns "" when there is nothing to set.
+func typstDocumentRules(opts GenerateOptions) string {
+	var params []string
+	if opts.Tagged || opts.Author != "" || opts.Subject != "" || len(opts.Keywords) > 0 {
+		params = append(params, "title: "+clean.TypstString(opts.Title))
+	}
+	if opts.Author != "" {
+		params = append(params, "author: "+clean.TypstString(opts.Author))
+	}
+	if opts.Subject != "" {
+		params = append(params, "description: "+clean.TypstString(opts.Subject))
+	}
+	if len(opts.Keywords) > 0 {
+		quoted := make([]string, len(opts.Keywords))
+		for i, k := range opts.Keywords {
+			quoted[i] = clean.TypstString(k) + "," // trailing comma keeps a single keyword an array
+		}
+		params = append(params, "keywords: ("+strings.Join(quoted, " ")+")")
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "#set document(" + strings.Join(params, ", ") + ")\n"
+}
@@ -0,0 +1,39 @@
+package pdf
+
+import "testing"
+
+func TestTypstDocumentRules(t *testing.T) {
+	tests := []struct {
+		name string
+		opts GenerateOptions
+		want string
+	}{
+		{"nothing to set", GenerateOptions{Title: "Weekly"}, ""},
+		{"tagged needs a title", GenerateOptions{Title: "Weekly", Tagged: true}, `#set document(title: "Weekly")` + "\n"},
+		{
+			"single keyword stays an array",
+			GenerateOptions{Title: "Weekly", Keywords: []string{"economics"}},
+			`#set document(title: "Weekly", keywords: ("economics",))` + "\n",
+		},
+		{
+			"several keywords",
+			GenerateOptions{Title: "Weekly", Keywords: []string{"a", "b"}},
+			`#set document(title: "Weekly", keywords: ("a", "b",))` + "\n",
+		},
+		{
+			"quotes and backslashes",
+			GenerateOptions{Title: `The "Best" of C:\Letters`, Author: `Ann "AJ" Example`, Subject: "Line one\nline two"},
+			`#set document(title: "The \"Best\" of C:\\Letters", author: "Ann \"AJ\" Example", description: "Line one\nline two")` + "\n",
+		},
+		{
+			"non-printable and non-ASCII",
+			GenerateOptions{Title: "Zürich\u200bNotes\x01", Tagged: true},
+			`#set document(title: "Zürich\u{200b}Notes\u{1}")` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := typstDocumentRules(tt.opts); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
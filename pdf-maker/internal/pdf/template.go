@@ -6,22 +6,23 @@ import (
 	"fmt"
 	"html"
 	"html/template"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
 
 	art "pdf-maker/internal/article"
 	"pdf-maker/internal/clean"
 )
 
-//go:embed templates/newspaper.gohtml templates/essay.gohtml
+//go:embed templates/newspaper.gohtml templates/essay.gohtml templates/original.gohtml
 var layoutTemplates embed.FS
 
 // Package-level parsed templates — parsed once at program start.
 var (
 	newspaperTmpl = template.Must(template.ParseFS(layoutTemplates, "templates/newspaper.gohtml"))
 	essayTmpl     = template.Must(template.ParseFS(layoutTemplates, "templates/essay.gohtml"))
+	originalTmpl  = template.Must(template.ParseFS(layoutTemplates, "templates/original.gohtml"))
 )
 
 // npColumn holds pre-rendered HTML parts for one table column.
@@ -35,17 +36,30 @@ type npPage struct {
 	Columns []npColumn
 }
 
+// issueSection is a custom HTML block (editor's note, colophon) inserted
+// after the issue header or at the end of the issue.
+type issueSection struct {
+	HTML      template.HTML
+	PageBreak bool // header: break after the section; footer: break before it
+}
+
 // npData is the data struct passed to templates/newspaper.gohtml.
 type npData struct {
-	CSSPath  template.URL
-	Title    string
-	Subtitle string
-	Pages    []npPage
+	CSSPath      template.URL
+	ThemeCSS     template.CSS
+	WatermarkCSS template.CSS
+	Watermark    string
+	GridCSS      template.CSS
+	Title        string
+	Subtitle     string
+	Header       *issueSection
+	Pages        []npPage
+	Footer       *issueSection
 }
 
 // essayTOCEntry is one line item in the essay Table of Contents.
 type essayTOCEntry struct {
-	Num         int
+	Anchor      string
 	Title       string
 	Author      string
 	Publication string
@@ -53,26 +67,60 @@ type essayTOCEntry struct {
 
 // essayData is the data struct passed to templates/essay.gohtml.
 type essayData struct {
-	CSSPath  template.URL
-	Title    string
-	Subtitle string
-	TOC      []essayTOCEntry
-	Articles []template.HTML
+	CSSPath      template.URL
+	ThemeCSS     template.CSS
+	WatermarkCSS template.CSS
+	Watermark    string
+	Title        string
+	Subtitle     string
+	Header       *issueSection
+	TOC          []essayTOCEntry
+	Articles     []template.HTML
+	Footer       *issueSection
+}
+
+// originalData is the data struct passed to templates/original.gohtml.
+type originalData struct {
+	Title        string
+	Styles       []template.CSS
+	ThemeCSS     template.CSS
+	WatermarkCSS template.CSS
+	Watermark    string
+	Header       *issueSection
+	Articles     []template.HTML
+	Footer       *issueSection
 }
 
 // AssembleHTML builds the complete HTML document for the given layout.
-// layoutType can be "essay" or "newspaper" (default).
-// HTML structure is driven by templates/newspaper.gohtml or templates/essay.gohtml.
+// layoutType can be "essay", "original" or "newspaper" (default).
+// HTML structure is driven by templates/newspaper.gohtml, templates/essay.gohtml
+// or templates/original.gohtml.
 func AssembleHTML(articles []*art.Article, title string, layoutType ...string) (string, error) {
+	opts := GenerateOptions{Title: title}
+	if len(layoutType) > 0 {
+		opts.LayoutType = layoutType[0]
+	}
+	return AssembleHTMLWithOptions(articles, opts)
+}
+
+// AssembleHTMLWithOptions builds the complete HTML document using the title,
+// layout and per-article rendering options (e.g. DropCaps) from opts.
+func AssembleHTMLWithOptions(articles []*art.Article, opts GenerateOptions) (string, error) {
 	layout := "newspaper"
-	if len(layoutType) > 0 && layoutType[0] != "" {
-		if layoutType[0] == "essay" || layoutType[0] == "newspaper" {
-			layout = layoutType[0]
-		}
+	if opts.LayoutType == "essay" || opts.LayoutType == "original" {
+		layout = opts.LayoutType
 	}
+	title := opts.Title
 
 	cssAbsPath, _ := filepath.Abs(fmt.Sprintf("styles/%s.css", layout))
 	cssURL := template.URL("file://" + cssAbsPath)
+	// The stylesheet is only linked, so a missing file would otherwise yield
+	// a silently unstyled document. "original" uses the pages' own CSS.
+	if layout != "original" && !opts.AllowMissingStyles {
+		if _, err := os.Stat(cssAbsPath); err != nil {
+			return "", fmt.Errorf("stylesheet for %s layout: %w (set AllowMissingStyles to render without it)", layout, err)
+		}
+	}
 
 	articleCount := len(articles)
 	articleWord := "Articles"
@@ -80,16 +128,41 @@ func AssembleHTML(articles []*art.Article, title string, layoutType ...string) (
 		articleWord = "Article"
 	}
 	subtitle := fmt.Sprintf("%s \u2022 %d %s",
-		time.Now().Format("Monday, January 2, 2006"), articleCount, articleWord)
+		issueDate(articles, opts), articleCount, articleWord)
+
+	header, err := buildIssueSection(opts.HeaderHTML, opts.HeaderPageBreak)
+	if err != nil {
+		return "", fmt.Errorf("header html: %w", err)
+	}
+	footer, err := buildIssueSection(opts.FooterHTML, opts.FooterPageBreak)
+	if err != nil {
+		return "", fmt.Errorf("footer html: %w", err)
+	}
 
 	var buf bytes.Buffer
+	if layout == "original" {
+		data := buildOriginalData(articles, opts)
+		data.Header, data.Footer = header, footer
+		data.ThemeCSS = themeStyle(opts)
+		data.WatermarkCSS, data.Watermark = watermarkStyle(opts), strings.TrimSpace(opts.Watermark)
+		if err := originalTmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("original template: %w", err)
+		}
+		return buf.String(), nil
+	}
 	if layout == "newspaper" {
-		data := buildNewspaperData(articles, cssURL, title, subtitle)
+		data := buildNewspaperData(articles, cssURL, title, subtitle, opts)
+		data.Header, data.Footer = header, footer
+		data.ThemeCSS = themeStyle(opts)
+		data.WatermarkCSS, data.Watermark = watermarkStyle(opts), strings.TrimSpace(opts.Watermark)
 		if err := newspaperTmpl.Execute(&buf, data); err != nil {
 			return "", fmt.Errorf("newspaper template: %w", err)
 		}
 	} else {
-		data := buildEssayData(articles, cssURL, title, subtitle)
+		data := buildEssayData(articles, cssURL, title, subtitle, opts)
+		data.Header, data.Footer = header, footer
+		data.ThemeCSS = themeStyle(opts)
+		data.WatermarkCSS, data.Watermark = watermarkStyle(opts), strings.TrimSpace(opts.Watermark)
 		if err := essayTmpl.Execute(&buf, data); err != nil {
 			return "", fmt.Errorf("essay template: %w", err)
 		}
@@ -97,6 +170,19 @@ func AssembleHTML(articles []*art.Article, title string, layoutType ...string) (
 	return buf.String(), nil
 }
 
+// buildIssueSection sanitizes custom section HTML with the same cleaning pass
+// applied to article content. Returns nil when rawHTML is blank.
+func buildIssueSection(rawHTML string, pageBreak bool) (*issueSection, error) {
+	if strings.TrimSpace(rawHTML) == "" {
+		return nil, nil
+	}
+	cleaned, _, err := clean.CleanHTML(rawHTML, false)
+	if err != nil {
+		return nil, err
+	}
+	return &issueSection{HTML: template.HTML(cleaned), PageBreak: pageBreak}, nil
+}
+
 // ---------------------------------------------------------------------------
 // Newspaper layout helpers
 // ---------------------------------------------------------------------------
@@ -161,14 +247,15 @@ func npEstChars(h string) int {
 
 // npTOCHTML builds the IN THIS EDITION TOC box HTML.
 func npTOCHTML(articles []*art.Article) string {
+	anchors := ArticleAnchors(articles)
 	var sb strings.Builder
 	sb.WriteString("<div class=\"toc\">\n")
 	sb.WriteString("  <h2>IN THIS EDITION</h2>\n")
 	sb.WriteString("  <ul>\n")
 	for i, a := range articles {
 		sb.WriteString("    <li>\n")
-		sb.WriteString(fmt.Sprintf("      <a href=\"#article-%d\">\n", i+1))
-		sb.WriteString(fmt.Sprintf("        <span class=\"toc-title\">%s</span>\n", html.EscapeString(a.Title)))
+		sb.WriteString(fmt.Sprintf("      <a href=\"#%s\">\n", anchors[i]))
+		sb.WriteString(fmt.Sprintf("        <span class=\"toc-title\">%s</span>\n", html.EscapeString(a.DisplayTitle())))
 		var parts []string
 		if a.Author != "" {
 			parts = append(parts, html.EscapeString(a.Author))
@@ -179,6 +266,9 @@ func npTOCHTML(articles []*art.Article) string {
 		if len(parts) > 0 {
 			sb.WriteString(fmt.Sprintf("        <span class=\"toc-byline\">%s</span>\n", strings.Join(parts, ", ")))
 		}
+		if a.Excerpt != "" {
+			sb.WriteString(fmt.Sprintf("        <span class=\"toc-excerpt\">%s</span>\n", html.EscapeString(a.Excerpt)))
+		}
 		sb.WriteString("      </a>\n")
 		sb.WriteString("    </li>\n")
 	}
@@ -312,7 +402,7 @@ func distributeToColumns(parts []pagePart, numCols int) [][]pagePart {
 //
 // CSS column-count is NOT used: Qt WebKit 5.15 in wkhtmltopdf does not
 // reliably activate it. Table-based columns work without any special tricks.
-func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, subtitle string) npData {
+func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, subtitle string, opts GenerateOptions) npData {
 	// Page capacity in estimated visible characters (images counted by actual
 	// aspect ratio; text at 10pt/48 chars per line on a 3.3in column).
 	// US Letter landscape, 0.5in margins → 10in × 7.5in usable.
@@ -332,11 +422,13 @@ func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, sub
 	}
 
 	var chunks []chunk
+	anchors := ArticleAnchors(articles)
 	for i, a := range articles {
-		headerHTML := renderArticleHeader(a, i+1)
+		displayTitle := a.DisplayTitle()
+		headerHTML := renderArticleHeader(a, anchors[i], opts)
 		chunks = append(chunks, chunk{
 			artNum:   i + 1,
-			artTitle: a.Title,
+			artTitle: displayTitle,
 			isHeader: true,
 			html:     headerHTML,
 			chars:    npEstChars(headerHTML),
@@ -348,23 +440,45 @@ func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, sub
 				content = cleaned
 			}
 		}
+		if opts.DropCaps {
+			content = withDropCap(content)
+		}
 
 		// Extract top-level block elements (handles Substack outer wrapper divs).
 		// Each block is self-contained — no unclosed parent divs that would nest
 		// .newspaper-page divs inside each other and break page-break-before.
 		blocks := clean.ExtractBlocks(content)
 		for _, blk := range blocks {
+			// Blocks are scattered across table columns, so each one carries
+			// the article's language and direction rather than a shared container.
+			blk = withBlockAttrs(blk, langDirAttrs(a))
 			chunks = append(chunks, chunk{
 				artNum:   i + 1,
-				artTitle: a.Title,
+				artTitle: displayTitle,
 				isHeader: false,
 				html:     blk,
 				chars:    npEstChars(blk),
 			})
 		}
+		if src := sourceLinkHTML(a, opts) + sourceQRHTML(a, opts); src != "" {
+			chunks = append(chunks, chunk{
+				artNum:   i + 1,
+				artTitle: displayTitle,
+				html:     src,
+				chars:    npEstChars(src),
+			})
+		}
+		for _, blk := range commentBlocks(a, opts) {
+			chunks = append(chunks, chunk{
+				artNum:   i + 1,
+				artTitle: displayTitle,
+				html:     blk,
+				chars:    npEstChars(blk),
+			})
+		}
 		chunks = append(chunks, chunk{
 			artNum:   i + 1,
-			artTitle: a.Title,
+			artTitle: displayTitle,
 			html:     "<hr class=\"article-sep\">\n",
 			chars:    0, // zero so a separator never triggers a page flush alone
 		})
@@ -423,13 +537,14 @@ func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, sub
 	}
 
 	// Convert raw pages into npPage structs for the template.
+	numCols := newspaperColumns(opts)
 	pages := make([]npPage, len(rawPages))
 	for i, pg := range rawPages {
 		cls := "newspaper-page"
 		if pg.first {
 			cls += " newspaper-page-first"
 		}
-		dist := distributeToColumns(pg.parts, 3)
+		dist := distributeToColumns(pg.parts, numCols)
 		ncols := make([]npColumn, len(dist))
 		for j, col := range dist {
 			parts := make([]template.HTML, len(col))
@@ -443,6 +558,7 @@ func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, sub
 
 	return npData{
 		CSSPath:  cssURL,
+		GridCSS:  npGridStyle(opts, numCols),
 		Title:    title,
 		Subtitle: subtitle,
 		Pages:    pages,
@@ -450,19 +566,20 @@ func buildNewspaperData(articles []*art.Article, cssURL template.URL, title, sub
 }
 
 // buildEssayData assembles the essayData struct consumed by templates/essay.gohtml.
-func buildEssayData(articles []*art.Article, cssURL template.URL, title, subtitle string) essayData {
+func buildEssayData(articles []*art.Article, cssURL template.URL, title, subtitle string, opts GenerateOptions) essayData {
+	anchors := ArticleAnchors(articles)
 	toc := make([]essayTOCEntry, len(articles))
 	for i, a := range articles {
 		toc[i] = essayTOCEntry{
-			Num:         i + 1,
-			Title:       a.Title,
+			Anchor:      anchors[i],
+			Title:       a.DisplayTitle(),
 			Author:      a.Author,
 			Publication: a.Publication,
 		}
 	}
 	arts := make([]template.HTML, len(articles))
 	for i, a := range articles {
-		arts[i] = template.HTML(renderArticle(a, i+1))
+		arts[i] = template.HTML(renderArticle(a, anchors[i], opts))
 	}
 	return essayData{
 		CSSPath:  cssURL,
@@ -473,15 +590,80 @@ func buildEssayData(articles []*art.Article, cssURL template.URL, title, subtitl
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Original layout helpers
+// ---------------------------------------------------------------------------
+
+// buildOriginalData renders each article with the source page's own markup
+// classes (post-title, subtitle, available-content) and collects the pages'
+// CSS, de-duplicated so articles from the same publication share one copy.
+// No project stylesheet is applied.
+func buildOriginalData(articles []*art.Article, opts GenerateOptions) originalData {
+	anchors := ArticleAnchors(articles)
+	data := originalData{Title: opts.Title}
+	seen := make(map[string]bool)
+	for i, a := range articles {
+		if css := strings.TrimSpace(a.PageCSS); css != "" && !seen[css] {
+			seen[css] = true
+			data.Styles = append(data.Styles, template.CSS(css))
+		}
+		data.Articles = append(data.Articles, template.HTML(renderOriginalArticle(a, anchors[i], opts)))
+	}
+	return data
+}
+
+// renderOriginalArticle reproduces a Substack post header and body using the
+// class names Substack's stylesheets target.
+func renderOriginalArticle(a *art.Article, anchor string, opts GenerateOptions) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<article class=\"original-article post%s\" id=\"%s\"%s>\n", extraClasses(a), anchor, langDirAttrs(a)))
+	sb.WriteString("  <div class=\"post-header\">\n")
+	sb.WriteString(fmt.Sprintf("    <h1 class=\"post-title published\">%s</h1>\n", html.EscapeString(a.DisplayTitle())))
+	if a.Subtitle != "" {
+		sb.WriteString(fmt.Sprintf("    <h3 class=\"subtitle\">%s</h3>\n", html.EscapeString(a.Subtitle)))
+	}
+	meta := []string{}
+	if a.Author != "" {
+		meta = append(meta, html.EscapeString(a.Author))
+	}
+	if !a.PubDate.IsZero() {
+		meta = append(meta, a.PubDate.Format("Jan 02, 2006"))
+	}
+	if a.WasUpdated() {
+		meta = append(meta, "Updated "+a.UpdatedDate.Format("Jan 02, 2006"))
+	}
+	line := strings.Join(meta, " · ")
+	if opts.MetaFormat != "" {
+		line = html.EscapeString(formatMeta(opts.MetaFormat, a, "Jan 02, 2006"))
+	}
+	if line != "" {
+		sb.WriteString(fmt.Sprintf("    <div class=\"byline-wrapper\">%s</div>\n", line))
+	}
+	sb.WriteString("  </div>\n")
+
+	content := a.Content
+	if a.RemoveImages {
+		if cleaned, _, err := clean.RemoveAllImages(a.Content); err == nil {
+			content = cleaned
+		}
+	}
+	sb.WriteString("  <div class=\"available-content\">\n")
+	sb.WriteString(content)
+	sb.WriteString("\n  </div>\n")
+	sb.WriteString(sourceQRHTML(a, opts))
+	sb.WriteString("</article>\n\n")
+	return sb.String()
+}
+
 // renderArticleHeader generates just the header block for an article (title,
 // subtitle, meta). Used by buildNewspaper to treat the header as an
 // unbreakable chunk that must not be split from the first paragraph.
 // renderArticleHeader generates a self-contained article header block.
 // It closes all opened divs so it never leaves unclosed tags in a page section.
-func renderArticleHeader(a *art.Article, num int) string {
+func renderArticleHeader(a *art.Article, anchor string, opts GenerateOptions) string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("<div class=\"article-header\" id=\"article-%d\">\n", num))
-	sb.WriteString(fmt.Sprintf("  <h2 class=\"article-title\">%s</h2>\n", html.EscapeString(a.Title)))
+	sb.WriteString(fmt.Sprintf("<div class=\"article-header%s\" id=\"%s\"%s>\n", extraClasses(a), anchor, langDirAttrs(a)))
+	sb.WriteString(fmt.Sprintf("  <h2 class=\"article-title\">%s</h2>\n", html.EscapeString(a.DisplayTitle())))
 	if a.Subtitle != "" {
 		sb.WriteString(fmt.Sprintf("  <h3 class=\"article-subtitle\">%s</h3>\n", html.EscapeString(a.Subtitle)))
 	}
@@ -495,22 +677,33 @@ func renderArticleHeader(a *art.Article, num int) string {
 	if !a.PubDate.IsZero() {
 		meta = append(meta, a.PubDate.Format("January 2, 2006"))
 	}
-	if len(meta) > 0 {
-		sb.WriteString(fmt.Sprintf("  <p class=\"article-meta\">%s</p>\n", strings.Join(meta, " • ")))
+	if a.WasUpdated() {
+		meta = append(meta, "Updated "+a.UpdatedDate.Format("January 2, 2006"))
+	}
+	line := strings.Join(meta, " • ")
+	if opts.MetaFormat != "" {
+		line = html.EscapeString(formatMeta(opts.MetaFormat, a, "January 2, 2006"))
+	}
+	if line != "" {
+		avatar := ""
+		if opts.ShowAvatars {
+			avatar = avatarHTML(a)
+		}
+		sb.WriteString(fmt.Sprintf("  <p class=\"article-meta\">%s%s</p>\n", avatar, line))
 	}
 	sb.WriteString("</div>\n")
 	return sb.String()
 }
 
 // renderArticle generates the HTML for a single article section.
-func renderArticle(a *art.Article, num int) string {
+func renderArticle(a *art.Article, anchor string, opts GenerateOptions) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("<div class=\"article\" id=\"article-%d\">\n", num))
+	sb.WriteString(fmt.Sprintf("<div class=\"article%s\" id=\"%s\"%s>\n", extraClasses(a), anchor, langDirAttrs(a)))
 
 	// Article header
 	sb.WriteString("  <div class=\"article-header\">\n")
-	sb.WriteString(fmt.Sprintf("    <h2 class=\"article-title\">%s</h2>\n", html.EscapeString(a.Title)))
+	sb.WriteString(fmt.Sprintf("    <h2 class=\"article-title\">%s</h2>\n", html.EscapeString(a.DisplayTitle())))
 
 	if a.Subtitle != "" {
 		sb.WriteString(fmt.Sprintf("    <h3 class=\"article-subtitle\">%s</h3>\n", html.EscapeString(a.Subtitle)))
@@ -527,8 +720,19 @@ func renderArticle(a *art.Article, num int) string {
 	if !a.PubDate.IsZero() {
 		meta = append(meta, a.PubDate.Format("January 2, 2006"))
 	}
-	if len(meta) > 0 {
-		sb.WriteString(fmt.Sprintf("    <p class=\"article-meta\">%s</p>\n", strings.Join(meta, " • ")))
+	if a.WasUpdated() {
+		meta = append(meta, "Updated "+a.UpdatedDate.Format("January 2, 2006"))
+	}
+	line := strings.Join(meta, " • ")
+	if opts.MetaFormat != "" {
+		line = html.EscapeString(formatMeta(opts.MetaFormat, a, "January 2, 2006"))
+	}
+	if line != "" {
+		avatar := ""
+		if opts.ShowAvatars {
+			avatar = avatarHTML(a)
+		}
+		sb.WriteString(fmt.Sprintf("    <p class=\"article-meta\">%s%s</p>\n", avatar, line))
 	}
 
 	sb.WriteString("  </div>\n\n")
@@ -546,11 +750,71 @@ func renderArticle(a *art.Article, num int) string {
 		}
 	}
 
+	if opts.DropCaps {
+		articleContent = withDropCap(articleContent)
+	}
+
 	sb.WriteString("  <div class=\"article-content\">\n")
 	sb.WriteString(articleContent)
 	sb.WriteString("\n  </div>\n")
+	sb.WriteString(sourceLinkHTML(a, opts))
+	sb.WriteString(sourceQRHTML(a, opts))
+	sb.WriteString(commentsHTML(a, opts))
 
 	sb.WriteString("</div>\n\n")
 
 	return sb.String()
 }
+
+// avatarHTML returns the byline avatar image for a, or "" when none was found.
+func avatarHTML(a *art.Article) string {
+	if a.AuthorAvatar == "" {
+		return ""
+	}
+	return fmt.Sprintf("<img class=\"author-avatar\" src=\"%s\" alt=\"\">", html.EscapeString(a.AuthorAvatar))
+}
+
+// withDropCap marks the first letter of content for drop-cap styling, returning
+// content unchanged when it does not open with a text paragraph.
+func withDropCap(content string) string {
+	marked, ok, err := clean.AddDropCap(content)
+	if err != nil || !ok {
+		return content
+	}
+	return marked
+}
+
+// langDirAttrs returns the ` lang="..."` and ` dir="rtl"` attributes for an
+// article's container. Attributes are omitted when the language is unknown
+// or the article is left-to-right, so such output is unchanged.
+func langDirAttrs(a *art.Article) string {
+	attrs := ""
+	if a.Language != "" {
+		attrs += fmt.Sprintf(` lang="%s"`, html.EscapeString(a.Language))
+	}
+	if a.IsRTL() {
+		attrs += ` dir="rtl"`
+	}
+	return attrs
+}
+
+// extraClasses returns the article's sanitized CSSClass names, each preceded
+// by a space, for appending to its container's class attribute.
+func extraClasses(a *art.Article) string {
+	classes := a.CSSClasses()
+	if len(classes) == 0 {
+		return ""
+	}
+	return " " + strings.Join(classes, " ")
+}
+
+var blockOpenTagRe = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9]*)`)
+
+// withBlockAttrs inserts attrs into the opening tag of a top-level block
+// element. Text-only blocks (no opening tag) are returned unchanged.
+func withBlockAttrs(blk, attrs string) string {
+	if attrs == "" {
+		return blk
+	}
+	return blockOpenTagRe.ReplaceAllString(blk, "<${1}"+attrs)
+}
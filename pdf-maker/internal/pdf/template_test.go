@@ -0,0 +1,21 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestOriginalLayoutGalleryCSS(t *testing.T) {
+	articles := []*art.Article{{Title: "Photos", Content: `<div class="gallery gallery-3"><img src="1.jpg"><img src="2.jpg"><img src="3.jpg"></div>`}}
+	out, err := AssembleHTMLWithOptions(articles, GenerateOptions{LayoutType: "original", ImageGalleries: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<div class="gallery gallery-3">`, ".gallery-3 > * { width: 33.333%; }", ".gallery-2 > * { width: 50%; }"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("original layout lacks %q", want)
+		}
+	}
+}
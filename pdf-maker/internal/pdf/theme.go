@@ -0,0 +1,127 @@
+package pdf
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Color themes accepted by GenerateOptions.Theme.
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+	ThemeSepia = "sepia"
+)
+
+// themePalette holds the colors a theme applies to the page.
+type themePalette struct {
+	Background string
+	Text       string
+	Muted      string // bylines, dates, captions
+	Link       string
+	Rule       string // table and divider lines
+	Stripe     string // zebra fill for table rows
+}
+
+// themePalettes maps the non-default themes to their colors. The light theme
+// has no entry: it leaves the layout stylesheets untouched.
+var themePalettes = map[string]themePalette{
+	ThemeDark: {
+		Background: "#1e1e1e",
+		Text:       "#e0e0e0",
+		Muted:      "#a0a0a0",
+		Link:       "#8ab4f8",
+		Rule:       "#5a5a5a",
+		Stripe:     "#2a2a2a",
+	},
+	ThemeSepia: {
+		Background: "#f4ecd8",
+		Text:       "#433422",
+		Muted:      "#7a6a55",
+		Link:       "#7b4a12",
+		Rule:       "#b8a888",
+		Stripe:     "#ebe0c6",
+	},
+}
+
+// ValidateTheme reports whether t is a supported Theme value
+// (case-insensitive; empty selects the light theme).
+func ValidateTheme(t string) error {
+	switch strings.ToLower(t) {
+	case "", ThemeLight, ThemeDark, ThemeSepia:
+		return nil
+	}
+	return fmt.Errorf("invalid theme %q: must be %q, %q or %q", t, ThemeLight, ThemeDark, ThemeSepia)
+}
+
+// themePaletteFor returns the palette for opts.Theme, or false for the light
+// theme (and anything unrecognised).
+func themePaletteFor(opts GenerateOptions) (themePalette, bool) {
+	p, ok := themePalettes[strings.ToLower(opts.Theme)]
+	return p, ok
+}
+
+// themeCSS returns the stylesheet injected after the layout CSS for
+// opts.Theme and opts.DarkenImages, or "" when neither applies.
+//
+// wkhtmltopdf always runs with --print-media-type, so only unscoped and
+// @media print rules reach the PDF; rules under @media screen are ignored.
+// The theme is therefore declared for "print, screen" so the PDF and a kept
+// HTML file opened in a browser look the same. The palette is exposed as
+// --theme-* custom properties for user stylesheets, but the rules themselves
+// use literal colors because wkhtmltopdf's WebKit predates CSS variables.
+// The page margins are painted by wkhtmltopdf itself and stay white.
+func themeCSS(opts GenerateOptions) string {
+	p, themed := themePaletteFor(opts)
+	if !themed && !opts.DarkenImages {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("@media print, screen {\n")
+	if themed {
+		fmt.Fprintf(&sb, `  :root {
+    --theme-bg: %[1]s; --theme-text: %[2]s; --theme-muted: %[3]s;
+    --theme-link: %[4]s; --theme-rule: %[5]s; --theme-stripe: %[6]s;
+  }
+  html, body { background-color: %[1]s !important; color: %[2]s !important; }
+  body * { color: inherit !important; background-color: transparent !important; border-color: %[5]s !important; }
+  a, a * { color: %[4]s !important; }
+  .date, .toc-author, .toc-publication, .toc-excerpt, .article-meta, figcaption { color: %[3]s !important; }
+  tr:nth-child(even) > td { background-color: %[6]s !important; }
+`, p.Background, p.Text, p.Muted, p.Link, p.Rule, p.Stripe)
+	}
+	if opts.DarkenImages {
+		// Opacity darkens images against a dark page and works in old WebKit;
+		// the filter is applied where supported.
+		sb.WriteString("  img { opacity: 0.85; -webkit-filter: brightness(0.85) contrast(1.1); filter: brightness(0.85) contrast(1.1); }\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// themeStyle is themeCSS typed for the HTML templates.
+func themeStyle(opts GenerateOptions) template.CSS {
+	return template.CSS(themeCSS(opts))
+}
+
+// typstThemeRules returns Typst set rules applying opts.Theme's palette, to
+// follow the preamble's own set rules, or "" for the light theme. Typst has
+// no image filters, so DarkenImages does not apply to this renderer.
+func typstThemeRules(opts GenerateOptions) string {
+	p, ok := themePaletteFor(opts)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(`// Color theme
+#set page(fill: rgb("%s"))
+#set text(fill: rgb("%s"))
+#show link: set text(fill: rgb("%s"))
+#set line(stroke: rgb("%s"))
+#set table(
+  stroke: 0.5pt + rgb("%s"),
+  fill: (_, y) => if y > 0 and calc.even(y) { rgb("%s") },
+)
+
+`, p.Background, p.Text, p.Link, p.Text, p.Rule, p.Stripe)
+}
@@ -3,7 +3,6 @@ package pdf
 import (
 	"fmt"
 	"strings"
-	"time"
 
 	art "pdf-maker/internal/article"
 	"pdf-maker/internal/clean"
@@ -22,6 +21,15 @@ import (
 //   - Table of contents (#outline())
 //   - Per-article sections: heading with byline, then body content
 func AssembleNewspaperTypst(articles []*art.Article, title string) (string, error) {
+	return AssembleNewspaperTypstWithOptions(articles, GenerateOptions{Title: title})
+}
+
+// AssembleNewspaperTypstWithOptions builds the newspaper .typ document using
+// the title and rendering options from opts. Drop caps are part of the
+// newspaper design and are always applied, regardless of opts.DropCaps.
+func AssembleNewspaperTypstWithOptions(articles []*art.Article, opts GenerateOptions) (string, error) {
+	anchors := ArticleAnchors(articles)
+	title := opts.Title
 	if len(articles) == 0 {
 		return "", fmt.Errorf("no articles provided")
 	}
@@ -32,7 +40,7 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
 		articleWord = "Article"
 	}
 	dateLine := fmt.Sprintf("%s #h(2em) %d %s",
-		time.Now().Format("Monday, January 2, 2006"),
+		issueDate(articles, opts),
 		articleCount,
 		articleWord,
 	)
@@ -40,15 +48,15 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
 	var sb strings.Builder
 
 	// ── Page & text settings ────────────────────────────────────────────────
-	sb.WriteString(`#import "@preview/droplet:0.3.1": dropcap
-
-#set page(
-  paper: "us-letter",
-  flipped: true,
-  margin: (x: 0.75in, y: 0.75in),
-  columns: 3,
-)
-
+	landscape := orientationOrDefault(opts) == OrientationLandscape
+	sb.WriteString("#import \"@preview/droplet:0.3.1\": dropcap\n\n")
+	sb.WriteString(typstDocumentRules(opts))
+	sb.WriteString(fmt.Sprintf("#set page(\n  paper: \"us-letter\",\n  flipped: %t,\n  margin: (x: 0.75in, y: 0.75in),\n  columns: %d,\n)\n",
+		landscape, newspaperColumns(opts)))
+	if opts.Gutter != "" {
+		sb.WriteString(fmt.Sprintf("#set columns(gutter: %s)\n", typstLength(opts.Gutter)))
+	}
+	sb.WriteString(`
 #set text(
   font: ("Linux Libertine O", "Libertinus Serif", "Liberation Serif", "DejaVu Serif", "Noto Color Emoji"),
   size: 10pt,
@@ -71,7 +79,18 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
   block(above: 0.4em, below: 0.3em, it)
 }
 
+// Data tables: light rules, zebra striping below the first row, smaller text
+#set table(
+  stroke: 0.5pt + luma(160),
+  inset: 4pt,
+  fill: (_, y) => if y > 0 and calc.even(y) { luma(240) },
+)
+#show table: set text(size: 0.85em)
+#show table: set par(justify: false, first-line-indent: 0pt)
+
 `)
+	sb.WriteString(typstThemeRules(opts))
+	sb.WriteString(typstWatermarkRules(opts))
 
 	// ── Floating masthead ───────────────────────────────────────────────────
 	sb.WriteString("#place(\n")
@@ -90,39 +109,21 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
 	sb.WriteString("  }\n")
 	sb.WriteString(")\n\n")
 
-	// ── Table of contents (bordered box) ────────────────────────────────────
-	sb.WriteString("#rect(stroke: 0.5pt, inset: (x: 0.8em, y: 0.7em), width: 100%, radius: 2pt)[\n")
-	sb.WriteString("#v(0.1em)\n")
-	sb.WriteString("#align(center)[#text(size: 12pt, weight: \"medium\")[IN THIS EDITION]]\n")
-	sb.WriteString("#v(0.3em)\n")
-	sb.WriteString("#line(length: 100%, stroke: 0.4pt)\n")
-	sb.WriteString("#v(0.3em)\n")
-	for i, a := range articles {
-		label := fmt.Sprintf("article-%d", i+1)
-		title := escapeTypstContent(a.Title)
-		var bp []string
-		if a.Author != "" {
-			bp = append(bp, escapeTypstContent(a.Author))
-		}
-		if a.Publication != "" {
-			bp = append(bp, escapeTypstContent(a.Publication))
-		}
-		byline := strings.Join(bp, " · ")
-		if byline != "" {
-			sb.WriteString(fmt.Sprintf(
-				"#link(<%s>)[*%s*]\\\n#text(size: 8pt, fill: gray, style: \"italic\")[%s]\n\n",
-				label, title, byline))
-		} else {
-			sb.WriteString(fmt.Sprintf("#link(<%s>)[*%s*]\n\n", label, title))
-		}
+	if err := writeIssueSection(&sb, opts.HeaderHTML, opts.HeaderPageBreak, false); err != nil {
+		return "", fmt.Errorf("header html: %w", err)
 	}
-	sb.WriteString("]\n")
-	sb.WriteString("#v(0.5em)\n\n")
+
+	// ── Summary spread, then the table of contents (bordered box) ─────────
+	// The TOC stays with the spread on: it heads the first page of articles
+	sb.WriteString(typstSummarySpread(articles, opts))
+	writeNewspaperTOC(&sb, articles, opts)
 
 	// ── Articles ────────────────────────────────────────────────────────────
 	for i, a := range articles {
-		// Labelled heading so the TOC #link(<article-N>) can target it
-		sb.WriteString(fmt.Sprintf("== %s <article-%d>\n\n", escapeTypstContent(a.Title), i+1))
+		openArticleScope(&sb, a)
+
+		// Labelled heading so the TOC #link(<anchor>) can target it; see ArticleAnchors
+		sb.WriteString(fmt.Sprintf("== %s <%s>\n\n", escapeTypstContent(a.DisplayTitle()), anchors[i]))
 
 		// Byline
 		var bylineParts []string
@@ -135,9 +136,19 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
 		if !a.PubDate.IsZero() {
 			bylineParts = append(bylineParts, a.PubDate.Format("January 2, 2006"))
 		}
-		if len(bylineParts) > 0 {
+		if a.WasUpdated() {
+			bylineParts = append(bylineParts, "Updated "+a.UpdatedDate.Format("January 2, 2006"))
+		}
+		byline := strings.Join(bylineParts, " · ")
+		if opts.MetaFormat != "" {
+			byline = formatMeta(opts.MetaFormat, a, "January 2, 2006")
+		}
+		if byline != "" {
+			if opts.ShowAvatars {
+				sb.WriteString(typstAvatarFor(a))
+			}
 			sb.WriteString(fmt.Sprintf("#text(size: 8pt, style: \"italic\")[%s]\n\n",
-				escapeTypstContent(strings.Join(bylineParts, " · "))))
+				escapeTypstContent(byline)))
 		}
 
 		// Article body
@@ -150,6 +161,10 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
 			sb.WriteString(addDropCap(body))
 			sb.WriteString("\n\n")
 		}
+		sb.WriteString(typstSourceLink(a, opts, "8pt"))
+		sb.WriteString(typstSourceQR(a, opts))
+		sb.WriteString(typstComments(a, opts, "8pt"))
+		closeArticleScope(&sb, a)
 
 		// Article separator (skip after last article)
 		if i < len(articles)-1 {
@@ -159,15 +174,66 @@ func AssembleNewspaperTypst(articles []*art.Article, title string) (string, erro
 		}
 	}
 
+	if err := writeIssueSection(&sb, opts.FooterHTML, opts.FooterPageBreak, true); err != nil {
+		return "", fmt.Errorf("footer html: %w", err)
+	}
+
 	return sb.String(), nil
 }
 
+// writeNewspaperTOC writes the newspaper's IN THIS EDITION box: each
+// article's title (linked to it), byline and excerpt, beside its cover
+// thumbnail with opts.TOCThumbnails.
+func writeNewspaperTOC(sb *strings.Builder, articles []*art.Article, opts GenerateOptions) {
+	anchors := ArticleAnchors(articles)
+	sb.WriteString("#rect(stroke: 0.5pt, inset: (x: 0.8em, y: 0.7em), width: 100%, radius: 2pt)[\n")
+	sb.WriteString("#v(0.1em)\n")
+	sb.WriteString("#align(center)[#text(size: 12pt, weight: \"medium\")[IN THIS EDITION]]\n")
+	sb.WriteString("#v(0.3em)\n")
+	sb.WriteString("#line(length: 100%, stroke: 0.4pt)\n")
+	sb.WriteString("#v(0.3em)\n")
+	for i, a := range articles {
+		label := anchors[i]
+		title := escapeTypstContent(a.DisplayTitle())
+		var bp []string
+		if a.Author != "" {
+			bp = append(bp, escapeTypstContent(a.Author))
+		}
+		if a.Publication != "" {
+			bp = append(bp, escapeTypstContent(a.Publication))
+		}
+		byline := strings.Join(bp, " · ")
+		entry := fmt.Sprintf("#link(<%s>)[*%s*]", label, title)
+		if byline != "" {
+			entry += fmt.Sprintf("\\\n#text(size: 8pt, fill: gray, style: \"italic\")[%s]", byline)
+		}
+		if a.Excerpt != "" {
+			entry += fmt.Sprintf("\\\n#text(size: 7.5pt)[%s]", escapeTypstContent(a.Excerpt))
+		}
+		if thumb := typstCoverThumb(a, opts, "2.6em"); thumb != "" {
+			entry = fmt.Sprintf("#grid(columns: (2.6em, 1fr), column-gutter: 0.5em, %s, [%s])", thumb, entry)
+		}
+		sb.WriteString(entry + "\n\n")
+	}
+	sb.WriteString("]\n")
+	sb.WriteString("#v(0.5em)\n\n")
+}
+
 // AssembleEssayTypst builds a complete Typst (.typ) document for the essay layout.
 //
 // Portrait US Letter, single column, generous margins, 12pt serif body text.
-// No drop caps. Same floating masthead and bordered TOC box as the newspaper
+// No drop caps unless requested via GenerateOptions.DropCaps. Same floating masthead and bordered TOC box as the newspaper
 // layout, but without flipped: true or columns: 3.
 func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
+	return AssembleEssayTypstWithOptions(articles, GenerateOptions{Title: title})
+}
+
+// AssembleEssayTypstWithOptions builds the essay .typ document using the title
+// and rendering options from opts. When opts.DropCaps is set, each article's
+// opening paragraph gets the same drop cap used by the newspaper layout.
+func AssembleEssayTypstWithOptions(articles []*art.Article, opts GenerateOptions) (string, error) {
+	anchors := ArticleAnchors(articles)
+	title := opts.Title
 	if len(articles) == 0 {
 		return "", fmt.Errorf("no articles provided")
 	}
@@ -178,7 +244,7 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 		articleWord = "Article"
 	}
 	dateLine := fmt.Sprintf("%s • %d %s",
-		time.Now().Format("Monday, January 2, 2006"),
+		issueDate(articles, opts),
 		articleCount,
 		articleWord,
 	)
@@ -186,13 +252,11 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 	var sb strings.Builder
 
 	// ── Page & text settings ────────────────────────────────────────────────
-	sb.WriteString(`#import "@preview/droplet:0.3.1": dropcap
-
-#set page(
-  paper: "us-letter",
-  margin: (x: 1in, y: 0.75in),
-)
-
+	landscape := orientationOrDefault(opts) == OrientationLandscape
+	sb.WriteString("#import \"@preview/droplet:0.3.1\": dropcap\n\n")
+	sb.WriteString(typstDocumentRules(opts))
+	sb.WriteString(fmt.Sprintf("#set page(\n  paper: \"us-letter\",\n  flipped: %t,\n  margin: (x: 1in, y: 0.75in),\n)\n", landscape))
+	sb.WriteString(`
 #set text(
   font: ("Linux Libertine O", "Libertinus Serif", "Liberation Serif", "DejaVu Serif", "Noto Color Emoji"),
   size: 12pt,
@@ -217,7 +281,18 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
   )
 }
 
+// Data tables: light rules, zebra striping below the first row, smaller text
+#set table(
+  stroke: 0.5pt + luma(160),
+  inset: 4pt,
+  fill: (_, y) => if y > 0 and calc.even(y) { luma(240) },
+)
+#show table: set text(size: 0.85em)
+#show table: set par(justify: false, first-line-indent: 0pt)
+
 `)
+	sb.WriteString(typstThemeRules(opts))
+	sb.WriteString(typstWatermarkRules(opts))
 
 	// ── Floating masthead ───────────────────────────────────────────────────
 	sb.WriteString("#place(\n")
@@ -236,6 +311,12 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 	sb.WriteString("  }\n")
 	sb.WriteString(")\n\n")
 
+	if err := writeIssueSection(&sb, opts.HeaderHTML, opts.HeaderPageBreak, false); err != nil {
+		return "", fmt.Errorf("header html: %w", err)
+	}
+
+	sb.WriteString(typstSummarySpread(articles, opts))
+
 	// ── Table of contents (bordered box) ────────────────────────────────────
 	// sb.WriteString("#rect(stroke: 0.5pt, inset: (x: 0.8em, y: 0.7em), width: 100%, radius: 2pt)[\n")
 	// sb.WriteString("#v(0.2em)\n")
@@ -245,7 +326,7 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 	// sb.WriteString("#v(0.2em)\n")
 	// for i, a := range articles {
 	// 	label := fmt.Sprintf("article-%d", i+1)
-	// 	articleTitle := escapeTypstContent(a.Title)
+	// 	articleTitle := escapeTypstContent(a.DisplayTitle())
 	// 	var bp []string
 	// 	if a.Author != "" {
 	// 		bp = append(bp, escapeTypstContent(a.Author))
@@ -267,7 +348,8 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 
 	// ── Articles ────────────────────────────────────────────────────────────
 	for i, a := range articles {
-		sb.WriteString(fmt.Sprintf("== %s <article-%d>\n\n", escapeTypstContent(a.Title), i+1))
+		openArticleScope(&sb, a)
+		sb.WriteString(fmt.Sprintf("== %s <%s>\n\n", escapeTypstContent(a.DisplayTitle()), anchors[i]))
 
 		// Byline
 		var bylineParts []string
@@ -280,20 +362,37 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 		if !a.PubDate.IsZero() {
 			bylineParts = append(bylineParts, a.PubDate.Format("January 2, 2006"))
 		}
-		if len(bylineParts) > 0 {
+		if a.WasUpdated() {
+			bylineParts = append(bylineParts, "Updated "+a.UpdatedDate.Format("January 2, 2006"))
+		}
+		byline := strings.Join(bylineParts, " · ")
+		if opts.MetaFormat != "" {
+			byline = formatMeta(opts.MetaFormat, a, "January 2, 2006")
+		}
+		if byline != "" {
+			if opts.ShowAvatars {
+				sb.WriteString(typstAvatarFor(a))
+			}
 			sb.WriteString(fmt.Sprintf("#text(size: 9pt, style: \"italic\")[%s]\n\n",
-				escapeTypstContent(strings.Join(bylineParts, " · "))))
+				escapeTypstContent(byline)))
 		}
 
-		// Article body — no drop cap for essay format
+		// Article body — drop cap only when explicitly requested for essay format
 		body, err := clean.HTMLToTypst(a.Content, a.RemoveImages)
 		if err != nil {
 			sb.WriteString(fmt.Sprintf("#text(fill: red)[Error rendering article: %s]\n\n",
 				escapeTypstContent(err.Error())))
 		} else if body != "" {
+			if opts.DropCaps {
+				body = addDropCap(body)
+			}
 			sb.WriteString(body)
 			sb.WriteString("\n\n")
 		}
+		sb.WriteString(typstSourceLink(a, opts, "9pt"))
+		sb.WriteString(typstSourceQR(a, opts))
+		sb.WriteString(typstComments(a, opts, "10pt"))
+		closeArticleScope(&sb, a)
 
 		// Article separator (skip after last article)
 		if i < len(articles)-1 {
@@ -303,9 +402,95 @@ func AssembleEssayTypst(articles []*art.Article, title string) (string, error) {
 		}
 	}
 
+	if err := writeIssueSection(&sb, opts.FooterHTML, opts.FooterPageBreak, true); err != nil {
+		return "", fmt.Errorf("footer html: %w", err)
+	}
+
 	return sb.String(), nil
 }
 
+// writeIssueSection converts a custom HTML section (HeaderHTML/FooterHTML) to
+// Typst after running it through the article sanitizer. A header's page break
+// follows the section; a footer's precedes it. Blank sections write nothing.
+func writeIssueSection(sb *strings.Builder, rawHTML string, pageBreak, isFooter bool) error {
+	if strings.TrimSpace(rawHTML) == "" {
+		return nil
+	}
+	cleaned, _, err := clean.CleanHTML(rawHTML, false)
+	if err != nil {
+		return err
+	}
+	body, err := clean.HTMLToTypst(cleaned, false)
+	if err != nil {
+		return err
+	}
+	if body == "" {
+		return nil
+	}
+	if isFooter {
+		if pageBreak {
+			sb.WriteString("#pagebreak()\n\n")
+		} else {
+			sb.WriteString("#v(1.2em)\n#line(length: 100%, stroke: 0.5pt)\n#v(0.8em)\n\n")
+		}
+	}
+	sb.WriteString("#block(width: 100%)[\n")
+	sb.WriteString(body)
+	sb.WriteString("\n]\n")
+	if !isFooter {
+		if pageBreak {
+			sb.WriteString("#pagebreak()\n\n")
+		} else {
+			sb.WriteString("#v(0.5em)\n\n")
+		}
+	}
+	return nil
+}
+
+// openArticleScope starts a scoped content block that sets the article's
+// language (for hyphenation and localized quotes) and switches text direction
+// to right-to-left for RTL articles, so mixed-language issues are set per
+// article. It writes nothing when the language is unknown.
+func openArticleScope(sb *strings.Builder, a *art.Article) {
+	if !hasArticleScope(a) {
+		return
+	}
+	var params []string
+	if code := a.LanguageCode(); isTypstLang(code) {
+		params = append(params, fmt.Sprintf("lang: %s", clean.TypstString(code)))
+	}
+	if a.IsRTL() {
+		params = append(params, "dir: rtl")
+	}
+	sb.WriteString(fmt.Sprintf("#[\n#set text(%s)\n\n", strings.Join(params, ", ")))
+}
+
+// closeArticleScope ends the block opened by openArticleScope.
+func closeArticleScope(sb *strings.Builder, a *art.Article) {
+	if !hasArticleScope(a) {
+		return
+	}
+	sb.WriteString("]\n\n")
+}
+
+// hasArticleScope reports whether the article needs its own text settings.
+func hasArticleScope(a *art.Article) bool {
+	return a.IsRTL() || isTypstLang(a.LanguageCode())
+}
+
+// isTypstLang reports whether code is an ISO 639 code Typst accepts for lang.
+func isTypstLang(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
 // escapeTypstContent escapes a plain-text string for use as Typst content
 // (inside square brackets or directly in the document body).
 // Only characters that are syntactically special in Typst content need escaping.
@@ -345,6 +530,19 @@ func escapeTypstContent(s string) string {
 	return sb.String()
 }
 
+// typstCoverThumb returns a size×size thumbnail of a's cover image (in code
+// mode, for a grid cell), or "" unless opts.TOCThumbnails is set and the
+// cover is a local file: Typst reads no URLs, so a cover that could not be
+// downloaded is left out.
+func typstCoverThumb(a *art.Article, opts GenerateOptions, size string) string {
+	p := a.CoverImage
+	if !opts.TOCThumbnails || p == "" || strings.Contains(p, "://") {
+		return ""
+	}
+	return fmt.Sprintf("box(clip: true, radius: 2pt, image(%s, width: %s, height: %s, fit: \"cover\", alt: %s))",
+		clean.TypstString(p), size, size, clean.TypstString("Cover image of "+a.DisplayTitle()))
+}
+
 // addDropCap wraps the first body-text paragraph with the droplet package's
 // #dropcap() function, which automatically extracts the first letter, scales
 // it to the given line height, and splits the paragraph text to wrap around it.
@@ -396,3 +594,21 @@ func addDropCap(body string) string {
 	}
 	return body
 }
+
+// typstAvatar returns the markup for a round author avatar placed inline
+// before the byline. stripBadImage matches this exact string to drop an
+// avatar Typst cannot decode.
+func typstAvatar(path string) string {
+	return fmt.Sprintf("#box(clip: true, radius: 50%%, baseline: 30%%, image(%s, width: 1.8em, height: 1.8em, alt: \"Author photo\")) ", clean.TypstString(path))
+}
+
+// typstAvatarFor returns the avatar markup for a, or "" when the article has
+// no downloaded avatar. Remote URLs are skipped because Typst only reads
+// local files.
+func typstAvatarFor(a *art.Article) string {
+	p := a.AuthorAvatar
+	if p == "" || strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+		return ""
+	}
+	return typstAvatar(p)
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// pdfTailWindow is how far from the end of the file %%EOF may appear;
+// writers may append a trailing newline or a little padding after it.
+const pdfTailWindow = 1024
+
+var (
+	pdfPageObjRe   = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfPagesCount  = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+	pdfObjStreamRe = regexp.MustCompile(`(?s)<<((?:[^<>]|<<[^<>]*>>)*/Type\s*/ObjStm(?:[^<>]|<<[^<>]*>>)*)>>\s*stream\r?\n`)
+)
+
+// ValidatePDF checks that path holds a complete PDF: it starts with the
+// %PDF- header, ends with %%EOF, and at least one page can be found. It
+// catches zero-byte and truncated output from a renderer that exited 0
+// after running out of memory.
+func ValidatePDF(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read pdf: %w", err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("pdf %s is empty", path)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return fmt.Errorf("pdf %s: missing %%PDF- header", path)
+	}
+	tail := data
+	if len(tail) > pdfTailWindow {
+		tail = tail[len(tail)-pdfTailWindow:]
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return fmt.Errorf("pdf %s: missing %%%%EOF marker (truncated?)", path)
+	}
+	if n := pdfPageCount(data); n == 0 {
+		return fmt.Errorf("pdf %s: no pages found", path)
+	}
+	return nil
+}
+
+// pdfPageCount estimates the number of pages: the /Count of the page tree
+// root when present, else the number of /Type /Page objects. Objects packed
+// into compressed object streams (PDF 1.5+) are inflated and searched too.
+// Returns 0 when no page information is found.
+func pdfPageCount(data []byte) int {
+	if n := pageCountIn(data); n > 0 {
+		return n
+	}
+	var inflated bytes.Buffer
+	for _, loc := range pdfObjStreamRe.FindAllSubmatchIndex(data, -1) {
+		if !bytes.Contains(data[loc[2]:loc[3]], []byte("/FlateDecode")) {
+			continue
+		}
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data[start : start+end]))
+		if err != nil {
+			continue
+		}
+		io.Copy(&inflated, zr) // keep whatever inflated before an error
+		zr.Close()
+		inflated.WriteByte('\n')
+	}
+	return pageCountIn(inflated.Bytes())
+}
+
+// pageCountIn applies pdfPageCount's two heuristics to uncompressed bytes.
+func pageCountIn(b []byte) int {
+	best := 0
+	for _, m := range pdfPagesCount.FindAllSubmatch(b, -1) {
+		v := m[1]
+		if len(v) == 0 {
+			v = m[2]
+		}
+		if n, err := strconv.Atoi(string(v)); err == nil && n > best {
+			best = n
+		}
+	}
+	if best > 0 {
+		return best
+	}
+	return len(pdfPageObjRe.FindAll(b, -1))
+}
@@ -0,0 +1,95 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// plainPDF is a minimal uncompressed PDF with a page tree of n pages.
+func plainPDF(n int) string {
+	var kids []string
+	var objs strings.Builder
+	for i := 0; i < n; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", i+3))
+		fmt.Fprintf(&objs, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>\nendobj\n", i+3)
+	}
+	return "%PDF-1.4\n" +
+		"1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		fmt.Sprintf("2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), n) +
+		objs.String() +
+		"trailer\n<< /Root 1 0 R >>\n%%EOF\n"
+}
+
+// objStmPDF is a PDF 1.5 file whose page tree lives only inside a
+// FlateDecode object stream, as Typst writes it.
+func objStmPDF(t *testing.T, n int) string {
+	t.Helper()
+	packed := fmt.Sprintf("<< /Type /Catalog /Pages 2 0 R >> << /Type /Pages /Kids [3 0 R] /Count %d >> << /Type /Page /Parent 2 0 R >>", n)
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write([]byte(packed))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return "%PDF-1.7\n" +
+		fmt.Sprintf("5 0 obj\n<< /Type /ObjStm /N 3 /First 12 /Filter /FlateDecode /Length %d >>\nstream\n", z.Len()) +
+		z.String() + "\nendstream\nendobj\n" +
+		"6 0 obj\n<< /Type /XRef /Root 1 0 R /Size 7 >>\nendobj\n" +
+		"startxref\n0\n%%EOF\n"
+}
+
+func TestValidatePDF(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"empty file", "", "is empty"},
+		{"missing header", "<html>not a pdf</html>\n%%EOF\n", "missing %PDF- header"},
+		{"truncated before %%EOF", strings.TrimSuffix(plainPDF(2), "trailer\n<< /Root 1 0 R >>\n%%EOF\n"), "truncated"},
+		{"no pages", "%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n%%EOF\n", "no pages found"},
+		{"plain page tree", plainPDF(3), ""},
+		{"pages only in an object stream", objStmPDF(t, 4), ""},
+		{"padding after %%EOF", plainPDF(1) + strings.Repeat(" ", 100), ""},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("%d.pdf", i))
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := ValidatePDF(path)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: err = %v, want one mentioning %q", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := ValidatePDF(filepath.Join(dir, "missing.pdf")); err == nil {
+		t.Error("missing file passed")
+	}
+}
+
+func TestPDFPageCount(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"plain /Type /Pages /Count", plainPDF(3), 3},
+		{"/Count before /Type", "%PDF-1.4\n2 0 obj\n<< /Kids [3 0 R 4 0 R] /Count 2 /Type /Pages >>\nendobj\n", 2},
+		{"largest tree wins over an intermediate node", plainPDF(7) + "9 0 obj\n<< /Type /Pages /Parent 2 0 R /Count 4 >>\nendobj\n", 7},
+		{"page objects without a tree count", "%PDF-1.4\n<< /Type /Page >>\n<< /Type /Page >>\n<< /Type /Pages >>\n", 2},
+		{"object stream", objStmPDF(t, 12), 12},
+		{"nothing", "%PDF-1.4\n%%EOF\n", 0},
+	}
+	for _, tt := range tests {
+		if got := pdfPageCount([]byte(tt.data)); got != tt.want {
+			t.Errorf("%s: pdfPageCount = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package pdf
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// watermarkCSS positions the .pdf-watermark element. wkhtmltopdf repeats
+// position: fixed elements on every printed page; z-index keeps the text
+// behind the content and pointer-events/user-select keep it out of the way
+// of selection. The -webkit- prefixes are for wkhtmltopdf's old WebKit.
+const watermarkCSS = `@media print, screen {
+  .pdf-watermark {
+    position: fixed;
+    top: 45%;
+    left: 0;
+    width: 100%;
+    text-align: center;
+    font: bold 96px sans-serif;
+    color: #808080;
+    opacity: 0.15;
+    z-index: -1;
+    -webkit-transform: rotate(-45deg);
+    transform: rotate(-45deg);
+    pointer-events: none;
+    -webkit-user-select: none;
+    user-select: none;
+    white-space: nowrap;
+  }
+}`
+
+// watermarkStyle returns the CSS for the layout templates, or "" when no
+// watermark is set.
+func watermarkStyle(opts GenerateOptions) template.CSS {
+	if strings.TrimSpace(opts.Watermark) == "" {
+		return ""
+	}
+	return template.CSS(watermarkCSS)
+}
+
+// typstWatermarkRules returns a Typst set rule drawing opts.Watermark
+// diagonally across every page's background, beneath the content, or ""
+// when no watermark is set. Like the theme rules it must precede the
+// masthead so it applies from the first page.
+func typstWatermarkRules(opts GenerateOptions) string {
+	text := strings.TrimSpace(opts.Watermark)
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf(`// Watermark
+#set page(background: rotate(-45deg, text(size: 96pt, weight: "bold", fill: luma(128).transparentize(85%%))[%s]))
+
+`, escapeTypstContent(text))
+}
@@ -0,0 +1,55 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestWatermarkInAssembledHTML(t *testing.T) {
+	articles := []*art.Article{{Title: "Post", Link: "https://example.com/p/post", Content: "<p>Body</p>"}}
+	for _, layout := range []string{"newspaper", "essay", "original"} {
+		t.Run(layout, func(t *testing.T) {
+			out, err := AssembleHTMLWithOptions(articles, GenerateOptions{LayoutType: layout, Watermark: " DRAFT <v2> ", AllowMissingStyles: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, `<div class="pdf-watermark" aria-hidden="true">DRAFT &lt;v2&gt;</div>`) {
+				t.Error("no escaped watermark element")
+			}
+			if !strings.Contains(out, "position: fixed;") || !strings.Contains(out, ".pdf-watermark {") {
+				t.Error("no watermark CSS")
+			}
+
+			out, err = AssembleHTMLWithOptions(articles, GenerateOptions{LayoutType: layout, Watermark: "  ", AllowMissingStyles: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(out, "pdf-watermark") {
+				t.Error("blank watermark rendered")
+			}
+		})
+	}
+}
+
+func TestWatermarkInTypst(t *testing.T) {
+	articles := []*art.Article{{Title: "Post", Link: "https://example.com/p/post", Content: "<p>Body</p>"}}
+	opts := GenerateOptions{Watermark: "DRAFT #1"}
+	for name, assemble := range map[string]func([]*art.Article, GenerateOptions) (string, error){
+		"newspaper": AssembleNewspaperTypstWithOptions,
+		"essay":     AssembleEssayTypstWithOptions,
+	} {
+		out, err := assemble(articles, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule := strings.Index(out, `#set page(background: rotate(-45deg,`)
+		if rule < 0 || !strings.Contains(out[rule:], `[DRAFT \#1]`) {
+			t.Errorf("%s: no escaped watermark background rule", name)
+		}
+		if title := strings.Index(out, "Post"); title >= 0 && title < rule {
+			t.Errorf("%s: watermark rule comes after content, so the first page lacks it", name)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+)
+
+// checkpointIndex is the name of the index file inside a checkpoint directory.
+const checkpointIndex = "checkpoint.json"
+
+// checkpointEntry is one successfully fetched URL: the article's metadata
+// and the file (inside the checkpoint directory) holding its content.
+type checkpointEntry struct {
+	File      string       `json:"file"`
+	FetchedAt time.Time    `json:"fetched_at"`
+	Article   *art.Article `json:"article"` // Content and ContentPath are empty
+}
+
+// Checkpoint records, keyed by URL, the articles a batch has fetched so
+// far, so an interrupted run can be resumed without fetching them again.
+// Each article's cleaned, image-localized content is saved to its own file
+// as soon as it arrives and the index is rewritten atomically, so a kill at
+// any point leaves a usable checkpoint.
+//
+// A restored entry is only trusted while everything it points at still
+// exists: an entry whose content file, local images, local avatar or local
+// cover image are missing (e.g. the images directory was cleaned up) is dropped and its URL
+// fetched again. A nil *Checkpoint records and restores nothing.
+type Checkpoint struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]checkpointEntry
+}
+
+// OpenCheckpoint opens the checkpoint in dir, creating the directory if
+// needed. With resume, entries saved by an earlier run are loaded;
+// otherwise any earlier checkpoint in dir is discarded.
+func OpenCheckpoint(dir string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{dir: dir, entries: make(map[string]checkpointEntry)}
+	if !resume {
+		if err := c.Remove(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create checkpoint dir: %w", err)
+	}
+	if !resume {
+		return c, nil
+	}
+	b, err := os.ReadFile(filepath.Join(dir, checkpointIndex))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(b, &c.entries); err != nil {
+		return nil, fmt.Errorf("parse checkpoint: %w", err)
+	}
+	return c, nil
+}
+
+// Len returns the number of URLs recorded.
+func (c *Checkpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Dir returns the checkpoint directory.
+func (c *Checkpoint) Dir() string {
+	if c == nil {
+		return ""
+	}
+	return c.dir
+}
+
+// Restore returns a copy of the article recorded for url with its content
+// loaded into memory. ok is false when url was not recorded or its entry is
+// stale, in which case the entry is forgotten.
+func (c *Checkpoint) Restore(url string) (a *art.Article, ok bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	e, found := c.entries[url]
+	c.mu.Unlock()
+	if !found || e.Article == nil {
+		return nil, false
+	}
+	content, err := os.ReadFile(filepath.Join(c.dir, e.File))
+	if err == nil && localFilesExist(string(content), e.Article.AuthorAvatar, e.Article.CoverImage) {
+		restored := *e.Article
+		restored.Content = string(content)
+		return &restored, true
+	}
+	c.mu.Lock()
+	delete(c.entries, url)
+	c.mu.Unlock()
+	return nil, false
+}
+
+// Record saves a's content and metadata under url and rewrites the index.
+// Called concurrently as fetches finish.
+func (c *Checkpoint) Record(url string, a *art.Article) error {
+	if c == nil || a == nil {
+		return nil
+	}
+	content, err := a.LoadContent()
+	if err != nil {
+		return err
+	}
+	file := fmt.Sprintf("%x.html", sha1.Sum([]byte(url)))
+	if err := writeFileAtomic(filepath.Join(c.dir, file), []byte(content)); err != nil {
+		return fmt.Errorf("write checkpoint content: %w", err)
+	}
+	meta := *a
+	meta.Content, meta.ContentPath = "", ""
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = checkpointEntry{File: file, FetchedAt: time.Now().UTC(), Article: &meta}
+	b, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(c.dir, checkpointIndex), b); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint directory, e.g. once the run it protected
+// has completed.
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("remove checkpoint: %w", err)
+	}
+	c.mu.Lock()
+	c.entries = make(map[string]checkpointEntry)
+	c.mu.Unlock()
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames
+// it into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// localFilesExist reports whether every local image the content refers to,
+// and each of the other image files (the avatar and cover), is still on
+// disk. Downloaded images have filesystem paths; remote and data: URLs are
+// ignored.
+func localFilesExist(content string, images ...string) bool {
+	paths := append([]string(nil), images...)
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(content)); err == nil {
+		doc.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+			paths = append(paths, img.AttrOr("src", ""))
+		})
+	}
+	for _, p := range paths {
+		if p == "" || strings.Contains(p, "://") || strings.HasPrefix(p, "data:") || strings.HasPrefix(p, "//") {
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+// writeImage creates a placeholder image file in dir and returns its path.
+func writeImage(t *testing.T, dir, name string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte("img"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCheckpointDropsEntryWithMissingCover(t *testing.T) {
+	images := t.TempDir()
+	cover := writeImage(t, images, "cover.jpg")
+	c, err := OpenCheckpoint(filepath.Join(t.TempDir(), "ckpt"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const url = "https://x.substack.com/p/a"
+	if err := c.Record(url, &art.Article{Title: "A", Link: url, Content: "<p>a</p>", CoverImage: cover}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(cover); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Restore(url); ok {
+		t.Error("restored an entry whose cover image is gone")
+	}
+	if c.Len() != 0 {
+		t.Errorf("stale entry kept: Len = %d", c.Len())
+	}
+}
+
+func TestCheckpointRecordRestore(t *testing.T) {
+	images := t.TempDir()
+	photo := writeImage(t, images, "photo.png")
+	avatar := writeImage(t, images, "avatar.png")
+	c, err := OpenCheckpoint(filepath.Join(t.TempDir(), "ckpt"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const url = "https://x.substack.com/p/a"
+	in := &art.Article{Title: "A", Author: "Ann", Link: url, AuthorAvatar: avatar, Content: `<p>Body</p><img src="` + photo + `"/>`}
+	if err := c.Record(url, in); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Restore(url)
+	if !ok {
+		t.Fatal("recorded entry not restored")
+	}
+	if got == in || got.Title != "A" || got.Author != "Ann" || got.AuthorAvatar != avatar || got.Content != in.Content {
+		t.Errorf("restored %+v, want a copy of %+v", got, in)
+	}
+	if _, ok := c.Restore("https://x.substack.com/p/other"); ok {
+		t.Error("restored a URL never recorded")
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	c, err := OpenCheckpoint(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{"https://x.substack.com/p/a", "https://x.substack.com/p/b"}
+	for _, u := range urls {
+		if err := c.Record(u, &art.Article{Title: u, Link: u, Content: "<p>" + u + "</p>"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resumed, err := OpenCheckpoint(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Len() != 2 {
+		t.Fatalf("resumed Len = %d, want 2", resumed.Len())
+	}
+	for _, u := range urls {
+		if a, ok := resumed.Restore(u); !ok || a.Content != "<p>"+u+"</p>" {
+			t.Errorf("resume lost %s: %+v", u, a)
+		}
+	}
+
+	fresh, err := OpenCheckpoint(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Len() != 0 {
+		t.Errorf("opening without resume kept %d entries", fresh.Len())
+	}
+	if again, _ := OpenCheckpoint(dir, true); again.Len() != 0 {
+		t.Errorf("the discarded checkpoint came back with %d entries", again.Len())
+	}
+}
+
+func TestCheckpointDropsEntryWithMissingImage(t *testing.T) {
+	images := t.TempDir()
+	photo := writeImage(t, images, "photo.png")
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	c, err := OpenCheckpoint(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const url = "https://x.substack.com/p/a"
+	content := `<img src="` + photo + `"/><img src="https://cdn.example.com/remote.png"/><img src="data:image/png;base64,AA=="/>`
+	if err := c.Record(url, &art.Article{Title: "A", Link: url, Content: content}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Restore(url); !ok {
+		t.Fatal("entry with its images on disk was not restored (remote and data: sources must be ignored)")
+	}
+	if err := os.Remove(photo); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := OpenCheckpoint(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resumed.Restore(url); ok {
+		t.Error("restored an entry whose local image is gone")
+	}
+	if resumed.Len() != 0 {
+		t.Errorf("stale entry kept: Len = %d", resumed.Len())
+	}
+}
+
+// Concurrent Records each rewrite the index through a temporary file and a
+// rename: the index always parses, holds every entry at the end, and no
+// temporary files are left behind.
+func TestCheckpointIndexRewrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	c, err := OpenCheckpoint(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u := fmt.Sprintf("https://x.substack.com/p/%d", i)
+			if err := c.Record(u, &art.Article{Title: u, Link: u, Content: "<p>x</p>"}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	b, err := os.ReadFile(filepath.Join(dir, checkpointIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]checkpointEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatalf("index does not parse: %v", err)
+	}
+	if len(entries) != n {
+		t.Errorf("index holds %d entries, want %d", len(entries), n)
+	}
+	for _, e := range entries {
+		if e.Article == nil || e.Article.Content != "" {
+			t.Errorf("index entry %s carries content or no article", e.File)
+		}
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".tmp-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+
+	path := filepath.Join(dir, "file.txt")
+	if err := writeFileAtomic(path, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "two" {
+		t.Errorf("rewrite left %q", got)
+	}
+}
+
+func TestNilCheckpoint(t *testing.T) {
+	var c *Checkpoint
+	if err := c.Record("https://x.substack.com/p/a", &art.Article{}); err != nil {
+		t.Error(err)
+	}
+	if _, ok := c.Restore("https://x.substack.com/p/a"); ok || c.Len() != 0 || c.Dir() != "" || c.Remove() != nil {
+		t.Error("nil checkpoint recorded something")
+	}
+}
@@ -0,0 +1,332 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/clean"
+	"pdf-maker/internal/fetch"
+	"pdf-maker/internal/media"
+	"pdf-maker/internal/pdf"
+)
+
+// Options configures a Pipeline.
+type Options struct {
+	// Fetch configures fetching and cleaning. Fetch.ImageDownloader is used
+	// for images when set; its totals are included in the Report. OnCleaned
+	// and OnResult are wrapped, not replaced.
+	Fetch fetch.FetchOptions
+
+	// PDF configures rendering.
+	PDF pdf.GenerateOptions
+
+	// Split renders one PDF per article instead of a single issue.
+	Split bool
+
+	// OnFetched, when set, is called as each URL finishes fetching. Called
+	// concurrently.
+	OnFetched func(fetch.ArticleResult)
+
+	// Checkpoint, when set, records every successfully fetched URL, and
+	// Fetch takes URLs already recorded from it instead of fetching them
+	// again (see Checkpoint).
+	Checkpoint *Checkpoint
+
+	// Sources, when set, keeps the raw page of every successfully fetched
+	// URL for SourceArchive.WriteZip.
+	Sources *SourceArchive
+
+	// MaxAgeDays, when positive, makes FilterByAge drop articles published
+	// more than this many days ago. Articles without a PubDate are kept
+	// unless SkipUndated is set.
+	MaxAgeDays int
+
+	// SkipUndated makes FilterByAge drop articles with no PubDate.
+	SkipUndated bool
+}
+
+// Report aggregates the outcome of a pipeline run.
+type Report struct {
+	Queued   int     // URLs passed to Fetch
+	Fetched  int     // URLs fetched successfully (before duplicates are collapsed)
+	Resumed  int     // URLs taken from the checkpoint instead of fetched (included in Fetched)
+	Failures []error // fetch failures, one per URL
+	TooOld   int     // articles dropped by FilterByAge for Options.MaxAgeDays
+	Undated  int     // articles dropped by FilterByAge for Options.SkipUndated
+
+	Clean  clean.Stats         // cleaning totals across every fetched page
+	Images media.DownloadStats // image totals from the options' downloader
+
+	PDFs    []pdf.GenerateResult // one result, or one per article with Split
+	PDFPath string               // the combined PDF (empty with Split or on failure)
+	PDFSize int64                // size of PDFPath in bytes
+
+	Elapsed time.Duration // time spent in Fetch and Render
+}
+
+// Pipeline runs fetch → clean → images → PDF with a single set of options.
+// A Pipeline accumulates one Report; use a new Pipeline per run.
+type Pipeline struct {
+	opts Options
+
+	mu     sync.Mutex
+	report Report
+}
+
+// New returns a Pipeline configured by opts.
+func New(opts Options) *Pipeline {
+	return &Pipeline{opts: opts}
+}
+
+// Run fetches urls and renders the successfully fetched articles that pass
+// FilterByAge. The returned error is non-nil when nothing could be fetched,
+// nothing passed the filter or rendering failed; the Report is returned
+// either way.
+func (p *Pipeline) Run(ctx context.Context, urls []string) (*Report, error) {
+	articles, _ := p.Fetch(ctx, urls)
+	if len(articles) == 0 {
+		return p.Report(), fmt.Errorf("no articles successfully fetched")
+	}
+	articles = p.FilterByAge(articles, time.Now())
+	if len(articles) == 0 {
+		return p.Report(), fmt.Errorf("no articles left after the age filter")
+	}
+	err := p.Render(ctx, articles)
+	return p.Report(), err
+}
+
+// Fetch fetches, cleans and localizes the images of urls concurrently, as
+// fetch.FetchArticlesConcurrentWithOptions does, recording the outcome in the
+// Report.
+//
+// URLs found in Options.Checkpoint are restored rather than fetched; the
+// articles are still returned in the order of urls.
+func (p *Pipeline) Fetch(ctx context.Context, urls []string) ([]*art.Article, []error) {
+	start := time.Now()
+	opts := p.opts.Fetch
+
+	all := urls
+	restored, pending := p.restore(urls)
+	if len(restored) > 0 {
+		fmt.Printf("Resuming: %d of %d URLs restored from checkpoint %s\n", len(restored), len(urls), p.opts.Checkpoint.Dir())
+		numbers := make([]int, 0, len(urls)-len(restored))
+		remaining := make([]string, 0, len(urls)-len(restored))
+		var pubIDs []string
+		for i, u := range urls {
+			if pending[i] {
+				remaining = append(remaining, u)
+				numbers = append(numbers, articleNumber(opts.ArticleNumbers, i))
+				if i < len(opts.PublicationIDs) {
+					pubIDs = append(pubIDs, opts.PublicationIDs[i])
+				}
+			}
+		}
+		opts.ArticleNumbers = numbers
+		if len(opts.PublicationIDs) > 0 {
+			opts.PublicationIDs = pubIDs
+		}
+		urls = remaining
+	}
+
+	onCleaned := opts.OnCleaned
+	opts.OnCleaned = func(pageURL string, stats clean.Stats) {
+		p.mu.Lock()
+		p.report.Clean.Add(stats)
+		p.mu.Unlock()
+		if onCleaned != nil {
+			onCleaned(pageURL, stats)
+		}
+	}
+	onResult := opts.OnResult
+	opts.OnResult = func(r fetch.ArticleResult) {
+		if r.Err == nil {
+			p.mu.Lock()
+			p.report.Fetched++
+			p.mu.Unlock()
+			if err := p.opts.Checkpoint.Record(r.URL, r.Article); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to checkpoint %s: %v\n", r.URL, err)
+			}
+			if err := p.opts.Sources.Add(r.URL, r.Raw); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to keep source of %s: %v\n", r.URL, err)
+			}
+		}
+		if onResult != nil {
+			onResult(r)
+		}
+		if p.opts.OnFetched != nil {
+			p.opts.OnFetched(r)
+		}
+	}
+
+	articles, errs := fetch.FetchArticlesConcurrentWithOptions(ctx, urls, opts)
+	if len(restored) > 0 {
+		articles = mergeRestored(all, restored, articles)
+	}
+
+	p.mu.Lock()
+	p.report.Queued += len(urls) + len(restored)
+	p.report.Fetched += len(restored)
+	p.report.Resumed += len(restored)
+	p.report.Failures = append(p.report.Failures, errs...)
+	p.report.Elapsed += time.Since(start)
+	p.mu.Unlock()
+	return articles, errs
+}
+
+// FilterByAge returns articles without those published more than
+// Options.MaxAgeDays before now and, with Options.SkipUndated, those with no
+// PubDate, counting both in the Report. The input slice is not modified.
+func (p *Pipeline) FilterByAge(articles []*art.Article, now time.Time) []*art.Article {
+	if p.opts.MaxAgeDays <= 0 && !p.opts.SkipUndated {
+		return articles
+	}
+	cutoff := now.AddDate(0, 0, -p.opts.MaxAgeDays)
+	kept := make([]*art.Article, 0, len(articles))
+	var tooOld, undated int
+	for _, a := range articles {
+		switch {
+		case a.PubDate.IsZero() && p.opts.SkipUndated:
+			undated++
+		case !a.PubDate.IsZero() && p.opts.MaxAgeDays > 0 && a.PubDate.Before(cutoff):
+			tooOld++
+		default:
+			kept = append(kept, a)
+		}
+	}
+	p.mu.Lock()
+	p.report.TooOld += tooOld
+	p.report.Undated += undated
+	p.mu.Unlock()
+	return kept
+}
+
+// restore takes the URLs recorded in the checkpoint, keyed by their
+// position in urls, and reports which positions still need fetching.
+// Restored content is spilled to Fetch.SpillDir like fetched content.
+func (p *Pipeline) restore(urls []string) (map[int]*art.Article, []bool) {
+	restored := make(map[int]*art.Article)
+	pending := make([]bool, len(urls))
+	for i, u := range urls {
+		a, ok := p.opts.Checkpoint.Restore(u)
+		if ok && p.opts.Fetch.SpillDir != "" {
+			ok = art.SpillContent(a, p.opts.Fetch.SpillDir) == nil
+		}
+		if ok {
+			restored[i] = a
+		} else {
+			pending[i] = true
+		}
+	}
+	return restored, pending
+}
+
+// articleNumber returns the issue position of the i-th URL, as
+// FetchArticlesConcurrentWithOptions numbers it.
+func articleNumber(numbers []int, i int) int {
+	if i < len(numbers) {
+		return numbers[i]
+	}
+	return i + 1
+}
+
+// mergeRestored puts restored articles (keyed by position in urls) and the
+// freshly fetched ones back into the order of urls, and collapses duplicates
+// across the two sets.
+func mergeRestored(urls []string, restored map[int]*art.Article, fetched []*art.Article) []*art.Article {
+	fetchedByURL := make(map[string]*art.Article, len(fetched))
+	for _, a := range fetched {
+		fetchedByURL[a.Link] = a
+	}
+	merged := make([]*art.Article, 0, len(restored)+len(fetched))
+	for i, u := range urls {
+		if a, ok := restored[i]; ok {
+			merged = append(merged, a)
+		} else if a, ok := fetchedByURL[u]; ok {
+			merged = append(merged, a)
+			delete(fetchedByURL, u)
+		}
+	}
+	merged, dupes := fetch.DedupeArticles(merged)
+	if dupes > 0 {
+		fmt.Fprintf(os.Stderr, "Collapsed %d duplicate article(s)\n", dupes)
+	}
+	return merged
+}
+
+// Render generates the PDF (or one PDF per article with Split) from
+// articles. For the "original" layout the articles' stylesheets are inlined
+// first. The PDF options may be adjusted with SetPDFOptions between Fetch
+// and Render, e.g. once the layout is known.
+func (p *Pipeline) Render(ctx context.Context, articles []*art.Article) error {
+	start := time.Now()
+	defer func() {
+		p.mu.Lock()
+		p.report.Elapsed += time.Since(start)
+		p.mu.Unlock()
+	}()
+
+	opts := p.opts.PDF
+	if opts.LayoutType == "original" {
+		fetch.InlineStylesheetsWithOptions(ctx, articles, p.opts.Fetch)
+	}
+
+	if p.opts.Split {
+		results := pdf.GenerateSplitPDFs(ctx, articles, opts)
+		p.mu.Lock()
+		p.report.PDFs = results
+		p.mu.Unlock()
+		for _, r := range results {
+			if r.Success {
+				return nil
+			}
+		}
+		return fmt.Errorf("PDF generation failed for all %d articles", len(results))
+	}
+
+	result := pdf.GeneratePDF(ctx, articles, opts)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.PDFs = []pdf.GenerateResult{result}
+	if !result.Success {
+		return result.Error
+	}
+	p.report.PDFPath = result.PDFPath
+	if info, err := os.Stat(result.PDFPath); err == nil {
+		p.report.PDFSize = info.Size()
+	}
+	return nil
+}
+
+// SetPDFOptions replaces the rendering options used by Render.
+func (p *Pipeline) SetPDFOptions(opts pdf.GenerateOptions) {
+	p.opts.PDF = opts
+}
+
+// SetArticleNumbers sets fetch.FetchOptions.ArticleNumbers for later Fetch
+// calls: the issue position of each URL, for numbering image filenames.
+func (p *Pipeline) SetArticleNumbers(numbers []int) {
+	p.opts.Fetch.ArticleNumbers = numbers
+}
+
+// SetPublicationIDs sets fetch.FetchOptions.PublicationIDs for later Fetch
+// calls: the publication ID of each URL, for looking up its settings.
+func (p *Pipeline) SetPublicationIDs(ids []string) {
+	p.opts.Fetch.PublicationIDs = ids
+}
+
+// Report returns a snapshot of the aggregated report so far.
+func (p *Pipeline) Report() *Report {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.report
+	r.Failures = append([]error(nil), p.report.Failures...)
+	r.PDFs = append([]pdf.GenerateResult(nil), p.report.PDFs...)
+	if d := p.opts.Fetch.ImageDownloader; d != nil {
+		r.Images = d.Totals()
+	}
+	return &r
+}
@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func testArticle(u string) *art.Article {
+	return &art.Article{Title: u, Link: u, Content: fmt.Sprintf("<p>The body of %s, which no other article shares.</p>", u)}
+}
+
+func TestMergeRestoredKeepsURLOrder(t *testing.T) {
+	urls := []string{
+		"https://x.substack.com/p/a",
+		"https://x.substack.com/p/b",
+		"https://x.substack.com/p/c",
+		"https://x.substack.com/p/d",
+		"https://x.substack.com/p/e",
+	}
+	restored := map[int]*art.Article{1: testArticle(urls[1]), 3: testArticle(urls[3])}
+	// Fetched articles arrive in completion order; c failed to fetch
+	fetched := []*art.Article{testArticle(urls[4]), testArticle(urls[0])}
+
+	merged := mergeRestored(urls, restored, fetched)
+	var got []string
+	for _, a := range merged {
+		got = append(got, a.Link)
+	}
+	want := []string{urls[0], urls[1], urls[3], urls[4]}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("merged order = %v, want %v", got, want)
+	}
+
+	// A restored article and a fetched one that are the same story collapse
+	dupe := testArticle(urls[2])
+	dupe.CanonicalURL = urls[1]
+	merged = mergeRestored(urls[:3], map[int]*art.Article{1: testArticle(urls[1])}, []*art.Article{testArticle(urls[0]), dupe})
+	if len(merged) != 2 {
+		t.Errorf("%d articles after merging a duplicate, want 2", len(merged))
+	}
+}
+
+// With every URL in the checkpoint, Fetch makes no request and returns the
+// restored articles in the order asked for.
+func TestFetchResumesFromCheckpoint(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	c, err := OpenCheckpoint(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{"https://x.substack.com/p/b", "https://x.substack.com/p/a", "https://x.substack.com/p/c"}
+	for _, u := range []string{urls[2], urls[0], urls[1]} {
+		if err := c.Record(u, testArticle(u)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resumed, err := OpenCheckpoint(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(Options{Checkpoint: resumed})
+	articles, errs := p.Fetch(context.Background(), urls)
+	if len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	if len(articles) != len(urls) {
+		t.Fatalf("%d articles, want %d", len(articles), len(urls))
+	}
+	for i, a := range articles {
+		if a.Link != urls[i] {
+			t.Errorf("article %d = %s, want %s", i, a.Link, urls[i])
+		}
+	}
+	if r := p.Report(); r.Resumed != 3 || r.Fetched != 3 || r.Queued != 3 {
+		t.Errorf("report: queued %d, fetched %d, resumed %d; want 3 each", r.Queued, r.Fetched, r.Resumed)
+	}
+}
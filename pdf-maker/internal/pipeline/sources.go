@@ -0,0 +1,310 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	art "pdf-maker/internal/article"
+	"pdf-maker/internal/fetch"
+)
+
+// SourceArchive keeps the raw pages a run fetched so they can be bundled,
+// with the articles built from them and their images, into a zip for
+// archival (WriteZip). Pages are written to a temporary directory as they
+// arrive rather than held in memory; Close removes it. A nil
+// *SourceArchive records nothing.
+type SourceArchive struct {
+	dir string
+
+	mu    sync.Mutex
+	pages map[string]sourcePage // page URL → saved raw body
+}
+
+// sourcePage is one raw page saved by a SourceArchive.
+type sourcePage struct {
+	file      string // path of the saved body
+	ext       string // ".html", or ".json" for Substack API responses
+	fetchedAt time.Time
+}
+
+// sourceManifest is the manifest.json at the root of a source archive.
+type sourceManifest struct {
+	Created  time.Time               `json:"created"`
+	Articles []sourceManifestArticle `json:"articles"`
+}
+
+// sourceManifestArticle describes one article of a source archive. Paths
+// are relative to the archive root; Source is empty for articles whose raw
+// page was not fetched in this run (content given in the input, or
+// restored from a checkpoint).
+type sourceManifestArticle struct {
+	Index        int        `json:"index"`
+	Title        string     `json:"title"`
+	URL          string     `json:"url,omitempty"`
+	CanonicalURL string     `json:"canonical_url,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	Publication  string     `json:"publication,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	FetchedAt    *time.Time `json:"fetched_at,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	Content      string     `json:"content"`
+	Images       []string   `json:"images,omitempty"`
+}
+
+// NewSourceArchive returns an empty SourceArchive backed by a new temporary
+// directory.
+func NewSourceArchive() (*SourceArchive, error) {
+	dir, err := os.MkdirTemp("", "makepdf-sources-")
+	if err != nil {
+		return nil, fmt.Errorf("create source archive dir: %w", err)
+	}
+	return &SourceArchive{dir: dir, pages: make(map[string]sourcePage)}, nil
+}
+
+// Add saves the raw body fetched from pageURL, replacing any earlier one
+// for the same URL. Safe for concurrent use.
+func (s *SourceArchive) Add(pageURL string, raw []byte) error {
+	if s == nil || len(raw) == 0 {
+		return nil
+	}
+	ext := ".html"
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		ext = ".json"
+	}
+	file := filepath.Join(s.dir, fmt.Sprintf("%x%s", sha1.Sum([]byte(pageURL)), ext))
+	if err := os.WriteFile(file, raw, 0o644); err != nil {
+		return fmt.Errorf("save raw page: %w", err)
+	}
+	s.mu.Lock()
+	s.pages[pageURL] = sourcePage{file: file, ext: ext, fetchedAt: time.Now().UTC()}
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of raw pages saved.
+func (s *SourceArchive) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pages)
+}
+
+// Close removes the saved raw pages.
+func (s *SourceArchive) Close() error {
+	if s == nil {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// WriteZip writes the archive for articles to path:
+//
+//	manifest.json                  issue and per-article metadata
+//	articles/NN-<slug>/source.html raw page as fetched (source.json for API responses)
+//	articles/NN-<slug>/content.html cleaned content, images pointing into images/
+//	images/<file>                  every local image, avatar and cover, stored once
+//
+// The zip is written to a temporary file and renamed into place, so a
+// failed write leaves no partial archive.
+func (s *SourceArchive) WriteZip(path string, articles []*art.Article) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".archive-*.zip")
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
+
+	zw := zip.NewWriter(f)
+	err = s.writeEntries(zw, articles)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// writeEntries writes every article, its images and the manifest to zw.
+func (s *SourceArchive) writeEntries(zw *zip.Writer, articles []*art.Article) error {
+	manifest := sourceManifest{Created: time.Now().UTC()}
+	images := make(map[string]string) // local path → name in the zip; see archiveImages
+	for i, a := range articles {
+		slug := strings.TrimSuffix(fetch.DeriveFilename(a.Link), ".html")
+		if a.Link == "" {
+			slug = "article"
+		}
+		dir := fmt.Sprintf("articles/%02d-%s", i+1, slug)
+		entry := sourceManifestArticle{
+			Index:        i + 1,
+			Title:        a.DisplayTitle(),
+			URL:          a.Link,
+			CanonicalURL: a.CanonicalURL,
+			Author:       a.Author,
+			Publication:  a.Publication,
+		}
+		if !a.PubDate.IsZero() {
+			entry.Published = &a.PubDate
+		}
+
+		if page, ok := s.page(a.Link); ok {
+			entry.Source = dir + "/source" + page.ext
+			entry.FetchedAt = &page.fetchedAt
+			if err := zipFile(zw, entry.Source, page.file); err != nil {
+				return err
+			}
+		}
+
+		content, err := a.LoadContent()
+		if err != nil {
+			return fmt.Errorf("load content for '%s': %w", a.Title, err)
+		}
+		content, used, err := archiveImages(zw, content, images, a.AuthorAvatar, a.CoverImage)
+		if err != nil {
+			return err
+		}
+		entry.Images = used
+		entry.Content = dir + "/content.html"
+		if err := zipBytes(zw, entry.Content, []byte(content)); err != nil {
+			return err
+		}
+		manifest.Articles = append(manifest.Articles, entry)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return zipBytes(zw, "manifest.json", b)
+}
+
+// page returns the saved raw page for pageURL.
+func (s *SourceArchive) page(pageURL string) (sourcePage, bool) {
+	if s == nil || pageURL == "" {
+		return sourcePage{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pages[pageURL]
+	return p, ok
+}
+
+// archiveImages adds the local images content refers to, and those of
+// extra (the avatar and cover) that are local files, to zw under images/
+// (each file once across the archive, tracked in stored), and returns
+// content with its sources rewritten to point at the stored copies from an
+// article directory, plus the images it used.
+func archiveImages(zw *zip.Writer, content string, stored map[string]string, extra ...string) (string, []string, error) {
+	var used []string
+	store := func(local string) (string, error) {
+		if name, ok := stored[local]; ok {
+			used = append(used, name)
+			return name, nil
+		}
+		name := path.Join("images", filepath.Base(local))
+		for n := 2; storedName(stored, name); n++ {
+			ext := filepath.Ext(local)
+			name = path.Join("images", fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filepath.Base(local), ext), n, ext))
+		}
+		if err := zipFile(zw, name, local); err != nil {
+			return "", err
+		}
+		stored[local] = name
+		used = append(used, name)
+		return name, nil
+	}
+
+	for _, local := range extra {
+		if isLocalFile(local) {
+			if _, err := store(local); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content, used, nil
+	}
+	var storeErr error
+	doc.Find("img[src]").EachWithBreak(func(_ int, img *goquery.Selection) bool {
+		src := img.AttrOr("src", "")
+		if !isLocalFile(src) {
+			return true
+		}
+		name, err := store(src)
+		if err != nil {
+			storeErr = err
+			return false
+		}
+		img.SetAttr("src", "../../"+name)
+		return true
+	})
+	if storeErr != nil {
+		return "", nil, storeErr
+	}
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return content, used, nil
+	}
+	return strings.TrimSpace(out), used, nil
+}
+
+// storedName reports whether name is already used in stored.
+func storedName(stored map[string]string, name string) bool {
+	for _, v := range stored {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalFile reports whether an image src is a downloaded file still on
+// disk rather than a remote or data: URL.
+func isLocalFile(src string) bool {
+	if src == "" || strings.Contains(src, "://") || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "//") {
+		return false
+	}
+	info, err := os.Stat(src)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// zipBytes stores data in zw under name.
+func zipBytes(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// zipFile stores the file at src in zw under name.
+func zipFile(zw *zip.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	art "pdf-maker/internal/article"
+)
+
+func TestSourceArchiveStoresCover(t *testing.T) {
+	images := t.TempDir()
+	photo := writeImage(t, images, "photo.png")
+	cover := writeImage(t, images, "cover.jpg")
+	s, err := NewSourceArchive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	a := &art.Article{
+		Title:      "A",
+		Link:       "https://x.substack.com/p/a",
+		Content:    `<p>a</p><img src="` + photo + `"/>`,
+		CoverImage: cover,
+	}
+	if err := s.Add(a.Link, []byte("<html>raw</html>")); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "sources.zip")
+	if err := s.WriteZip(zipPath, []*art.Article{a}); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	var manifest sourceManifest
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name != "manifest.json" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(r)
+		r.Close()
+		if err := json.Unmarshal(b, &manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"images/cover.jpg", "images/photo.png"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("archive lacks %s: %v", want, names)
+		}
+	}
+	if len(manifest.Articles) != 1 || !slices.Contains(manifest.Articles[0].Images, "images/cover.jpg") {
+		t.Fatalf("manifest images = %+v, want the cover listed", manifest.Articles)
+	}
+	if src := manifest.Articles[0].Source; !slices.Contains(names, src) {
+		t.Errorf("manifest source %q not in the archive", src)
+	}
+}
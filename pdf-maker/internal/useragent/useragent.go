@@ -0,0 +1,69 @@
+// Package useragent rotates User-Agent strings across requests.
+//
+// Rotation is a mild measure for publications that block a single fixed
+// agent; it is meant for fetching articles the user is entitled to read, at
+// the same polite concurrency as usual. It does not defeat paywalls or
+// robots rules and should not be used to disguise bulk scraping.
+package useragent
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Rotator hands out User-Agent strings from a fixed list. A nil *Rotator is
+// valid and always returns the caller's fallback, so options can leave it
+// unset to keep the default single agent. Safe for concurrent use.
+type Rotator struct {
+	agents  []string // in rotation order (shuffled by seed)
+	perHost bool
+	seed    uint64
+	next    atomic.Uint64
+}
+
+// Options configures a Rotator.
+type Options struct {
+	// Seed fixes the rotation order so a run is reproducible. 0 picks a
+	// random order.
+	Seed int64
+	// PerHost gives every request to the same host the same agent instead of
+	// advancing on each request.
+	PerHost bool
+}
+
+// New returns a Rotator over agents (blank entries are dropped), or nil when
+// none remain.
+func New(agents []string, opts Options) *Rotator {
+	var list []string
+	for _, a := range agents {
+		if a = strings.TrimSpace(a); a != "" {
+			list = append(list, a)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+	return &Rotator{agents: list, perHost: opts.PerHost, seed: uint64(seed)}
+}
+
+// Pick returns the agent for a request to host, or fallback when r is nil.
+func (r *Rotator) Pick(host, fallback string) string {
+	if r == nil {
+		return fallback
+	}
+	if r.perHost {
+		h := fnv.New64a()
+		h.Write([]byte(strings.ToLower(host)))
+		return r.agents[(h.Sum64()^r.seed)%uint64(len(r.agents))]
+	}
+	return r.agents[(r.next.Add(1)-1)%uint64(len(r.agents))]
+}